@@ -0,0 +1,7 @@
+module github.com/Wikunia/Ghess/cecp
+
+go 1.16
+
+replace github.com/Wikunia/Ghess/ghess => ../ghess
+
+require github.com/Wikunia/Ghess/ghess v0.0.0-20210415175110-7d8e2df87018
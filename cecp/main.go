@@ -0,0 +1,256 @@
+// Command cecp runs Ghess as an engine for the XBoard/WinBoard protocol (CECP version 2).
+//
+//	xboard -fcp "cecp" -fd /path/to/ghess/cecp
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+const ENGINE_NAME = "Ghess v0.1.0"
+
+// FEATURES are announced after protover 2. Moves are sent with the usermove prefix in coordinate notation.
+const FEATURES = `feature myname="` + ENGINE_NAME + `" ping=1 setboard=1 playother=1 usermove=1 time=1 draw=0 san=0 ` +
+	`sigint=0 sigterm=0 reuse=1 analyze=1 colors=0 done=1`
+
+var board = ghess.GetBoardFromFen(ghess.START_FEN)
+var startFen = ghess.START_FEN
+var moves = []string{} // moves played since startFen in long algebraic notation
+var forceMode = false
+var engineIsBlack = true
+var analyzing = false
+var post = false
+var hint = "" // expected reply of the opponent from the last search
+
+func main() {
+	evalFile := flag.String("evalfile", "", "JSON file with evaluation weights (default: built in weights)")
+	nnueFile := flag.String("nnue", "", "neural network file which is used instead of the handcrafted evaluation")
+	flag.Parse()
+	if *evalFile != "" {
+		if err := ghess.LoadEvalParams(*evalFile); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *nnueFile != "" {
+		network, err := ghess.ReadNetwork(*nnueFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ghess.SetNetwork(network)
+	}
+	board.RefreshEvaluation()
+
+	// commands are read in their own goroutine such that ? and exit arrive while thinking
+	input := make(chan string)
+	go func() {
+		reader := bufio.NewReader(os.Stdin)
+		for {
+			s, err := reader.ReadString('\n')
+			if s != "" {
+				input <- s
+			}
+			if err != nil {
+				if err != io.EOF {
+					log.Println("Error in read string", err)
+				}
+				close(input)
+				return
+			}
+		}
+	}()
+	for {
+		var results chan [30]ghess.Move
+		if current != nil {
+			results = current.result
+		}
+		select {
+		case in, ok := <-input:
+			if !ok || !runCommand(strings.TrimSpace(in)) {
+				stopThinking(false)
+				return
+			}
+		case pv := <-results:
+			finishThinking(pv)
+		}
+	}
+}
+
+// runCommand handles a command of the GUI and returns false on quit
+func runCommand(in string) bool {
+	fields := strings.Fields(in)
+	if len(fields) == 0 {
+		return true
+	}
+	args := fields[1:]
+	switch fields[0] {
+	case "xboard", "accepted", "rejected", "random", "computer", "name", "rating", "ics", "easy", "hard", "otim", "draw", ".":
+	case "protover":
+		fmt.Println(FEATURES)
+	case "ping":
+		fmt.Println("pong " + strings.Join(args, " "))
+	case "new":
+		stopThinking(false)
+		setPosition(ghess.START_FEN)
+		forceMode = false
+		engineIsBlack = true
+		resetLimits()
+	case "setboard":
+		stopThinking(false)
+		setPosition(strings.Join(args, " "))
+	case "force":
+		stopThinking(false)
+		forceMode = true
+	case "go":
+		stopThinking(false)
+		forceMode = false
+		engineIsBlack = board.IsBlacksTurn
+		startThinking(false)
+	case "playother":
+		stopThinking(false)
+		forceMode = false
+		engineIsBlack = !board.IsBlacksTurn
+	case "white", "black":
+		// protocol version 1: the side to move changes and the engine plays the other color
+		stopThinking(false)
+		engineIsBlack = fields[0] == "white"
+	case "usermove":
+		if len(args) > 0 {
+			userMove(args[0])
+		}
+	case "?":
+		stopThinking(true)
+	case "undo":
+		takeBack(1)
+	case "remove":
+		takeBack(2)
+	case "level":
+		setLevel(args)
+	case "st":
+		setMoveTime(args)
+	case "sd":
+		setDepth(args)
+	case "time":
+		setClock(args)
+	case "post":
+		post = true
+	case "nopost":
+		post = false
+	case "analyze":
+		analyzing = true
+		startThinking(true)
+	case "exit":
+		stopThinking(false)
+		analyzing = false
+	case "hint":
+		if hint != "" {
+			if _, err := board.GetMoveFromLongAlgebraic(hint); err == nil {
+				fmt.Printf("Hint: %s\n", hint)
+			}
+		}
+	case "result":
+		stopThinking(false)
+		forceMode = true
+	case "quit":
+		return false
+	default:
+		// protocol version 1 sends moves without the usermove prefix
+		if _, err := board.GetMoveFromLongAlgebraic(fields[0]); err == nil {
+			userMove(fields[0])
+		} else {
+			fmt.Printf("Error (unknown command): %s\n", fields[0])
+		}
+	}
+	return true
+}
+
+// setPosition replaces the game by the position given as FEN
+func setPosition(fen string) {
+	startFen = fen
+	board = ghess.GetBoardFromFen(fen)
+	moves = []string{}
+	hint = ""
+	if analyzing {
+		startThinking(true)
+	}
+}
+
+// userMove makes the move of the opponent and starts thinking if it's the turn of the engine
+func userMove(moveStr string) {
+	stopThinking(false)
+	if err := board.MoveLongAlgebraic(moveStr); err != nil {
+		fmt.Printf("Illegal move: %s\n", moveStr)
+		return
+	}
+	moves = append(moves, moveStr)
+	if analyzing {
+		startThinking(true)
+		return
+	}
+	if printResult() {
+		return
+	}
+	if !forceMode && board.IsBlacksTurn == engineIsBlack {
+		startThinking(false)
+	}
+}
+
+// takeBack takes back the last plies by replaying the game without them
+func takeBack(plies int) {
+	stopThinking(false)
+	if plies > len(moves) {
+		plies = len(moves)
+	}
+	played := moves[:len(moves)-plies]
+	board = ghess.GetBoardFromFen(startFen)
+	moves = []string{}
+	for _, moveStr := range played {
+		board.MoveLongAlgebraic(moveStr)
+		moves = append(moves, moveStr)
+	}
+	if analyzing {
+		startThinking(true)
+	}
+}
+
+// playEngineMove makes the first move of the pv and sends it to the GUI
+func playEngineMove(pv [30]ghess.Move) {
+	if pv[0].PieceId == 0 {
+		return
+	}
+	moveStr := ghess.GetAlgebraicFromMove(&pv[0])
+	board.MoveLongAlgebraic(moveStr)
+	moves = append(moves, moveStr)
+	hint = ""
+	if pv[1].PieceId != 0 {
+		hint = ghess.GetAlgebraicFromMove(&pv[1])
+	}
+	fmt.Printf("move %s\n", moveStr)
+	printResult()
+}
+
+// printResult sends the result if the game ended and returns true in that case
+func printResult() bool {
+	ended, endType, _ := board.CheckGameEnded()
+	if !ended {
+		return false
+	}
+	switch {
+	case endType == "checkmate" && board.IsBlacksTurn:
+		fmt.Println("1-0 {White mates}")
+	case endType == "checkmate":
+		fmt.Println("0-1 {Black mates}")
+	case board.GetNumberOfMoves(1) == 0:
+		fmt.Println("1/2-1/2 {Stalemate}")
+	default:
+		fmt.Println("1/2-1/2 {Draw}")
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// MOVE_OVERHEAD is the time in ms which is kept for the communication with the GUI
+const MOVE_OVERHEAD = 50
+
+// DEFAULT_MOVES_LEFT is the number of moves the remaining time is split into without a fixed number of moves per session
+const DEFAULT_MOVES_LEFT = 30
+
+// UNLIMITED_TIME is the time in ms an analysis runs until exit or a new position
+const UNLIMITED_TIME = 1000 * 60 * 60 * 24
+
+// MATE_SCORE is the score of a mate in 0 moves in the thinking output
+const MATE_SCORE = 100000
+
+// thinking is a running search. Only one search runs at a time and sends its pv on result when it ends.
+type thinking struct {
+	analyze bool
+	stop    chan bool // closed to end the search
+	result  chan [30]ghess.Move
+}
+
+var current *thinking
+
+// limits set by level, st, sd and time. Times are in ms.
+var movesPerSession = 0
+var baseTime = 5 * 60 * 1000
+var increment = 0
+var moveTime = 0
+var maxDepth = 0
+var engineClock = 0
+
+// resetLimits removes the depth and time limits
+func resetLimits() {
+	moveTime = 0
+	maxDepth = 0
+	engineClock = 0
+}
+
+// setLevel handles "level MPS BASE INC" where BASE is given in minutes or as minutes:seconds and INC in seconds
+func setLevel(args []string) {
+	if len(args) < 3 {
+		return
+	}
+	movesPerSession, _ = strconv.Atoi(args[0])
+	base := strings.SplitN(args[1], ":", 2)
+	minutes, _ := strconv.Atoi(base[0])
+	seconds := 0
+	if len(base) == 2 {
+		seconds, _ = strconv.Atoi(base[1])
+	}
+	baseTime = (60*minutes + seconds) * 1000
+	inc, _ := strconv.ParseFloat(args[2], 64)
+	increment = int(1000 * inc)
+	moveTime = 0
+}
+
+// setMoveTime handles "st SECONDS" which fixes the time per move
+func setMoveTime(args []string) {
+	if len(args) > 0 {
+		seconds, _ := strconv.ParseFloat(args[0], 64)
+		moveTime = int(1000 * seconds)
+	}
+}
+
+// setDepth handles "sd DEPTH"
+func setDepth(args []string) {
+	if len(args) > 0 {
+		maxDepth, _ = strconv.Atoi(args[0])
+	}
+}
+
+// setClock handles "time CENTISECONDS" which is the remaining time of the engine
+func setClock(args []string) {
+	if len(args) > 0 {
+		centiseconds, _ := strconv.Atoi(args[0])
+		engineClock = 10 * centiseconds
+	}
+}
+
+// thinkingTime returns the time in ms the engine uses for the next move
+func thinkingTime() int {
+	if moveTime > 0 {
+		return max(1, moveTime-MOVE_OVERHEAD)
+	}
+	clock := engineClock
+	if clock <= 0 {
+		clock = baseTime
+	}
+	movesLeft := DEFAULT_MOVES_LEFT
+	if movesPerSession > 0 {
+		movesLeft = movesPerSession - (len(moves)/2)%movesPerSession
+	}
+	return max(1, min(clock/movesLeft+increment, clock-MOVE_OVERHEAD))
+}
+
+// startThinking searches the current position in a new goroutine. An analysis runs until it is stopped.
+func startThinking(analyze bool) {
+	stopThinking(false)
+	if ended, _, _ := board.CheckGameEnded(); ended {
+		return
+	}
+	t := &thinking{analyze: analyze, stop: make(chan bool), result: make(chan [30]ghess.Move, 1)}
+	timeLimit := UNLIMITED_TIME
+	if !analyze {
+		timeLimit = thinkingTime()
+	}
+	depth := 30
+	if maxDepth > 0 && !analyze {
+		depth = maxDepth
+	}
+	// the search works on its own copy with its own pawn hash table such that the board can't change while it runs
+	searchBoard := board.Copy()
+	searchBoard.SetStopChannel(t.stop)
+	// post and nopost only apply to the next search
+	showThinking := post || analyze
+	searchBoard.SetInfoHandler(func(info ghess.SearchInfo) {
+		if showThinking && info.CurrMoveNumber == 0 {
+			printThinking(info)
+		}
+	})
+	current = t
+	go func() {
+		ab := searchBoard.AlphaBetaEngineMove([30]ghess.Move{}, min(2, depth), depth, false, false, timeLimit)
+		t.result <- ab.Pv
+	}()
+}
+
+// stopThinking ends the running search and plays its move if play is set and it isn't an analysis
+func stopThinking(play bool) {
+	if current == nil {
+		return
+	}
+	close(current.stop)
+	pv := <-current.result
+	if play {
+		finishThinking(pv)
+	} else {
+		current = nil
+	}
+}
+
+// finishThinking plays the move of a finished search unless it was an analysis
+func finishThinking(pv [30]ghess.Move) {
+	t := current
+	current = nil
+	if !t.analyze {
+		playEngineMove(pv)
+	}
+}
+
+// printThinking prints "ply score time nodes pv" with the score in centipawns from the perspective of the engine
+// and the time in centiseconds
+func printThinking(info ghess.SearchInfo) {
+	score := int(info.Score)
+	if info.Mate > 0 {
+		score = MATE_SCORE + info.Mate
+	} else if info.Mate < 0 {
+		score = -MATE_SCORE + info.Mate
+	}
+	pv := make([]string, len(info.Pv))
+	for i := range info.Pv {
+		pv[i] = ghess.GetAlgebraicFromMove(&info.Pv[i])
+	}
+	fmt.Printf("%d %d %d %d %s\n", info.Depth, score, info.Time.Milliseconds()/10, info.Nodes, strings.Join(pv, " "))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
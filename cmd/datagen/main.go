@@ -0,0 +1,125 @@
+// Command datagen generates training data by letting the alpha beta engine play against itself from random openings
+// with a fixed number of nodes per move.
+//
+// Every quiet position is written as a line "fen | score | result" with the search score in centipawns and the game
+// result (1.0, 0.5 or 0.0) both from whites perspective, which can be read by the tune command.
+// Every game ends with a line "# game <n>" with the id n of the game which determines its opening. Games played in
+// parallel finish in any order, so when the output file already exists exactly the games missing in it are played.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+const GAME_MARKER = "# game "
+
+// resumeOutput truncates the output file after the last complete game and returns the ids of the complete games
+func resumeOutput(path string) (map[int]bool, error) {
+	games := map[int]bool{}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return games, nil
+	} else if err != nil {
+		return nil, err
+	}
+	end := 0
+	for offset := 0; offset < len(data); {
+		lineEnd := bytes.IndexByte(data[offset:], '\n')
+		if lineEnd < 0 {
+			// unfinished last line
+			break
+		}
+		line := string(data[offset : offset+lineEnd])
+		offset += lineEnd + 1
+		if strings.HasPrefix(line, GAME_MARKER) {
+			id, err := strconv.Atoi(strings.TrimPrefix(line, GAME_MARKER))
+			if err != nil {
+				return nil, fmt.Errorf("invalid game marker %q", line)
+			}
+			games[id] = true
+			end = offset
+		}
+	}
+	return games, os.Truncate(path, int64(end))
+}
+
+func main() {
+	outPath := flag.String("out", "selfplay.txt", "output file which gets continued if it exists")
+	numGames := flag.Int("games", 1000, "total number of games in the output file")
+	nodes := flag.Int("nodes", 5000, "nodes per move")
+	randomPlies := flag.Int("random", 8, "number of random moves at the start of each game")
+	maxPlies := flag.Int("maxplies", 300, "games are adjudicated as a draw after this many plies")
+	threads := flag.Int("threads", runtime.NumCPU(), "number of games played in parallel")
+	seed := flag.Int64("seed", 1, "seed of the random openings. Game n uses seed + n")
+	flag.Parse()
+
+	completed, err := resumeOutput(*outPath)
+	if err != nil {
+		log.Fatalf("Reading %s failed: %s", *outPath, err)
+	}
+	completedGames := len(completed)
+	if completedGames > 0 {
+		log.Printf("Continuing after %d games", completedGames)
+	}
+	file, err := os.OpenFile(*outPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	gameIds := make(chan int)
+	go func() {
+		for id := 0; id < *numGames; id++ {
+			if !completed[id] {
+				gameIds <- id
+			}
+		}
+		close(gameIds)
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < *threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range gameIds {
+				positions := ghess.PlaySelfPlayGame(ghess.SelfPlayOptions{
+					RandomPlies: *randomPlies,
+					Nodes:       *nodes,
+					MaxPlies:    *maxPlies,
+					Rand:        rand.New(rand.NewSource(*seed + int64(id))),
+				})
+				var sb strings.Builder
+				for _, position := range positions {
+					sb.WriteString(position.String() + "\n")
+				}
+				mu.Lock()
+				completedGames++
+				sb.WriteString(fmt.Sprintf("%s%d\n", GAME_MARKER, id))
+				// a game is written at once such that an interrupted run loses at most the unfinished games
+				w := bufio.NewWriter(file)
+				w.WriteString(sb.String())
+				if err := w.Flush(); err != nil {
+					log.Fatalf("Writing %s failed: %s", *outPath, err)
+				}
+				log.Printf("game %d/%d: %d positions", completedGames, *numGames, len(positions))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestResumeOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selfplay.txt")
+	if games, err := resumeOutput(path); err != nil || len(games) != 0 {
+		t.Fatalf("Expected no games without an output file but got %v, %v", games, err)
+	}
+
+	// games played in parallel finish out of order and the run was interrupted while writing game 1
+	complete := "8/8/4k3/8/8/4K3/4P3/8 w - - 0 1 | 150 | 1.0\n" + GAME_MARKER + "2\n" +
+		"8/8/4k3/8/8/4K3/8/8 w - - 0 1 | 0 | 0.5\n" + GAME_MARKER + "0\n"
+	if err := ioutil.WriteFile(path, []byte(complete+"8/8/4k3/8/8/4K3/8/8 b - - 0 1 | 0 | 0.5\n8/8/4k3/8"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	games, err := resumeOutput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || !games[0] || !games[2] {
+		t.Errorf("Expected the complete games 0 and 2 but got %v", games)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != complete {
+		t.Errorf("Expected the output to be truncated after the last complete game but got %q", data)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(GAME_MARKER+"x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resumeOutput(path); err == nil {
+		t.Errorf("Expected an error for an invalid game marker")
+	}
+}
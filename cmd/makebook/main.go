@@ -0,0 +1,200 @@
+// Command makebook builds a Polyglot opening book from the first moves of the games in PGN files.
+//
+// Every move gets 2 points for each game the side playing it won and 1 point for each draw. The points are used as
+// the weight of the move in the book (scaled down if they don't fit into 16 bits). Moves which never scored are left
+// out as they would never be chosen.
+//
+//	makebook -out book.bin -plies 16 games1.pgn games2.pgn
+package main
+
+import (
+	"bufio"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// pgnGame is a game of a PGN file with its moves in standard algebraic notation
+type pgnGame struct {
+	fen    string // start position given by the FEN tag or the normal start position
+	moves  []string
+	result string
+}
+
+// bookKey identifies a move in a position
+type bookKey struct {
+	key  uint64
+	move uint16
+}
+
+// bookStats counts how often a move was played and the points it scored
+type bookStats struct {
+	games  int
+	points int
+}
+
+// readPGN calls handleGame for every game in r. Comments, variations and numeric annotations are skipped.
+func readPGN(r io.Reader, handleGame func(pgnGame)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	game := pgnGame{fen: ghess.START_FEN}
+	inComment := false
+	variationDepth := 0
+	token := ""
+	flushToken := func() {
+		// remove move numbers like 12. or 12...
+		if i := strings.LastIndex(token, "."); i >= 0 {
+			token = token[i+1:]
+		}
+		switch {
+		case token == "" || strings.HasPrefix(token, "$"):
+		case token == "1-0" || token == "0-1" || token == "1/2-1/2" || token == "*":
+			game.result = token
+			handleGame(game)
+			game = pgnGame{fen: ghess.START_FEN}
+		default:
+			game.moves = append(game.moves, token)
+		}
+		token = ""
+	}
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !inComment && variationDepth == 0 && strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[FEN ") {
+				game.fen = strings.Trim(strings.TrimPrefix(line, "[FEN "), "\"]")
+			}
+			continue
+		}
+		for _, c := range line + " " {
+			if inComment {
+				inComment = c != '}'
+				continue
+			}
+			if c == ';' && variationDepth == 0 {
+				// the rest of the line is a comment
+				break
+			}
+			switch c {
+			case '{':
+				flushToken()
+				inComment = true
+			case '(':
+				flushToken()
+				variationDepth++
+			case ')':
+				variationDepth--
+			case ' ', '\t':
+				if variationDepth == 0 {
+					flushToken()
+				}
+			default:
+				if variationDepth == 0 {
+					token += string(c)
+				}
+			}
+		}
+		if variationDepth == 0 {
+			flushToken()
+		}
+	}
+	return scanner.Err()
+}
+
+// addGame adds the first plies of game to stats and returns false if a move couldn't be read
+func addGame(stats map[bookKey]*bookStats, game pgnGame, plies int) bool {
+	whitePoints := map[string]int{"1-0": 2, "1/2-1/2": 1, "0-1": 0}
+	points, ok := whitePoints[game.result]
+	if !ok {
+		// unfinished game
+		return true
+	}
+	board := ghess.GetBoardFromFen(game.fen)
+	for ply, moveStr := range game.moves {
+		if ply >= plies {
+			break
+		}
+		move, err := board.GetMoveFromStandardAlgebraic(moveStr)
+		if err != nil {
+			return false
+		}
+		key := bookKey{board.PolyglotKey(), board.PolyglotMove(&move)}
+		if stats[key] == nil {
+			stats[key] = &bookStats{}
+		}
+		stats[key].games++
+		if board.IsBlacksTurn {
+			stats[key].points += 2 - points
+		} else {
+			stats[key].points += points
+		}
+		board.Move(&move)
+	}
+	return true
+}
+
+func main() {
+	outPath := flag.String("out", "book.bin", "output file of the Polyglot book")
+	plies := flag.Int("plies", 16, "number of plies of each game which are added to the book")
+	minGames := flag.Int("mingames", 1, "minimum number of games a move needs to be played in to be added")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("Usage: makebook [flags] games.pgn ...")
+	}
+
+	stats := make(map[bookKey]*bookStats)
+	numGames := 0
+	skipped := 0
+	for _, path := range flag.Args() {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = readPGN(bufio.NewReader(file), func(game pgnGame) {
+			numGames++
+			if !addGame(stats, game, *plies) {
+				skipped++
+			}
+		})
+		file.Close()
+		if err != nil {
+			log.Fatalf("Reading %s failed: %s", path, err)
+		}
+	}
+	if skipped > 0 {
+		log.Printf("%d games contained an illegal move and were only added up to that move", skipped)
+	}
+
+	maxPoints := 0
+	for _, s := range stats {
+		if s.games >= *minGames && s.points > maxPoints {
+			maxPoints = s.points
+		}
+	}
+	entries := []ghess.BookEntry{}
+	for key, s := range stats {
+		if s.games < *minGames || s.points == 0 {
+			continue
+		}
+		weight := s.points
+		if maxPoints > 0xFFFF {
+			weight = max(1, s.points*0xFFFF/maxPoints)
+		}
+		entries = append(entries, ghess.BookEntry{Key: key.key, Move: key.move, Weight: uint16(weight)})
+	}
+	book := ghess.NewBook(entries)
+	if err := book.WriteFile(*outPath); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %d entries from %d games to %s", book.Len(), numGames, *outPath)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Wikunia/Ghess/ghess"
+	"github.com/Wikunia/Ghess/ghess/uciclient"
+)
+
+// player is an engine of the match. Every game which runs in parallel has its own player for each engine.
+type player struct {
+	spec    string            // name of a built-in engine or path of a UCI engine
+	options map[string]string // UCI options
+	engine  ghess.Engine
+	uci     *uciclient.Engine // nil for built-in engines
+}
+
+// parseOptions reads UCI options given as "Name=value,Name=value"
+func parseOptions(s string) (map[string]string, error) {
+	options := map[string]string{}
+	for _, option := range strings.Split(s, ",") {
+		if strings.TrimSpace(option) == "" {
+			continue
+		}
+		parts := strings.SplitN(option, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("option %q is not of the form name=value", option)
+		}
+		options[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return options, nil
+}
+
+// newPlayer creates a built-in engine by its name or starts the UCI engine at the path of spec
+func newPlayer(spec string, options map[string]string) (*player, error) {
+	p := &player{spec: spec, options: options}
+	if engine, err := ghess.NewEngine(spec); err == nil {
+		if len(options) > 0 {
+			return nil, fmt.Errorf("options can only be set for UCI engines")
+		}
+		p.engine = engine
+		return p, nil
+	}
+	return p, p.start()
+}
+
+// start launches the UCI engine and sets its options
+func (p *player) start() error {
+	engine, err := uciclient.Start(p.spec)
+	if err != nil {
+		return err
+	}
+	for name, value := range p.options {
+		if err := engine.SetOption(name, value); err != nil {
+			engine.Kill()
+			return fmt.Errorf("setting option %s of %s failed: %w", name, p.spec, err)
+		}
+	}
+	p.uci = engine
+	p.engine = engine
+	return nil
+}
+
+// checkEngine restarts a UCI engine which crashed or stopped answering in the last game
+func (p *player) checkEngine() error {
+	if p.uci == nil || p.uci.Err() == nil {
+		return nil
+	}
+	p.uci.Kill()
+	return p.start()
+}
+
+// close quits a UCI engine
+func (p *player) close() {
+	if p.uci != nil {
+		p.uci.Quit()
+	}
+}
@@ -0,0 +1,297 @@
+// Command match plays games between two engines and reports the result with the Elo difference.
+//
+// Engines are given by the name of a built-in engine (random, captureRandom, checkCaptureRandom, alphaBeta or mcts)
+// or the path of a UCI engine. Every opening is played twice with reversed colours.
+// With -sprt the match stops as soon as a sequential probability ratio test accepts that the first engine is at most
+// elo0 or at least elo1 Elo stronger.
+//
+//	match -engine1 alphaBeta -engine2 ../uci/uci -options2 Hash=16 -games 200 -tc 10+0.1 -openings book.epd -pgnout games.pgn
+//	match -engine1 ./new -engine2 ./old -games 20000 -tc 10+0.1 -concurrency 8 -sprt -elo0 0 -elo1 5
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// parseTimeControl reads "moves/seconds+increment" where moves and the increment are optional
+func parseTimeControl(s string) (ghess.TimeControl, error) {
+	tc := ghess.TimeControl{}
+	if s == "" {
+		return tc, nil
+	}
+	if parts := strings.SplitN(s, "/", 2); len(parts) == 2 {
+		moves, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return tc, fmt.Errorf("invalid number of moves in time control %s", s)
+		}
+		tc.MovesToGo = moves
+		s = parts[1]
+	}
+	parts := strings.SplitN(s, "+", 2)
+	seconds, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || seconds <= 0 {
+		return tc, fmt.Errorf("invalid time in time control %s", s)
+	}
+	tc.Time = time.Duration(seconds * float64(time.Second))
+	if len(parts) == 2 {
+		increment, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return tc, fmt.Errorf("invalid increment in time control %s", s)
+		}
+		tc.Increment = time.Duration(increment * float64(time.Second))
+	}
+	return tc, nil
+}
+
+// results counts the games and the game pairs with the same opening from the perspective of the first engine
+type results struct {
+	wins, draws, losses int
+	pairs               [5]int          // number of pairs by the half points scored in them
+	pending             map[int]float64 // score of the first finished game of the unfinished pairs
+}
+
+// add counts the game with the given id. The first engine plays white in even games.
+func (r *results) add(id int, game ghess.Game) {
+	score := game.WhiteScore()
+	if id%2 == 1 {
+		score = 1 - score
+	}
+	switch score {
+	case 1:
+		r.wins++
+	case 0:
+		r.losses++
+	default:
+		r.draws++
+	}
+	if other, ok := r.pending[id/2]; ok {
+		r.pairs[int(2*(score+other))]++
+		delete(r.pending, id/2)
+	} else {
+		r.pending[id/2] = score
+	}
+}
+
+func (r results) games() int {
+	return r.wins + r.draws + r.losses
+}
+
+// String returns "W - L - D [score] games"
+func (r results) String() string {
+	score := 0.0
+	if r.games() > 0 {
+		score = (float64(r.wins) + 0.5*float64(r.draws)) / float64(r.games())
+	}
+	return fmt.Sprintf("%d - %d - %d [%.3f] %d", r.wins, r.losses, r.draws, score, r.games())
+}
+
+func main() {
+	engine1 := flag.String("engine1", "alphaBeta", "first engine: built-in engine name or path of a UCI engine")
+	engine2 := flag.String("engine2", "random", "second engine: built-in engine name or path of a UCI engine")
+	options1 := flag.String("options1", "", "UCI options of the first engine as Name=value,Name=value")
+	options2 := flag.String("options2", "", "UCI options of the second engine as Name=value,Name=value")
+	name1 := flag.String("name1", "", "name of the first engine in the output (default: its name)")
+	name2 := flag.String("name2", "", "name of the second engine in the output (default: its name)")
+	numGames := flag.Int("games", 100, "number of games (rounded up to an even number)")
+	concurrency := flag.Int("concurrency", 1, "number of games played in parallel")
+	tcFlag := flag.String("tc", "", "time control as moves/seconds+increment, e.g. 40/60 or 10+0.1")
+	moveTime := flag.Float64("st", 0, "seconds per move")
+	depth := flag.Int("depth", 0, "depth per move")
+	nodes := flag.Int("nodes", 0, "nodes per move")
+	openingsPath := flag.String("openings", "", "EPD or PGN file with the start positions (default: start position)")
+	pgnOut := flag.String("pgnout", "", "file all games are written to in PGN")
+	event := flag.String("event", "Ghess match", "event of the games in the PGN")
+	resignScore := flag.Int("resignscore", 1000, "centipawns for a resign adjudication")
+	resignMoves := flag.Int("resignmoves", 0, "moves of each engine with a resign score before a game is adjudicated (0 disables)")
+	drawScore := flag.Int("drawscore", 10, "centipawns within which the scores have to stay for a draw adjudication")
+	drawMoves := flag.Int("drawmoves", 0, "moves of each engine with a draw score before a game is adjudicated (0 disables)")
+	drawMoveNumber := flag.Int("drawmovenumber", 40, "first move number at which a draw is adjudicated")
+	syzygyPath := flag.String("syzygy", "", "directory of Syzygy tablebases used to adjudicate games")
+	maxPlies := flag.Int("maxplies", 0, "games are adjudicated as a draw after this many plies")
+	useSPRT := flag.Bool("sprt", false, "stop as soon as the sequential probability ratio test accepts H0 or H1 "+
+		"(games is the maximum number of games)")
+	elo0 := flag.Float64("elo0", 0, "Elo difference of H0 of the SPRT")
+	elo1 := flag.Float64("elo1", 5, "Elo difference of H1 of the SPRT")
+	alpha := flag.Float64("alpha", 0.05, "probability of the SPRT to accept H1 if H0 is true")
+	beta := flag.Float64("beta", 0.05, "probability of the SPRT to accept H0 if H1 is true")
+	model := flag.String("model", "pentanomial", "model of the SPRT: pentanomial (game pairs) or trinomial (games)")
+	flag.Parse()
+
+	sprt := ghess.SPRT{Elo0: *elo0, Elo1: *elo1, Alpha: *alpha, Beta: *beta}
+	if *useSPRT {
+		if err := sprt.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		if *model != "pentanomial" && *model != "trinomial" {
+			log.Fatalf("unknown SPRT model %s", *model)
+		}
+	}
+
+	tc, err := parseTimeControl(*tcFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tc.MoveTime = time.Duration(*moveTime * float64(time.Second))
+	tc.Depth = *depth
+	tc.Nodes = *nodes
+	adjudication := ghess.Adjudication{
+		ResignScore:    *resignScore,
+		ResignMoves:    *resignMoves,
+		DrawScore:      *drawScore,
+		DrawMoves:      *drawMoves,
+		DrawMoveNumber: *drawMoveNumber,
+		MaxPlies:       *maxPlies,
+	}
+	if *syzygyPath != "" {
+		n, err := ghess.InitSyzygy(*syzygyPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Found %d tablebase files", n)
+		adjudication.Tablebases = true
+	}
+	openings := []ghess.Opening{{Fen: ghess.START_FEN}}
+	if *openingsPath != "" {
+		openings, err = ghess.ReadOpenings(*openingsPath)
+		if err != nil {
+			log.Fatalf("Reading %s failed: %s", *openingsPath, err)
+		}
+		if len(openings) == 0 {
+			log.Fatalf("%s contains no openings", *openingsPath)
+		}
+	}
+	var pgnFile *os.File
+	if *pgnOut != "" {
+		pgnFile, err = os.Create(*pgnOut)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer pgnFile.Close()
+	}
+	specs := [2]string{*engine1, *engine2}
+	optionStrings := [2]string{*options1, *options2}
+	options := [2]map[string]string{}
+	for i := range options {
+		if options[i], err = parseOptions(optionStrings[i]); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// each worker has its own engines as they keep state between the moves of a game
+	workers := make([][2]*player, *concurrency)
+	for w := range workers {
+		for i := range specs {
+			p, err := newPlayer(specs[i], options[i])
+			if err != nil {
+				log.Fatalf("Starting %s failed: %s", specs[i], err)
+			}
+			defer p.close()
+			workers[w][i] = p
+		}
+	}
+	names := [2]string{*name1, *name2}
+	for i := range names {
+		if names[i] == "" {
+			names[i] = workers[0][i].engine.Name()
+		}
+	}
+	if names[0] == names[1] {
+		names[0] += " (1)"
+		names[1] += " (2)"
+	}
+
+	total := *numGames + *numGames%2
+	gameIds := make(chan int)
+	stop := make(chan bool) // closed when the SPRT decided
+	go func() {
+		defer close(gameIds)
+		for id := 0; id < total; id++ {
+			select {
+			case gameIds <- id:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	r := results{pending: map[int]float64{}}
+	status := ghess.SPRT_CONTINUE
+	llr := func() float64 {
+		if *model == "trinomial" {
+			return sprt.LLRTrinomial(r.wins, r.draws, r.losses)
+		}
+		return sprt.LLRPentanomial(r.pairs)
+	}
+	lower, upper := sprt.Bounds()
+	for _, players := range workers {
+		wg.Add(1)
+		go func(players [2]*player) {
+			defer wg.Done()
+			for id := range gameIds {
+				// the first engine plays white in even games and black in the repetition with reversed colours
+				white, black := players[0], players[1]
+				whiteName, blackName := names[0], names[1]
+				if id%2 == 1 {
+					white, black = black, white
+					whiteName, blackName = blackName, whiteName
+				}
+				game := ghess.PlayGame(white.engine, black.engine, ghess.GameOptions{
+					Opening:      openings[(id/2)%len(openings)],
+					TimeControl:  tc,
+					Adjudication: adjudication,
+				})
+				game.White, game.Black = whiteName, blackName
+				for _, p := range players {
+					if err := p.checkEngine(); err != nil {
+						log.Fatalf("Restarting %s failed: %s", p.spec, err)
+					}
+				}
+
+				mu.Lock()
+				r.add(id, game)
+				log.Printf("Game %d/%d: %s vs %s %s {%s}", id+1, total, whiteName, blackName, game.Result, game.Termination)
+				log.Printf("Score of %s vs %s: %s", names[0], names[1], r)
+				if *useSPRT && status == ghess.SPRT_CONTINUE {
+					log.Printf("LLR: %.2f (%.2f, %.2f) [%.2f, %.2f]", llr(), lower, upper, sprt.Elo0, sprt.Elo1)
+					// the games which are still running are finished and counted
+					if status = sprt.Status(llr()); status != ghess.SPRT_CONTINUE {
+						close(stop)
+					}
+				}
+				if pgnFile != nil {
+					if _, err := pgnFile.WriteString(game.PGN(*event, id+1)); err != nil {
+						log.Fatalf("Writing %s failed: %s", *pgnOut, err)
+					}
+				}
+				mu.Unlock()
+			}
+		}(players)
+	}
+	wg.Wait()
+
+	elo, margin := ghess.EloDifference(r.wins, r.draws, r.losses)
+	fmt.Printf("Score of %s vs %s: %s\n", names[0], names[1], r)
+	fmt.Printf("Elo difference: %.1f +/- %.1f\n", elo, margin)
+	if *useSPRT {
+		fmt.Printf("SPRT: llr %.2f (%.2f, %.2f) [%.2f, %.2f], %s model\n", llr(), lower, upper, sprt.Elo0, sprt.Elo1, *model)
+		switch status {
+		case ghess.SPRT_H0:
+			fmt.Println("H0 was accepted")
+		case ghess.SPRT_H1:
+			fmt.Println("H1 was accepted")
+		default:
+			fmt.Println("The test is inconclusive")
+		}
+	}
+}
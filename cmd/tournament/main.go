@@ -0,0 +1,85 @@
+// Command tournament plays a round robin or Swiss tournament between built-in engines and prints the crosstable and
+// the standings.
+//
+// Players are given as "[name=]engine[:key=value,...]" with the settings depth, nodes, movetime, threads and
+// exploration, for example
+//
+//	tournament -format swiss -rounds 5 -depth 3 -pgnout games.pgn -tableout table.txt random captureRandom \
+//		checkCaptureRandom alphaBeta ab2=alphaBeta:depth=2 mcts:threads=2
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/Wikunia/Ghess/ghess"
+	"github.com/Wikunia/Ghess/ghess/tournament"
+)
+
+func main() {
+	format := flag.String("format", tournament.ROUND_ROBIN, "tournament format: roundrobin or swiss")
+	rounds := flag.Int("rounds", 0, "cycles of a round robin or rounds of a Swiss tournament (default: 1 or log2 of the players)")
+	concurrency := flag.Int("concurrency", 1, "number of games played in parallel")
+	moveTime := flag.Float64("st", 0, "seconds per move")
+	depth := flag.Int("depth", 0, "depth per move")
+	nodes := flag.Int("nodes", 0, "nodes per move")
+	openingsPath := flag.String("openings", "", "EPD or PGN file with the start positions of the rounds")
+	maxPlies := flag.Int("maxplies", 0, "games are adjudicated as a draw after this many plies")
+	pgnOut := flag.String("pgnout", "", "file all games are written to in PGN")
+	tableOut := flag.String("tableout", "", "file the crosstable and the standings are written to")
+	event := flag.String("event", "Ghess tournament", "event of the games in the PGN")
+	flag.Parse()
+
+	players := []tournament.Player{}
+	for _, spec := range flag.Args() {
+		player, err := tournament.ParsePlayer(spec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		players = append(players, player)
+	}
+	options := tournament.Options{
+		Format: *format,
+		Rounds: *rounds,
+		Game: ghess.GameOptions{
+			TimeControl: ghess.TimeControl{
+				MoveTime: time.Duration(*moveTime * float64(time.Second)),
+				Depth:    *depth,
+				Nodes:    *nodes,
+			},
+			Adjudication: ghess.Adjudication{MaxPlies: *maxPlies},
+		},
+		Concurrency: *concurrency,
+		OnGame: func(record tournament.GameRecord) {
+			log.Printf("Round %d: %s vs %s %s {%s}", record.Round, record.Game.White, record.Game.Black,
+				record.Game.Result, record.Game.Termination)
+		},
+	}
+	if *openingsPath != "" {
+		openings, err := ghess.ReadOpenings(*openingsPath)
+		if err != nil {
+			log.Fatalf("Reading %s failed: %s", *openingsPath, err)
+		}
+		options.Openings = openings
+	}
+
+	t, err := tournament.Run(players, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	table := t.Crosstable() + "\n" + t.Table()
+	fmt.Print(table)
+	if *pgnOut != "" {
+		if err := ioutil.WriteFile(*pgnOut, []byte(t.PGN(*event)), 0644); err != nil {
+			log.Fatalf("Writing %s failed: %s", *pgnOut, err)
+		}
+	}
+	if *tableOut != "" {
+		if err := ioutil.WriteFile(*tableOut, []byte(table), 0644); err != nil {
+			log.Fatalf("Writing %s failed: %s", *tableOut, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Command tune optimizes the evaluation weights on a file of quiet positions labelled with game results (Texel tuning)
+// and writes them to a parameter file the engine can load.
+//
+// Every line of the data file consists of a fen followed by the result of the game, for example
+//
+//	rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1 [0.5]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// readPositions reads the labelled positions of the data file and skips empty lines and comments starting with #
+func readPositions(path string) ([]ghess.TuningPosition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var positions []ghess.TuningPosition
+	scanner := bufio.NewScanner(file)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		position, err := ghess.ParseTuningPosition(line)
+		if err != nil {
+			log.Printf("Skipping line %d: %s", lineNr, err)
+			continue
+		}
+		positions = append(positions, position)
+	}
+	return positions, scanner.Err()
+}
+
+func main() {
+	dataPath := flag.String("data", "", "file with one fen and game result per line")
+	paramsPath := flag.String("params", "", "parameter file to start from (default: built in weights)")
+	outPath := flag.String("out", "evalparams.json", "file the tuned weights get written to")
+	epochs := flag.Int("epochs", 1000, "number of gradient descent steps")
+	learningRate := flag.Float64("lr", 1.0, "learning rate in centipawns")
+	k := flag.Float64("k", 0, "sigmoid scaling (computed from the data if 0)")
+	flag.Parse()
+
+	if *dataPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	params := ghess.DefaultEvalParams()
+	if *paramsPath != "" {
+		var err error
+		params, err = ghess.ReadEvalParams(*paramsPath)
+		if err != nil {
+			log.Fatalf("Reading %s failed: %s", *paramsPath, err)
+		}
+	}
+
+	positions, err := readPositions(*dataPath)
+	if err != nil {
+		log.Fatalf("Reading %s failed: %s", *dataPath, err)
+	}
+
+	options := ghess.TunerOptions{Epochs: *epochs, LearningRate: *learningRate, K: *k, Log: os.Stdout}
+	tuned, err := ghess.TuneEvalParams(positions, params, options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// a diverged run would write weights which can't be loaded
+	if err := tuned.Validate(); err != nil {
+		log.Fatalf("The tuned weights are invalid: %s", err)
+	}
+	if err := tuned.WriteFile(*outPath); err != nil {
+		log.Fatalf("Writing %s failed: %s", *outPath, err)
+	}
+	log.Printf("Wrote the tuned weights to %s", *outPath)
+}
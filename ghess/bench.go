@@ -0,0 +1,45 @@
+package ghess
+
+import (
+	"math/rand"
+	"time"
+)
+
+// BENCH_DEPTH is the default depth of the bench positions
+const BENCH_DEPTH = 3
+
+// BENCH_SEED seeds the random move ordering such that the number of nodes of the bench is reproducible
+const BENCH_SEED = 42
+
+// benchFens are the positions of the bench covering the opening, tactical middlegames and endgames
+var benchFens = []string{
+	START_FEN,
+	"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r1bq1rk1/pp2ppbp/2np1np1/8/3NP3/2N1BP2/PPPQ2PP/R3KB1R w KQ - 3 9",
+	"2r3k1/pp3ppp/2n1p3/3pP3/3P1P2/P1R3P1/1P4KP/8 b - - 0 27",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	"8/8/4k3/8/2p5/8/B2P2K1/8 w - - 0 1",
+	"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1",
+}
+
+// BenchResult is the total number of nodes and time of the bench
+type BenchResult struct {
+	Nodes int
+	Time  time.Duration
+}
+
+// Bench searches all bench positions to the given depth with the alpha beta search. The number of nodes only
+// changes if the search or the evaluation changes such that it can be used as a signature of the engine.
+func Bench(depth int) BenchResult {
+	result := BenchResult{}
+	for _, fen := range benchFens {
+		board := GetBoardFromFen(fen)
+		board.moveOrderRand = rand.New(rand.NewSource(BENCH_SEED))
+		startTime := time.Now()
+		ab := board.AlphaBetaEngineMove([30]Move{}, min(2, depth), depth, false, false, 1000*60*60*24)
+		result.Time += time.Since(startTime)
+		result.Nodes += ab.NodesSearched
+	}
+	return result
+}
@@ -512,11 +512,14 @@ func (board *Board) TempMove(m *Move) Move {
 	}
 
 	if m.captureId != 0 {
+		captured := &board.pieces[m.captureId]
+		board.addPieceScore(captured.pieceType, captured.isBlack, captured.pos, -1)
 		// important for en passant
-		board.pos2PieceId[board.pieces[m.captureId].pos] = 0
-		board.pieces[m.captureId].pos = -1
-		board.pieces[m.captureId].posB = 0
+		board.pos2PieceId[captured.pos] = 0
+		captured.pos = -1
+		captured.posB = 0
 	}
+	board.addPieceScore(board.pieces[m.PieceId].pieceType, board.pieces[m.PieceId].isBlack, m.from, -1)
 	board.pieces[m.PieceId].pos = m.to
 	board.pieces[m.PieceId].posB = 1 << m.to
 	board.pos2PieceId[m.from] = 0
@@ -536,6 +539,7 @@ func (board *Board) TempMove(m *Move) Move {
 			board.pieces[m.PieceId].pieceType = 'n'
 		}
 	}
+	board.addPieceScore(board.pieces[m.PieceId].pieceType, board.pieces[m.PieceId].isBlack, m.to, 1)
 
 	board.whitePiecePosB = board.combinePositionsOf(board.whiteIds)
 	board.blackPiecePosB = board.combinePositionsOf(board.blackIds)
@@ -628,6 +632,8 @@ func (board *Board) reverseMove(m *Move, boardPrimitives *BoardPrimitives) Move
 			board.pieces[m.captureId].posB = 1 << m.to
 			board.pos2PieceId[m.to] = m.captureId
 		}
+		captured := &board.pieces[m.captureId]
+		board.addPieceScore(captured.pieceType, captured.isBlack, captured.pos, 1)
 		// need to update the combined positions
 		board.whitePiecePosB = board.combinePositionsOf(board.whiteIds)
 		board.blackPiecePosB = board.combinePositionsOf(board.blackIds)
@@ -731,8 +737,95 @@ func (board *Board) countMaterialOfColor(isBlack bool) int {
 	materialCount := 0
 	for _, PieceId := range PieceIds {
 		if board.pieces[PieceId].posB != 0 {
-			materialCount += materialCountMap[board.pieces[PieceId].pieceType]
+			materialCount += evalParams.MgPieceValue[pieceIndex(board.pieces[PieceId].pieceType)]
 		}
 	}
 	return materialCount
 }
+
+var knightAttacksB = getKnightAttacks()
+var kingAttacksB = getKingAttacks()
+
+// getKnightAttacks returns for every square the squares a knight on that square attacks
+func getKnightAttacks() [64]uint64 {
+	var attacks [64]uint64
+	jumps := [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	for pos := 0; pos < 64; pos++ {
+		x, y := xy(pos)
+		for _, jump := range jumps {
+			tx, ty := x+jump[0], y+jump[1]
+			if tx >= 0 && tx < 8 && ty >= 0 && ty < 8 {
+				attacks[pos] |= 1 << (ty*8 + tx)
+			}
+		}
+	}
+	return attacks
+}
+
+// getKingAttacks returns for every square the squares a king on that square attacks
+func getKingAttacks() [64]uint64 {
+	var attacks [64]uint64
+	for pos := 0; pos < 64; pos++ {
+		x, y := xy(pos)
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				tx, ty := x+dx, y+dy
+				if (dx != 0 || dy != 0) && tx >= 0 && tx < 8 && ty >= 0 && ty < 8 {
+					attacks[pos] |= 1 << (ty*8 + tx)
+				}
+			}
+		}
+	}
+	return attacks
+}
+
+// attacksOf returns the squares a piece attacks. In contrast to movementB this ignores pins, checks and castling
+// and includes squares occupied by pieces of the same color.
+func (board *Board) attacksOf(piece *Piece) uint64 {
+	if piece.posB == 0 {
+		return 0
+	}
+	switch piece.pieceType {
+	case PAWN:
+		return pawnAttacks(piece.posB, piece.isBlack)
+	case KNIGHT:
+		return knightAttacksB[piece.pos]
+	case KING:
+		return kingAttacksB[piece.pos]
+	}
+	directions := [8]int{NORTH, SOUTH, WEST, EAST, NORTH_EAST, NORTH_WEST, SOUTH_EAST, SOUTH_WEST}
+	startDir := 0
+	endDir := 8
+	switch piece.pieceType {
+	case ROOK:
+		endDir = 4
+	case BISHOP:
+		startDir = 4
+	}
+	occupiedB := board.whitePiecePosB | board.blackPiecePosB
+	var attacksB uint64
+	for dirId := startDir; dirId < endDir; dirId++ {
+		pos := piece.pos
+		for step := 1; step <= board.movesTilEdge[piece.pos][dirId]; step++ {
+			pos += directions[dirId]
+			attacksB |= 1 << pos
+			if occupiedB&(1<<pos) != 0 {
+				break
+			}
+		}
+	}
+	return attacksB
+}
+
+// attackMap returns all squares attacked by the pieces of one color
+func (board *Board) attackMap(isBlack bool) uint64 {
+	PieceIds := board.whiteIds
+	if isBlack {
+		PieceIds = board.blackIds
+	}
+	var attacksB uint64
+	for _, PieceId := range PieceIds {
+		attacksB |= board.attacksOf(&board.pieces[PieceId])
+	}
+	return attacksB
+}
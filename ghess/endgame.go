@@ -0,0 +1,395 @@
+package ghess
+
+import (
+	"math/bits"
+	"strings"
+	"sync"
+)
+
+// KNOWN_WIN_SCORE is added to the evaluation of endgames which are known to be won. It stays below the mate threshold of the search.
+const KNOWN_WIN_SCORE = 3000
+
+// Scale factors of the evaluation in 64ths for drawish endgames
+const (
+	SCALE_NORMAL                         = 64
+	SCALE_OPPOSITE_BISHOPS_WITH_ROOKS    = 48
+	SCALE_OPPOSITE_BISHOPS               = 32
+	SCALE_OPPOSITE_BISHOPS_SIMILAR_PAWNS = 16
+	SCALE_DRAW                           = 0
+)
+
+// materialSignature encodes the number of pieces of every type (indexed like pieceMap) for both colors
+type materialSignature uint64
+
+// endgameEvaluator returns the score of an endgame from whites perspective where strongIsBlack is the color of the stronger side
+type endgameEvaluator func(board *Board, strongIsBlack bool) float64
+
+type endgameEntry struct {
+	name          string
+	evaluate      endgameEvaluator
+	strongIsBlack bool
+}
+
+var endgames = map[materialSignature]endgameEntry{}
+
+func init() {
+	addEndgame("KPvK", evaluateKPK)
+	addEndgame("KBNvK", evaluateKBNK)
+	addEndgame("KRvK", evaluateKXK)
+	addEndgame("KQvK", evaluateKXK)
+}
+
+// addEndgame registers an evaluator for an endgame like KPvK and for the same endgame with the colors swapped
+func addEndgame(code string, evaluate endgameEvaluator) {
+	sides := strings.Split(code, "v")
+	var counts [2][6]int
+	for c, side := range sides {
+		for _, r := range side {
+			counts[c][pieceIndex(r-'A'+'a')]++
+		}
+	}
+	name := strings.Replace(code, "v", "", 1)
+	endgames[signatureOf(counts)] = endgameEntry{name: name, evaluate: evaluate}
+	endgames[signatureOf([2][6]int{counts[1], counts[0]})] = endgameEntry{name: name, evaluate: evaluate, strongIsBlack: true}
+}
+
+func signatureOf(counts [2][6]int) materialSignature {
+	var signature materialSignature
+	for c := 0; c < 2; c++ {
+		for t := 0; t < 6; t++ {
+			signature |= materialSignature(counts[c][t]) << (4 * (6*c + t))
+		}
+	}
+	return signature
+}
+
+// materialCounts returns the number of pieces of every type (indexed like pieceMap) for white and black
+func (board *Board) materialCounts() [2][6]int {
+	var counts [2][6]int
+	for _, piece := range board.pieces {
+		if piece.posB == 0 || piece.id == 0 {
+			continue
+		}
+		c := 0
+		if piece.isBlack {
+			c = 1
+		}
+		counts[c][pieceIndex(piece.pieceType)]++
+	}
+	return counts
+}
+
+// findPiece returns the position of a piece of the given type and color or -1 if there is none
+func (board *Board) findPiece(isBlack bool, pieceType rune) int {
+	PieceIds := board.whiteIds
+	if isBlack {
+		PieceIds = board.blackIds
+	}
+	for _, PieceId := range PieceIds {
+		piece := &board.pieces[PieceId]
+		if piece.posB != 0 && piece.pieceType == pieceType {
+			return piece.pos
+		}
+	}
+	return -1
+}
+
+// evaluateEndgame returns the score of a specialised endgame evaluator and its name if there is one for the material on the board
+func (board *Board) evaluateEndgame() (string, float64, bool) {
+	// all endgames with an evaluator have at most a queen besides pawns and kings
+	if board.phase > 4 {
+		return "", 0, false
+	}
+	entry, ok := endgames[signatureOf(board.materialCounts())]
+	if !ok {
+		return "", 0, false
+	}
+	return entry.name, entry.evaluate(board, entry.strongIsBlack), true
+}
+
+// strongScore converts a score from the perspective of the stronger side to whites perspective
+func strongScore(score int, strongIsBlack bool) float64 {
+	if strongIsBlack {
+		return -float64(score)
+	}
+	return float64(score)
+}
+
+// pushClose is a bonus for the strong king being close to the weak king
+func pushClose(a, b int) int {
+	return 140 - 20*distance(a, b)
+}
+
+// pushToEdge is a bonus for the weak king being close to the edge of the board
+func pushToEdge(pos int) int {
+	x, y := xy(pos)
+	fd := min(x, 7-x)
+	rd := min(y, 7-y)
+	return 90 - (7*fd*fd/2 + 7*rd*rd/2)
+}
+
+// pushToCorner is a bonus for the weak king being close to the a1 or h8 corner
+func pushToCorner(pos int) int {
+	x, y := xy(pos)
+	return abs(x - y)
+}
+
+// evaluateKXK drives the weak king to the edge in KQK and KRK
+func evaluateKXK(board *Board, strongIsBlack bool) float64 {
+	strongKing := board.findPiece(strongIsBlack, KING)
+	weakKing := board.findPiece(!strongIsBlack, KING)
+	material := 0
+	if board.findPiece(strongIsBlack, QUEEN) >= 0 {
+		material = evalParams.EgPieceValue[pieceIndex(QUEEN)]
+	} else {
+		material = evalParams.EgPieceValue[pieceIndex(ROOK)]
+	}
+	return strongScore(KNOWN_WIN_SCORE+material+pushToEdge(weakKing)+pushClose(strongKing, weakKing), strongIsBlack)
+}
+
+// evaluateKBNK drives the weak king to a corner of the color of the bishop
+func evaluateKBNK(board *Board, strongIsBlack bool) float64 {
+	strongKing := board.findPiece(strongIsBlack, KING)
+	weakKing := board.findPiece(!strongIsBlack, KING)
+	bishop := board.findPiece(strongIsBlack, BISHOP)
+	// pushToCorner drives to a1 and h8 which are dark squares, for a light squared bishop the board gets mirrored
+	if isLightSquare(bishop) {
+		weakKing ^= 7
+	}
+	score := KNOWN_WIN_SCORE + evalParams.EgPieceValue[pieceIndex(BISHOP)] + evalParams.EgPieceValue[pieceIndex(KNIGHT)] +
+		pushClose(strongKing, weakKing) + 40*pushToCorner(weakKing)
+	return strongScore(score, strongIsBlack)
+}
+
+// isLightSquare returns whether the square at pos is a light square (a8 is light)
+func isLightSquare(pos int) bool {
+	x, y := xy(pos)
+	return (x+y)%2 == 0
+}
+
+// evaluateKPK looks the position up in the KPK bitbase
+func evaluateKPK(board *Board, strongIsBlack bool) float64 {
+	// positions of the bitbase have white as the strong side with a1 = 0 and the pawn on the files a-d
+	strongKing := board.findPiece(strongIsBlack, KING) ^ 56
+	pawn := board.findPiece(strongIsBlack, PAWN) ^ 56
+	weakKing := board.findPiece(!strongIsBlack, KING) ^ 56
+	if strongIsBlack {
+		strongKing ^= 56
+		pawn ^= 56
+		weakKing ^= 56
+	}
+	if pawn&7 > 3 {
+		strongKing ^= 7
+		pawn ^= 7
+		weakKing ^= 7
+	}
+	stm := 1
+	if board.IsBlacksTurn == strongIsBlack {
+		stm = 0
+	}
+	if !probeKPK(strongKing, pawn, weakKing, stm) {
+		return 0
+	}
+	return strongScore(KNOWN_WIN_SCORE+evalParams.EgPieceValue[pieceIndex(PAWN)]+pawn>>3, strongIsBlack)
+}
+
+// The KPK bitbase stores for every position with white king, white pawn and black king whether white wins.
+// Squares are numbered with a1 = 0 and the pawn is on the files a-d and the ranks 2-7.
+// An index consists of the white king square (bits 0-5), the black king square (bits 6-11), the side to move (bit 12),
+// the pawn file (bits 13-14) and 7th rank - pawn rank (bits 15-17).
+const kpkMaxIndex = 2 * 24 * 64 * 64
+
+var kpkBitbase []uint32
+var kpkOnce sync.Once
+
+const (
+	kpkInvalid = 0
+	kpkUnknown = 1
+	kpkDraw    = 2
+	kpkWin     = 4
+)
+
+func kpkIndex(stm int, blackKing, whiteKing, pawn int) int {
+	return whiteKing | blackKing<<6 | stm<<12 | (pawn&7)<<13 | (6-pawn>>3)<<15
+}
+
+// probeKPK returns whether white wins. The bitbase is generated on the first call.
+func probeKPK(whiteKing, pawn, blackKing int, stm int) bool {
+	kpkOnce.Do(initKPK)
+	idx := kpkIndex(stm, blackKing, whiteKing, pawn)
+	return kpkBitbase[idx/32]&(1<<(idx%32)) != 0
+}
+
+// kpkKingAttacks returns the squares a king on sq attacks
+func kpkKingAttacks(sq int) uint64 {
+	var b uint64
+	for to := 0; to < 64; to++ {
+		if to != sq && distance(sq, to) == 1 {
+			b |= 1 << to
+		}
+	}
+	return b
+}
+
+// kpkPawnAttacks returns the squares a white pawn on sq attacks
+func kpkPawnAttacks(sq int) uint64 {
+	var b uint64
+	if sq&7 > 0 {
+		b |= 1 << (sq + 7)
+	}
+	if sq&7 < 7 {
+		b |= 1 << (sq + 9)
+	}
+	return b
+}
+
+// initKPK generates the bitbase by retrograde analysis
+func initKPK() {
+	var kingAttacks [64]uint64
+	for sq := 0; sq < 64; sq++ {
+		kingAttacks[sq] = kpkKingAttacks(sq)
+	}
+	db := make([]uint8, kpkMaxIndex)
+	for idx := range db {
+		whiteKing := idx & 0x3F
+		blackKing := (idx >> 6) & 0x3F
+		stm := (idx >> 12) & 1
+		pawn := (6-(idx>>15)&7)<<3 | (idx>>13)&3
+		switch {
+		// two pieces on the same square or a king can be captured
+		case distance(whiteKing, blackKing) <= 1 || whiteKing == pawn || blackKing == pawn ||
+			(stm == 0 && kpkPawnAttacks(pawn)&(1<<blackKing) != 0):
+			db[idx] = kpkInvalid
+		// the pawn promotes without getting captured
+		case stm == 0 && pawn>>3 == 6 && whiteKing != pawn+8 &&
+			(distance(blackKing, pawn+8) > 1 || distance(whiteKing, pawn+8) == 1):
+			db[idx] = kpkWin
+		// stalemate or the black king can capture the pawn
+		case stm == 1 && (kingAttacks[blackKing]&^(kingAttacks[whiteKing]|kpkPawnAttacks(pawn)) == 0 ||
+			kingAttacks[blackKing]&(1<<pawn)&^kingAttacks[whiteKing] != 0):
+			db[idx] = kpkDraw
+		default:
+			db[idx] = kpkUnknown
+		}
+	}
+
+	// a position is won for white if one move wins and drawn for black if one move draws
+	for repeat := true; repeat; {
+		repeat = false
+		for idx := range db {
+			if db[idx] != kpkUnknown {
+				continue
+			}
+			whiteKing := idx & 0x3F
+			blackKing := (idx >> 6) & 0x3F
+			stm := (idx >> 12) & 1
+			pawn := (6-(idx>>15)&7)<<3 | (idx>>13)&3
+			good, bad := uint8(kpkWin), uint8(kpkDraw)
+			if stm == 1 {
+				good, bad = kpkDraw, kpkWin
+			}
+			r := uint8(kpkInvalid)
+			if stm == 0 {
+				for b := kingAttacks[whiteKing]; b != 0; b &= b - 1 {
+					r |= db[kpkIndex(1, blackKing, bits.TrailingZeros64(b), pawn)]
+				}
+				if pawn>>3 < 6 {
+					r |= db[kpkIndex(1, blackKing, whiteKing, pawn+8)]
+				}
+				if pawn>>3 == 1 && pawn+8 != whiteKing && pawn+8 != blackKing {
+					r |= db[kpkIndex(1, blackKing, whiteKing, pawn+16)]
+				}
+			} else {
+				for b := kingAttacks[blackKing]; b != 0; b &= b - 1 {
+					r |= db[kpkIndex(0, bits.TrailingZeros64(b), whiteKing, pawn)]
+				}
+			}
+			if r&good != 0 {
+				db[idx] = good
+			} else if r&kpkUnknown == 0 {
+				db[idx] = bad
+			}
+			if db[idx] != kpkUnknown {
+				repeat = true
+			}
+		}
+	}
+
+	kpkBitbase = make([]uint32, kpkMaxIndex/32)
+	for idx, result := range db {
+		if result == kpkWin {
+			kpkBitbase[idx/32] |= 1 << (idx % 32)
+		}
+	}
+}
+
+// endgameScale returns the factor in 64ths the evaluation gets scaled with in drawish endgames.
+// The stronger side is the one the score is in favor of.
+func (board *Board) endgameScale(score float64) int {
+	// scaling only applies to endgames with at most two minor pieces and two rooks
+	if board.phase > 6 {
+		return SCALE_NORMAL
+	}
+	counts := board.materialCounts()
+	strongIsBlack := score < 0
+	strong, weak := 0, 1
+	if strongIsBlack {
+		strong, weak = 1, 0
+	}
+	pawnIdx, bishopIdx, knightIdx, rookIdx, queenIdx := 0, 1, 2, 3, 4
+
+	// all pawns on one rook file and the weak king in the corner can't be won if there is no bishop
+	// which controls the promotion square
+	strongPieces := counts[strong][knightIdx] + counts[strong][rookIdx] + counts[strong][queenIdx]
+	weakMaterial := counts[weak][pawnIdx] + counts[weak][bishopIdx] + counts[weak][knightIdx] + counts[weak][rookIdx] + counts[weak][queenIdx]
+	if counts[strong][pawnIdx] > 0 && strongPieces == 0 && counts[strong][bishopIdx] <= 1 && weakMaterial == 0 {
+		if promotion, ok := board.rookPawnsPromotionSquare(strongIsBlack); ok {
+			bishop := board.findPiece(strongIsBlack, BISHOP)
+			wrongBishop := bishop < 0 || isLightSquare(bishop) != isLightSquare(promotion)
+			if wrongBishop && distance(board.findPiece(!strongIsBlack, KING), promotion) <= 1 {
+				return SCALE_DRAW
+			}
+		}
+	}
+
+	// opposite colored bishops
+	if counts[0][bishopIdx] == 1 && counts[1][bishopIdx] == 1 && counts[0][knightIdx] == 0 && counts[1][knightIdx] == 0 &&
+		counts[0][queenIdx] == 0 && counts[1][queenIdx] == 0 &&
+		isLightSquare(board.findPiece(false, BISHOP)) != isLightSquare(board.findPiece(true, BISHOP)) {
+		if counts[0][rookIdx] > 0 || counts[1][rookIdx] > 0 {
+			return SCALE_OPPOSITE_BISHOPS_WITH_ROOKS
+		}
+		if abs(counts[0][pawnIdx]-counts[1][pawnIdx]) <= 1 {
+			return SCALE_OPPOSITE_BISHOPS_SIMILAR_PAWNS
+		}
+		return SCALE_OPPOSITE_BISHOPS
+	}
+	return SCALE_NORMAL
+}
+
+// rookPawnsPromotionSquare returns the promotion square if all pawns of the color are on the a or on the h file
+func (board *Board) rookPawnsPromotionSquare(isBlack bool) (int, bool) {
+	PieceIds := board.whiteIds
+	if isBlack {
+		PieceIds = board.blackIds
+	}
+	file := -1
+	for _, PieceId := range PieceIds {
+		piece := &board.pieces[PieceId]
+		if piece.posB == 0 || piece.pieceType != PAWN {
+			continue
+		}
+		x, _ := xy(piece.pos)
+		if (x != 0 && x != 7) || (file >= 0 && x != file) {
+			return 0, false
+		}
+		file = x
+	}
+	if file < 0 {
+		return 0, false
+	}
+	if isBlack {
+		return 56 + file, true
+	}
+	return file, true
+}
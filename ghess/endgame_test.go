@@ -0,0 +1,53 @@
+package ghess
+
+type kpkStruct struct {
+	fen string
+	win bool // whether the side with the pawn wins
+}
+
+var kpkTests = []kpkStruct{
+	{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", true},
+	{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", true},
+	{"8/8/8/8/4p3/4k3/8/4K3 b - - 0 1", true},
+	{"8/8/8/8/4p3/4k3/8/4K3 w - - 0 1", true},
+	{"8/8/8/8/8/k7/7P/7K w - - 0 1", true},
+	{"4k3/4P3/4K3/8/8/8/8/8 b - - 0 1", false},
+	{"8/8/8/8/8/4k3/4p3/4K3 w - - 0 1", false},
+	{"k7/8/K7/P7/8/8/8/8 w - - 0 1", false},
+	{"8/8/8/8/8/8/3kP3/7K b - - 0 1", false},
+}
+
+type endgameOrderStruct struct {
+	better string
+	worse  string
+}
+
+// the first position has to be evaluated better for white than the second one
+var endgameOrderTests = []endgameOrderStruct{
+	// the weak king is closer to the edge
+	{"7k/8/5K2/8/8/8/8/R7 w - - 0 1", "8/8/3k4/8/8/5K2/8/R7 w - - 0 1"},
+	{"8/8/8/8/8/8/1K6/k6Q b - - 0 1", "8/8/8/3k4/8/8/1K6/7Q b - - 0 1"},
+	// the weak king is in the corner of the color of the bishop
+	{"k7/8/1K6/8/2B5/8/8/6N1 w - - 0 1", "7k/8/6K1/8/2B5/8/8/1N6 w - - 0 1"},
+	// queen before rook before pawn
+	{"8/8/3k4/8/8/5K2/8/Q7 w - - 0 1", "8/8/3k4/8/8/5K2/8/R7 w - - 0 1"},
+	{"8/8/3k4/8/8/5K2/8/R7 w - - 0 1", "4k3/8/4K3/4P3/8/8/8/8 w - - 0 1"},
+	{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", "8/8/8/8/4p3/4k3/8/4K3 b - - 0 1"},
+}
+
+type endgameScaleStruct struct {
+	fen   string
+	scale int
+}
+
+var endgameScaleTests = []endgameScaleStruct{
+	{"4k3/4b3/8/2pP4/2P5/8/4B3/4K3 w - - 0 1", SCALE_OPPOSITE_BISHOPS_SIMILAR_PAWNS},
+	{"4k3/4b3/8/3P4/1PP5/P7/4B3/4K3 w - - 0 1", SCALE_OPPOSITE_BISHOPS},
+	{"3rk3/4b3/8/2pP4/2P5/8/4B3/3RK3 w - - 0 1", SCALE_OPPOSITE_BISHOPS_WITH_ROOKS},
+	{"4k3/5b2/8/2pP4/2P5/8/4B3/4K3 w - - 0 1", SCALE_NORMAL},
+	// the bishop doesn't control the promotion square of the rook pawn
+	{"k7/8/8/P7/8/8/8/2B1K3 w - - 0 1", SCALE_DRAW},
+	{"8/k7/8/P7/P7/8/8/4K3 w - - 0 1", SCALE_DRAW},
+	{"k7/8/8/P7/8/8/8/1B2K3 w - - 0 1", SCALE_NORMAL},
+	{"8/8/2k5/P7/8/8/8/2B1K3 w - - 0 1", SCALE_NORMAL},
+}
@@ -0,0 +1,198 @@
+package ghess
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// MATE_SCORE is the score of a checkmate at move 0 from whites perspective. A checkmate at full move n scores
+// MATE_SCORE - n such that faster mates score higher.
+const MATE_SCORE = 100000.0
+
+// SearchLimits restrict the search of an engine. Zero values are not used as a limit.
+// Without any limit an engine searches for MAX_ENGINE_TIME.
+type SearchLimits struct {
+	MoveTime int // ms
+	Nodes    int
+	Depth    int
+}
+
+// SearchResult is the move an engine found with its score in centipawns from whites perspective
+type SearchResult struct {
+	Move  Move
+	Score float64
+	Pv    []Move
+	Nodes int
+	Depth int
+}
+
+// Engine is a search algorithm which chooses moves for the positions of a game
+type Engine interface {
+	// Name identifies the search algorithm
+	Name() string
+	// Search returns the best move in the position of board which is left unchanged
+	Search(board *Board, limits SearchLimits) SearchResult
+	// NewGame clears everything the engine remembers from previous searches
+	NewGame()
+}
+
+// GameEngine is an Engine which needs the start position and the moves of the game to know the positions before the
+// current one, like an external engine which only gets the position it searches. PlayGame calls SearchGame instead of
+// Search for these engines such that they can detect repetitions.
+type GameEngine interface {
+	Engine
+	// SearchGame returns the best move in the position of board which is reached from the position given by startFen
+	// by the moves. board is left unchanged.
+	SearchGame(board *Board, startFen string, moves []Move, limits SearchLimits) SearchResult
+}
+
+// SearchInfo is the progress of a running search which is passed to the info handler of the board.
+// It either describes a line (CurrMoveNumber is 0) or the root move which is searched right now.
+type SearchInfo struct {
+	Depth          int
+	SelDepth       int
+	MultiPV        int     // number of the line starting at 1
+	Score          float64 // centipawns from the perspective of the side to move
+	Mate           int     // moves until checkmate (negative if the side to move gets mated) or 0
+	Nodes          int
+	Time           time.Duration
+	HashFull       int // permille of the used hash table entries
+	Pv             []Move
+	CurrMove       Move
+	CurrMoveNumber int
+}
+
+// SetInfoHandler sets a function which is called with the progress of the searches on board (nil for none)
+func (board *Board) SetInfoHandler(handler func(SearchInfo)) {
+	board.infoHandler = handler
+}
+
+// Copy returns a copy of board with its own pawn hash table such that both boards can be used concurrently
+func (board *Board) Copy() Board {
+	copied := *board
+	copied.pawnTable = nil
+	return copied
+}
+
+// MateIn returns the number of moves until checkmate for a score from whites perspective in the position of board.
+// It's positive if the side to move mates and negative if it gets mated. The second value is false for other scores.
+func (board *Board) MateIn(score float64) (int, bool) {
+	if math.Abs(score) < MATE_SCORE/2 {
+		return 0, false
+	}
+	// full move number of the checkmate position
+	mateMove := int(math.Round(MATE_SCORE - math.Abs(score)))
+	moves := mateMove - board.nextMove
+	if (score > 0) == !board.IsBlacksTurn {
+		if !board.IsBlacksTurn {
+			moves++
+		}
+		return moves, true
+	}
+	return -moves, true
+}
+
+// MateScore is the inverse of MateIn and returns the score from whites perspective of a checkmate in moves moves
+// (negative if the side to move gets mated) in the position of board
+func (board *Board) MateScore(moves int) float64 {
+	mateMove := board.nextMove + abs(moves)
+	if moves > 0 && !board.IsBlacksTurn {
+		mateMove--
+	}
+	if (moves > 0) == !board.IsBlacksTurn {
+		return MATE_SCORE - float64(mateMove)
+	}
+	return -(MATE_SCORE - float64(mateMove))
+}
+
+// lineInfo returns the info of a line with a score from whites perspective
+func (board *Board) lineInfo(multiPV int, score float64, pv []Move, depth, selDepth, nodes int, startTime time.Time) SearchInfo {
+	info := SearchInfo{Depth: depth, SelDepth: selDepth, MultiPV: multiPV, Score: score, Nodes: nodes,
+		Time: time.Since(startTime), HashFull: board.hashFull(), Pv: pv}
+	if board.IsBlacksTurn {
+		info.Score = -score
+	}
+	info.Mate, _ = board.MateIn(score)
+	return info
+}
+
+// pvSlice returns the moves of a pv without the empty moves at the end
+func pvSlice(pv [30]Move) []Move {
+	moves := []Move{}
+	for _, move := range pv {
+		if move.PieceId == 0 {
+			break
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
+
+// moveTime returns the time in ms an engine can use. Searches limited by nodes or depth only aren't stopped by time.
+func (limits SearchLimits) moveTime() int {
+	if limits.MoveTime > 0 {
+		return limits.MoveTime
+	}
+	if limits.Nodes > 0 || limits.Depth > 0 {
+		return 1000 * 60 * 60 * 24
+	}
+	return MAX_ENGINE_TIME
+}
+
+// AlphaBetaEngine is the iterative deepening alpha beta search of AlphaBetaEngineMove
+type AlphaBetaEngine struct {
+	Verbose bool // print the search statistics
+}
+
+func (engine *AlphaBetaEngine) Name() string {
+	return "alphaBeta"
+}
+
+func (engine *AlphaBetaEngine) NewGame() {}
+
+func (engine *AlphaBetaEngine) Search(board *Board, limits SearchLimits) SearchResult {
+	nodeLimit := board.nodeLimit
+	board.SetNodeLimit(limits.Nodes)
+	defer board.SetNodeLimit(nodeLimit)
+	maxDepth := 30
+	if limits.Depth > 0 {
+		maxDepth = limits.Depth
+	}
+	ab := board.AlphaBetaEngineMove([30]Move{}, min(2, maxDepth), maxDepth, false, engine.Verbose, limits.moveTime())
+	return SearchResult{Move: ab.Pv[0], Score: ab.Score, Pv: pvSlice(ab.Pv), Nodes: ab.NodesSearched, Depth: ab.Depth}
+}
+
+// moveEngine chooses moves without a search like the random engines
+type moveEngine struct {
+	name       string
+	chooseMove func(board *Board) Move
+}
+
+func (engine *moveEngine) Name() string {
+	return engine.name
+}
+
+func (engine *moveEngine) NewGame() {}
+
+func (engine *moveEngine) Search(board *Board, limits SearchLimits) SearchResult {
+	return SearchResult{Move: engine.chooseMove(board)}
+}
+
+// NewEngine returns a new engine by its name: random, captureRandom, checkCaptureRandom, alphaBeta or mcts.
+// Engines keep state between the searches of a game such that every game running in parallel needs its own engine.
+func NewEngine(name string) (Engine, error) {
+	switch name {
+	case "random":
+		return &moveEngine{name: name, chooseMove: (*Board).randomEngineMove}, nil
+	case "captureRandom":
+		return &moveEngine{name: name, chooseMove: (*Board).captureEngineMove}, nil
+	case "checkCaptureRandom":
+		return &moveEngine{name: name, chooseMove: (*Board).checkCaptureEngineMove}, nil
+	case "alphaBeta":
+		return &AlphaBetaEngine{}, nil
+	case "mcts":
+		return NewMCTSEngine(), nil
+	}
+	return nil, fmt.Errorf("unknown engine %s", name)
+}
@@ -2,12 +2,25 @@ package ghess
 
 import (
 	"fmt"
+	"log"
 	"math"
 	"math/rand"
 	"sort"
 	"time"
 )
 
+// SINGULAR_MIN_DEPTH is the minimum remaining depth at which the pv move is tested for being singular
+const SINGULAR_MIN_DEPTH = 4
+
+// QUIESCENCE_CHECK_PLIES is the number of quiescence plies in which a side in check has to evade the check. Deeper in
+// the quiescence search evading every check of a capture sequence blows up the tree, so a side in check stands pat and
+// only captures are searched.
+const QUIESCENCE_CHECK_PLIES = 2
+
+// SINGULAR_MARGIN is how much worse every alternative needs to be for the pv move to be singular.
+// It is half a pawn in centipawns of the tapered evaluation, which was 5 on the old scale of a pawn being worth 10.
+const SINGULAR_MARGIN = 50.0
+
 type Eval struct {
 	id    int
 	move  Move
@@ -27,66 +40,18 @@ func (board *Board) staticEvaluation() float64 {
 			return 0.0
 		}
 	}
-	// white pieces - black pieces
-	material := float64(board.countMaterialOfColor(false) - board.countMaterialOfColor(true))
-
-	// piece activity
-	activity := 0.0
-	if board.IsBlacksTurn {
-		activity += board.getWhiteMovementScore()
-	} else {
-		activity += board.getBlackMovementScore()
-	}
-	board.IsBlacksTurn = !board.IsBlacksTurn
-	board.setMovement()
-	if board.IsBlacksTurn {
-		activity += board.getWhiteMovementScore()
-	} else {
-		activity += board.getBlackMovementScore()
-	}
-	board.IsBlacksTurn = !board.IsBlacksTurn
-	board.setMovement()
-
-	return material + activity
-}
-
-func (board *Board) getWhiteMovementScore() float64 {
-	activity := 0.0
-	for _, PieceId := range board.whiteIds {
-		piece := board.pieces[PieceId]
-		if piece.posB == 0 || (piece.pieceType == 'q' && board.nextMove < 10) {
-			continue
-		}
-		pieceAct := 0.0
-		for i := 0; i < 32; i++ {
-			var bit uint64 = 1 << i
-			if piece.movementB&bit != 0 && board.whitePiecePosB&bit == 0 {
-				pieceAct += 1.0
-			}
-		}
-		activity += pieceAct
+	if _, score, ok := board.evaluateEndgame(); ok {
+		return score
 	}
-
-	return activity
-}
-
-func (board *Board) getBlackMovementScore() float64 {
-	activity := 0.0
-	for _, PieceId := range board.blackIds {
-		piece := board.pieces[PieceId]
-		if piece.posB == 0 || (piece.pieceType == 'q' && board.nextMove < 10) {
-			continue
-		}
-		pieceAct := 0.0
-		for i := 32; i < 64; i++ {
-			var bit uint64 = 1 << i
-			if piece.movementB&bit != 0 && board.blackPiecePosB&bit == 0 {
-				pieceAct -= 1.0
-			}
-		}
-		activity += pieceAct
+	if nnueNetwork != nil {
+		return board.nnueEvaluation() + board.skillNoise()
 	}
-	return activity
+	// material and piece square tables are updated incrementally in TempMove and reverseMove
+	mobility := board.getMobilityScore()
+	pawnMg, pawnEg := board.getPawnScore()
+	kingSafety := board.getKingSafetyScore()
+	score := board.taper(board.mgScore+mobility+pawnMg+kingSafety, board.egScore+mobility+pawnEg)
+	return score*float64(board.endgameScale(score))/SCALE_NORMAL + board.skillNoise()
 }
 
 type OrderedMoves struct {
@@ -94,28 +59,23 @@ type OrderedMoves struct {
 	score int
 }
 
+// getPieceVal returns the midgame material value of a piece which is used for move ordering.
+// The king gets a high value such that captures with the king are tried last.
 func (board *Board) getPieceVal(pieceId int) int {
 	piece := board.pieces[pieceId]
-	switch piece.pieceType {
-	case 'p':
-		return 10
-	case 'n':
-		return 30
-	case 'b':
-		return 35
-	case 'r':
-		return 50
-	case 'q':
-		return 100
-	case 'k':
+	if piece.pieceType == KING {
 		return 100000
 	}
-	return 0
+	return evalParams.MgPieceValue[pieceIndex(piece.pieceType)]
 }
 
 func (board *Board) getPossibleMovesOrdered(usePv bool, pv [30]Move, currentDepth int) []OrderedMoves {
 	moves := board.getPossibleMoves()
-	rand.Shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
+	shuffle := rand.Shuffle
+	if board.moveOrderRand != nil {
+		shuffle = board.moveOrderRand.Shuffle
+	}
+	shuffle(len(moves), func(i, j int) { moves[i], moves[j] = moves[j], moves[i] })
 	// order by capture value
 	orderedMoves := make([]OrderedMoves, len(moves))
 	for i, move := range moves {
@@ -143,9 +103,7 @@ func (board *Board) getPossibleMovesOrdered(usePv bool, pv [30]Move, currentDept
 		}
 	}
 	if !found {
-		fmt.Println("board.isBlacksTurn: ", board.IsBlacksTurn)
-		fmt.Println(orderedMoves)
-		fmt.Println("Could not find: ", pv[currentDepth])
+		log.Println("Could not find pv move", GetAlgebraicFromMove(&pv[currentDepth]), "in", board.GetFen())
 	}
 	orderedMoves[0], orderedMoves[id] = orderedMoves[id], orderedMoves[0]
 	return orderedMoves
@@ -205,6 +163,14 @@ type AlphaBetaOutput struct {
 	Pv            [30]Move
 	NodesSearched int
 	Depth         int
+	SelDepth      int
+	Lines         []PvLine // best lines of the last completed iteration when several lines are searched (MultiPV)
+}
+
+// PvLine is a principal variation starting with a different root move and its score from whites perspective
+type PvLine struct {
+	Score float64
+	Pv    [30]Move
 }
 
 func (board *Board) AlphaBetaEngineMove(bestPv [30]Move, currentDepth int, maxDepth int, completedOnce bool, verbose bool, maxDuration int) AlphaBetaOutput {
@@ -212,40 +178,79 @@ func (board *Board) AlphaBetaEngineMove(bestPv [30]Move, currentDepth int, maxDe
 	myColor := board.IsBlacksTurn
 	bestScore := 0.0
 	maxTime := time.Duration(maxDuration) * time.Millisecond
-	stopPondering := make(chan bool)
+	stopPondering := board.stop
+	board.nodes = 0
 	if maxDepth > 30 {
 		maxDepth = 30
 	}
-	numMoves := board.GetNumberOfMoves(1)
+	rootMoves := board.rootMoves()
+	numMoves := len(rootMoves)
 	if numMoves == 1 {
-		fmt.Println("Only one move possible")
-		moves := board.getPossibleMoves()
-		bestPv[0] = moves[0]
+		if verbose {
+			fmt.Println("Only one move possible")
+		}
+		bestPv[0] = rootMoves[0]
+		board.reportLine(1, 0, bestPv, 1, startTime)
 		return AlphaBetaOutput{Score: math.NaN(), Pv: bestPv}
 	}
+	if board.isWeakened() {
+		ab := board.skillEngineMove(maxTime, verbose)
+		board.reportLine(1, ab.Score, ab.Pv, ab.Depth, startTime)
+		return ab
+	}
+	// in tablebase positions the tablebase optimal move is played without a search
+	if move, wdl, ok := board.probeSyzygyRoot(); ok {
+		bestPv = [30]Move{move}
+		if verbose {
+			printPv(bestPv)
+			fmt.Println("tablebase result: ", wdl)
+		}
+		board.selDepth = 1
+		board.reportLine(1, board.syzygyScore(wdl, 0), bestPv, 1, startTime)
+		return AlphaBetaOutput{Completed: true, Score: board.syzygyScore(wdl, 0), Pv: bestPv, Depth: 1, SelDepth: 1}
+	}
 	factor := time.Duration(1.0)
 	lastRun := time.Duration(0.0)
 	completeAb := AlphaBetaOutput{Score: math.NaN(), Pv: bestPv}
+	board.pvScore = math.NaN()
 
-	for time.Since(startTime) <= maxTime && currentDepth <= maxDepth {
+	for time.Since(startTime) <= maxTime && currentDepth <= maxDepth && !board.nodeLimitReached() && !board.stopped() {
 		startRun := time.Now()
+		board.rootDepth = currentDepth
+		board.selDepth = 0
 		ab := board.alphaBetaPruning(stopPondering, completedOnce, 0, currentDepth, math.Inf(-1), math.Inf(1), !myColor, bestPv, true, startTime, maxTime, AlphaBetaOutput{})
+		var lines []PvLine
+		if ab.Completed && board.multiPV > 1 {
+			if lines = board.searchMultiPV(stopPondering, completedOnce, currentDepth, ab, min(board.multiPV, numMoves), startTime, maxTime); lines != nil {
+				completeAb.Lines = lines
+			}
+		}
 		if ab.Completed {
+			if lines == nil {
+				lines = []PvLine{{Score: ab.Score, Pv: ab.Pv}}
+			}
+			for i, line := range lines {
+				board.reportLine(i+1, line.Score, line.Pv, currentDepth, startTime)
+			}
 			bestScore = ab.Score
 			currentDepth += 1
 			completedOnce = true
 			completeAb.Completed = true
 			completeAb.Score = bestScore
-			completeAb.Depth = ab.Depth
+			completeAb.Depth = currentDepth - 1
+			completeAb.SelDepth = board.selDepth
 			completeAb.Pv = ab.Pv
-			completeAb.NodesSearched += ab.NodesSearched
+			bestPv = ab.Pv
+			board.pvScore = ab.Score
 		}
 		if lastRun.Milliseconds() > 1 {
 			factor = time.Since(startRun) / lastRun
 		}
 		lastRun = time.Since(startRun)
 		if time.Since(startTime)+factor*lastRun >= maxTime {
-			fmt.Println("factor break factor: ", factor)
+			if verbose {
+				fmt.Println("factor break factor: ", factor)
+			}
 			break
 		}
 
@@ -256,8 +261,15 @@ func (board *Board) AlphaBetaEngineMove(bestPv [30]Move, currentDepth int, maxDe
 		}
 	}
 
+	// a search which was stopped before the first iteration completed still returns a legal move
+	if completeAb.Pv[0].PieceId == 0 && numMoves > 0 {
+		completeAb.Pv[0] = rootMoves[0]
+	}
+	completeAb.NodesSearched = board.nodes
 	if verbose {
 		fmt.Printf("evaluated up to depth %d in %.02f sec.\n", currentDepth-1, time.Since(startTime).Seconds())
+		fmt.Printf("nodes %d\n", board.nodes)
+		fmt.Printf("selective depth %d\n", completeAb.SelDepth)
 		printPv(bestPv)
 		fmt.Println("score from whites perspective: ", bestScore)
 	}
@@ -265,49 +277,204 @@ func (board *Board) AlphaBetaEngineMove(bestPv [30]Move, currentDepth int, maxDe
 	return completeAb
 }
 
-func (board *Board) AlphaBetaEnginePonder(stopPondering chan bool, isready chan bool, currentBestPv chan [30]Move) {
-	startTime := time.Now()
-	myColor := board.IsBlacksTurn
-	maxTime := 10000000 * time.Millisecond
-	bestPv := [30]Move{}
-	currentDepth := 2
-	completedOnce := false
-
-	for {
-		select {
-		case <-stopPondering:
-			isready <- true
-			return
-		default:
-			ab := board.alphaBetaPruning(stopPondering, completedOnce, 0, currentDepth, math.Inf(-1), math.Inf(1), !myColor, bestPv, true, startTime, maxTime, AlphaBetaOutput{})
-			if ab.Completed {
-				bestPv = ab.Pv
-				currentDepth += 1
-				completedOnce = true
-				currentBestPv <- bestPv
-			} else {
-				isready <- true
-				return
-			}
+// reportLine passes a line with a score from whites perspective of a completed iteration to the info handler
+func (board *Board) reportLine(multiPV int, score float64, pv [30]Move, depth int, startTime time.Time) {
+	if board.infoHandler == nil {
+		return
+	}
+	if math.IsNaN(score) {
+		score = 0
+	}
+	board.infoHandler(board.lineInfo(multiPV, score, pvSlice(pv), depth, board.selDepth, board.nodes, startTime))
+}
+
+// reportCurrMove passes the root move which gets searched to the info handler once the search runs for a second
+func (board *Board) reportCurrMove(move Move, number int, startTime time.Time) {
+	if board.infoHandler == nil || time.Since(startTime) < time.Second {
+		return
+	}
+	board.infoHandler(SearchInfo{Depth: board.rootDepth, Nodes: board.nodes, Time: time.Since(startTime), CurrMove: move, CurrMoveNumber: number})
+}
+
+// SetStopChannel sets a channel which ends the searches on board as soon as it is closed (nil for none)
+func (board *Board) SetStopChannel(stop chan bool) {
+	board.stop = stop
+}
+
+// stopped returns true if the stop channel of the board is closed
+func (board *Board) stopped() bool {
+	select {
+	case <-board.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetSingularExtensions enables or disables singular extensions of the pv move in the alpha beta search
+func (board *Board) SetSingularExtensions(enabled bool) {
+	board.singularExtensions = enabled
+}
+
+// SetMultiPV sets the number of best lines with different root moves AlphaBetaEngineMove returns in Lines.
+// Each additional line costs a search of the root position without the moves of the previous lines.
+func (board *Board) SetMultiPV(lines int) {
+	board.multiPV = lines
+}
+
+// searchMultiPV searches the root position again excluding the root moves of the better lines until there are
+// numLines lines. The lines are only returned if all searches completed.
+func (board *Board) searchMultiPV(stopPondering chan bool, completedOnce bool, depth int, best AlphaBetaOutput, numLines int, startTime time.Time, maxTime time.Duration) []PvLine {
+	defer func() { board.excludedRootMoves = nil }()
+	lines := []PvLine{{Score: best.Score, Pv: best.Pv}}
+	for len(lines) < numLines {
+		board.excludedRootMoves = append(board.excludedRootMoves, lines[len(lines)-1].Pv[0])
+		ab := board.alphaBetaPruning(stopPondering, completedOnce, 0, depth, math.Inf(-1), math.Inf(1), !board.IsBlacksTurn, [30]Move{}, false, startTime, maxTime, AlphaBetaOutput{})
+		if !ab.Completed {
+			return nil
 		}
+		lines = append(lines, PvLine{Score: ab.Score, Pv: ab.Pv})
 	}
+	return lines
 }
 
-func (board *Board) quiesce(alpha, beta float64, maximizing bool) float64 {
-	standPat := board.staticEvaluation()
+// SetSearchMoves restricts the search to the given root moves. nil or an empty slice searches all moves.
+func (board *Board) SetSearchMoves(moves []Move) {
+	board.searchMoves = moves
+}
+
+// isRootMoveSearched returns false if the root move isn't one of the search moves or is excluded from the search
+func (board *Board) isRootMoveSearched(move *Move) bool {
+	for _, excluded := range board.excludedRootMoves {
+		if move.isEqual(&excluded) {
+			return false
+		}
+	}
+	if len(board.searchMoves) == 0 {
+		return true
+	}
+	for _, searchMove := range board.searchMoves {
+		if move.isEqual(&searchMove) {
+			return true
+		}
+	}
+	return false
+}
+
+// rootMoves returns the legal moves of the root position which are searched
+func (board *Board) rootMoves() []Move {
+	moves := []Move{}
+	for _, move := range board.getPossibleMoves() {
+		if board.isRootMoveSearched(&move) {
+			moves = append(moves, move)
+		}
+	}
+	return moves
+}
+
+// filterRootMoves removes the root moves which aren't searched
+func (board *Board) filterRootMoves(orderedMoves []OrderedMoves) []OrderedMoves {
+	if len(board.excludedRootMoves) == 0 && len(board.searchMoves) == 0 {
+		return orderedMoves
+	}
+	filtered := make([]OrderedMoves, 0, len(orderedMoves))
+	for _, om := range orderedMoves {
+		if board.isRootMoveSearched(&om.move) {
+			filtered = append(filtered, om)
+		}
+	}
+	return filtered
+}
+
+// SetNodeLimit stops the search of AlphaBetaEngineMove after the given number of nodes as soon as one iteration completed.
+// 0 disables the limit.
+func (board *Board) SetNodeLimit(nodes int) {
+	board.nodeLimit = nodes
+}
+
+// nodeLimitReached returns true if the node limit is set and the search visited at least that many nodes
+func (board *Board) nodeLimitReached() bool {
+	return board.nodeLimit > 0 && board.nodes >= board.nodeLimit
+}
+
+// searchExhausted returns true if the search has to stop because the time or node budget is used up.
+// The first iteration always completes such that there is a move to play.
+func (board *Board) searchExhausted(startTime time.Time, maxTime time.Duration, completedOnce bool) bool {
+	return completedOnce && (time.Since(startTime) >= maxTime || board.nodeLimitReached())
+}
+
+// updateSelDepth stores the deepest ply reached in the current search
+func (board *Board) updateSelDepth(ply int) {
+	if ply > board.selDepth {
+		board.selDepth = ply
+	}
+}
+
+// checkExtension returns the number of plies the search gets extended after a move was made.
+// Moves which give check are extended by one ply as long as the line still fits into the pv
+// and the extensions along the line don't exceed the nominal depth of the iteration.
+func (board *Board) checkExtension(currentDepth, depth int) int {
+	if board.check && currentDepth+depth+1 < 30 && currentDepth+depth < 2*board.rootDepth {
+		return 1
+	}
+	return 0
+}
+
+// getQuiescenceMoves returns the captures ordered by value and in the first quiescence ply additionally the quiet moves which give check.
+// If the side to move has to evade a check all moves are returned as standing pat isn't possible.
+func (board *Board) getQuiescenceMoves(qPly int, evadeCheck bool) []OrderedMoves {
+	if evadeCheck {
+		return board.getPossibleMovesOrdered(false, [30]Move{}, 0)
+	}
+	captures := board.getPossibleCaptures()
+	if qPly > 0 {
+		return captures
+	}
+	for _, move := range board.getPossibleMoves() {
+		if move.captureId != 0 {
+			continue
+		}
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&move)
+		givesCheck := board.check
+		board.reverseMove(&move, &boardPrimitives)
+		if givesCheck {
+			captures = append(captures, OrderedMoves{move: move, score: 0})
+		}
+	}
+	return captures
+}
+
+// quiesce searches captures (and checks in the first ply) until the position is quiet to avoid the horizon effect
+func (board *Board) quiesce(alpha, beta float64, maximizing bool, qPly, currentDepth int) float64 {
+	board.nodes++
+	board.updateSelDepth(currentDepth)
+	// checks are only evaded in the replies to the checks of the first ply
+	inCheck := board.check && qPly < QUIESCENCE_CHECK_PLIES
+	moves := board.getQuiescenceMoves(qPly, inCheck)
+	if inCheck && len(moves) == 0 {
+		// checkmate
+		return board.staticEvaluation()
+	}
+	standPat := 0.0
+	if !inCheck {
+		standPat = board.staticEvaluation()
+	}
 	if maximizing {
 		// it is whites turn now
-		if standPat >= beta {
-			return beta
-		}
-		if standPat > alpha {
-			alpha = standPat
+		if !inCheck {
+			if standPat >= beta {
+				return beta
+			}
+			if standPat > alpha {
+				alpha = standPat
+			}
 		}
 
-		for _, om := range board.getPossibleCaptures() {
+		for _, om := range moves {
 			boardPrimitives := board.getBoardPrimitives()
 			board.Move(&om.move)
-			score := board.quiesce(alpha, beta, !maximizing)
+			score := board.quiesce(alpha, beta, !maximizing, qPly+1, currentDepth+1)
 			board.reverseMove(&om.move, &boardPrimitives)
 
 			if score >= beta {
@@ -320,17 +487,19 @@ func (board *Board) quiesce(alpha, beta float64, maximizing bool) float64 {
 		return alpha
 	} else {
 		// it is blacks turn now
-		if standPat <= alpha {
-			return alpha
-		}
-		if standPat < beta {
-			beta = standPat
+		if !inCheck {
+			if standPat <= alpha {
+				return alpha
+			}
+			if standPat < beta {
+				beta = standPat
+			}
 		}
 
-		for _, om := range board.getPossibleCaptures() {
+		for _, om := range moves {
 			boardPrimitives := board.getBoardPrimitives()
 			board.Move(&om.move)
-			score := board.quiesce(alpha, beta, !maximizing)
+			score := board.quiesce(alpha, beta, !maximizing, qPly+1, currentDepth+1)
 			board.reverseMove(&om.move, &boardPrimitives)
 
 			if score <= alpha {
@@ -345,10 +514,49 @@ func (board *Board) quiesce(alpha, beta float64, maximizing bool) float64 {
 
 }
 
+// isSingular checks whether the pv move which is expected to score pvScore is clearly better than all alternatives by
+// searching the other moves with a reduced depth and a null window around pvScore shifted by SINGULAR_MARGIN.
+// The tree has no transposition table so the pv move and the score of the previous iteration take the role of the hash
+// move and its stored score. With scores from whites perspective every node of the pv has the score of the root.
+// The second return value is false if the search was interrupted.
+func (board *Board) isSingular(stopPondering chan bool, completedOnce bool, currentDepth, depth int, pvScore float64, maximizing bool, orderedMoves []OrderedMoves,
+	startTime time.Time, maxTime time.Duration) (bool, bool) {
+	for _, om := range orderedMoves[1:] {
+		move := om.move
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&move)
+		var ab AlphaBetaOutput
+		if maximizing {
+			singularBeta := pvScore - SINGULAR_MARGIN
+			ab = board.alphaBetaPruning(stopPondering, completedOnce, currentDepth+1, depth/2, singularBeta-1, singularBeta, !maximizing, [30]Move{}, false, startTime, maxTime, AlphaBetaOutput{})
+			board.reverseMove(&move, &boardPrimitives)
+			if ab.Completed && ab.Score >= singularBeta {
+				return false, true
+			}
+		} else {
+			singularAlpha := pvScore + SINGULAR_MARGIN
+			ab = board.alphaBetaPruning(stopPondering, completedOnce, currentDepth+1, depth/2, singularAlpha, singularAlpha+1, !maximizing, [30]Move{}, false, startTime, maxTime, AlphaBetaOutput{})
+			board.reverseMove(&move, &boardPrimitives)
+			if ab.Completed && ab.Score <= singularAlpha {
+				return false, true
+			}
+		}
+		if !ab.Completed {
+			return false, false
+		}
+	}
+	return true, true
+}
+
 func (board *Board) alphaBetaPruning(stopPondering chan bool, completedOnce bool, currentDepth, depth int, alpha, beta float64, maximizing bool, startPV [30]Move, usePv bool,
 	startTime time.Time, maxTime time.Duration, output AlphaBetaOutput) AlphaBetaOutput {
 
+	board.nodes++
+	board.updateSelDepth(currentDepth)
 	orderedMoves := board.getPossibleMovesOrdered(usePv, startPV, currentDepth)
+	if currentDepth == 0 {
+		orderedMoves = board.filterRootMoves(orderedMoves)
+	}
 	gameEnded, _, _ := board.CheckGameEnded()
 	if gameEnded || depth == 0 {
 		output.Completed = true
@@ -360,13 +568,33 @@ func (board *Board) alphaBetaPruning(stopPondering chan bool, completedOnce bool
 		output.Score = board.staticEvaluation()
 		return output
 	}
+	if currentDepth > 0 {
+		if score, ok := board.probeSyzygyScore(currentDepth); ok {
+			output.Completed = true
+			output.Score = score
+			return output
+		}
+	}
 	if depth == 0 {
-		output.Score = board.quiesce(alpha, beta, maximizing)
+		output.Score = board.quiesce(alpha, beta, maximizing, 0, currentDepth)
 		return output
 	}
 	bestPv := startPV
 	notCompletedOutput := AlphaBetaOutput{Completed: false, Score: math.NaN(), Pv: startPV}
 	completedOutput := AlphaBetaOutput{Completed: true}
+	// only the pv move of the previous iteration is a candidate for a singular extension. It is verified before its
+	// own search against the score of the previous iteration.
+	singularExtension := 0
+	if board.singularExtensions && usePv && depth >= SINGULAR_MIN_DEPTH && len(orderedMoves) > 1 &&
+		startPV[currentDepth].PieceId != 0 && currentDepth+depth+1 < 30 && !math.IsNaN(board.pvScore) {
+		singular, completed := board.isSingular(stopPondering, completedOnce, currentDepth, depth, board.pvScore, maximizing, orderedMoves, startTime, maxTime)
+		if !completed {
+			return notCompletedOutput
+		}
+		if singular {
+			singularExtension = 1
+		}
+	}
 
 	// maximizing player
 	if maximizing {
@@ -374,17 +602,23 @@ func (board *Board) alphaBetaPruning(stopPondering chan bool, completedOnce bool
 		for i, om := range orderedMoves {
 			select {
 			case <-stopPondering:
-				fmt.Println("stop pondering inside")
 				return notCompletedOutput
 			default:
 				move := om.move
-				if time.Since(startTime) >= maxTime && completedOnce {
+				if board.searchExhausted(startTime, maxTime, completedOnce) {
 					return notCompletedOutput
 				}
 
+				if currentDepth == 0 {
+					board.reportCurrMove(move, i+1, startTime)
+				}
 				boardPrimitives := board.getBoardPrimitives()
 				board.Move(&move)
-				ab := board.alphaBetaPruning(stopPondering, completedOnce, currentDepth+1, depth-1, alpha, beta, !maximizing, startPV, usePv && i == 0, startTime, maxTime, output)
+				extension := board.checkExtension(currentDepth, depth)
+				if i == 0 {
+					extension = max(extension, singularExtension)
+				}
+				ab := board.alphaBetaPruning(stopPondering, completedOnce, currentDepth+1, depth-1+extension, alpha, beta, !maximizing, startPV, usePv && i == 0, startTime, maxTime, output)
 				board.reverseMove(&move, &boardPrimitives)
 				if !ab.Completed {
 					return notCompletedOutput
@@ -410,17 +644,23 @@ func (board *Board) alphaBetaPruning(stopPondering chan bool, completedOnce bool
 		for i, om := range orderedMoves {
 			select {
 			case <-stopPondering:
-				fmt.Println("stop pondering inside")
 				return notCompletedOutput
 			default:
 				move := om.move
-				if time.Since(startTime) >= maxTime && completedOnce {
+				if board.searchExhausted(startTime, maxTime, completedOnce) {
 					return notCompletedOutput
 				}
 
+				if currentDepth == 0 {
+					board.reportCurrMove(move, i+1, startTime)
+				}
 				boardPrimitives := board.getBoardPrimitives()
 				board.Move(&move)
-				ab := board.alphaBetaPruning(stopPondering, completedOnce, currentDepth+1, depth-1, alpha, beta, !maximizing, startPV, usePv && i == 0, startTime, maxTime, output)
+				extension := board.checkExtension(currentDepth, depth)
+				if i == 0 {
+					extension = max(extension, singularExtension)
+				}
+				ab := board.alphaBetaPruning(stopPondering, completedOnce, currentDepth+1, depth-1+extension, alpha, beta, !maximizing, startPV, usePv && i == 0, startTime, maxTime, output)
 				board.reverseMove(&move, &boardPrimitives)
 				if !ab.Completed {
 					return notCompletedOutput
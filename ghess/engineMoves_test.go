@@ -21,3 +21,15 @@ var engineMovesTests = []engineMoves{
 	{"5k2/1P6/2P2K2/8/8/8/8/8 w - - 0 1", "checkCaptureRandom", []string{"b7b8q", "b7b8r"}},
 	{"5k2/1P6/2P2K2/8/8/8/8/8 w - - 0 1", "alphaBeta", []string{"b7b8q", "b7b8r"}},
 }
+
+type forcedMate struct {
+	fen      string
+	maxDepth int
+	expected string
+}
+
+// mates which are only found within maxDepth due to check extensions and checks in quiescence search
+var forcedMateTests = []forcedMate{
+	{"8/8/7k/R7/8/8/8/1R1K4 w - - 0 1", 2, "b1b6"},
+	{"1r1k4/8/8/8/r7/7K/8/8 b - - 0 1", 2, "b8b3"},
+}
@@ -0,0 +1,14 @@
+package ghess
+
+// mateInStruct is a position with a forced checkmate in moves moves (negative if the side to move gets mated)
+type mateInStruct struct {
+	fen   string
+	moves int
+}
+
+var mateInTests = []mateInStruct{
+	{"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", 1},
+	{"r5k1/8/8/8/8/8/5PPP/6K1 b - - 0 1", 1},
+	{"k7/p7/P1K5/6p1/8/8/8/7R b - - 0 1", -1},
+	{"7k/8/6K1/8/8/8/8/R7 w - - 0 40", 1},
+}
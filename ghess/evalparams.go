@@ -0,0 +1,270 @@
+package ghess
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+// MAX_EVAL_PARAM is the largest absolute value of a weight such that evaluations stay clearly below mate scores
+const MAX_EVAL_PARAM = 5000
+
+// EvalParams are all weights of the static evaluation in centipawns.
+// Arrays indexed by piece type are ordered like pieceMap, pairs are midgame and endgame values
+// and penalties are stored as positive numbers.
+type EvalParams struct {
+	MgPieceValue [6]int     `json:"mgPieceValue"`
+	EgPieceValue [6]int     `json:"egPieceValue"`
+	MgPST        [6][64]int `json:"mgPST"` // from whites perspective where index 0 is a8
+	EgPST        [6][64]int `json:"egPST"`
+	Mobility     int        `json:"mobility"`
+
+	DoubledPawn                 [2]int    `json:"doubledPawn"`
+	IsolatedPawn                [2]int    `json:"isolatedPawn"`
+	BackwardPawn                [2]int    `json:"backwardPawn"`
+	ConnectedPawn               [2][8]int `json:"connectedPawn"` // indexed by the relative rank
+	PassedPawn                  [2][8]int `json:"passedPawn"`
+	PassedPawnOwnKingDistance   int       `json:"passedPawnOwnKingDistance"`
+	PassedPawnEnemyKingDistance int       `json:"passedPawnEnemyKingDistance"`
+
+	PawnShield           [3]int `json:"pawnShield"` // indexed by the number of ranks between king and pawn
+	MissingPawnShield    int    `json:"missingPawnShield"`
+	PawnStorm            [5]int `json:"pawnStorm"`
+	SemiOpenFileNearKing int    `json:"semiOpenFileNearKing"`
+	OpenFileNearKing     int    `json:"openFileNearKing"`
+	KingAttackValue      [6]int `json:"kingAttackValue"`
+	KingAttackWeight     [8]int `json:"kingAttackWeight"` // in percent indexed by the number of attackers
+}
+
+// evalParams are the weights used by the static evaluation
+var evalParams = DefaultEvalParams()
+
+// DefaultEvalParams returns the built in evaluation weights
+func DefaultEvalParams() EvalParams {
+	return EvalParams{
+		MgPieceValue: defaultMgPieceValue,
+		EgPieceValue: defaultEgPieceValue,
+		MgPST:        defaultMgPST,
+		EgPST:        defaultEgPST,
+		Mobility:     DEFAULT_MOBILITY,
+
+		DoubledPawn:                 defaultDoubledPawnPenalty,
+		IsolatedPawn:                defaultIsolatedPawnPenalty,
+		BackwardPawn:                defaultBackwardPawnPenalty,
+		ConnectedPawn:               defaultConnectedPawnBonus,
+		PassedPawn:                  defaultPassedPawnBonus,
+		PassedPawnOwnKingDistance:   DEFAULT_PASSED_PAWN_OWN_KING_DISTANCE,
+		PassedPawnEnemyKingDistance: DEFAULT_PASSED_PAWN_ENEMY_KING_DISTANCE,
+
+		PawnShield:           defaultPawnShieldBonus,
+		MissingPawnShield:    DEFAULT_MISSING_PAWN_SHIELD_PENALTY,
+		PawnStorm:            defaultPawnStormPenalty,
+		SemiOpenFileNearKing: DEFAULT_SEMI_OPEN_FILE_NEAR_KING_PENALTY,
+		OpenFileNearKing:     DEFAULT_OPEN_FILE_NEAR_KING_PENALTY,
+		KingAttackValue:      defaultKingAttackValue,
+		KingAttackWeight:     defaultKingAttackWeight,
+	}
+}
+
+// GetEvalParams returns the weights currently used by the static evaluation
+func GetEvalParams() EvalParams {
+	return evalParams
+}
+
+// SetEvalParams replaces the weights of the static evaluation.
+// Boards created before need to call RefreshEvaluation.
+func SetEvalParams(params EvalParams) {
+	evalParams = params
+}
+
+// RefreshEvaluation recomputes the incrementally updated evaluation and clears the pawn hash table
+// which is needed after the evaluation weights changed
+func (board *Board) RefreshEvaluation() {
+	board.initEvaluation()
+	board.pawnTable = nil
+}
+
+// vector returns pointers to all weights in a fixed order such that the weights can be treated as one vector
+func (params *EvalParams) vector() []*int {
+	var v []*int
+	appendAll := func(values []int) {
+		for i := range values {
+			v = append(v, &values[i])
+		}
+	}
+	appendAll(params.MgPieceValue[:])
+	appendAll(params.EgPieceValue[:])
+	for i := range params.MgPST {
+		appendAll(params.MgPST[i][:])
+	}
+	for i := range params.EgPST {
+		appendAll(params.EgPST[i][:])
+	}
+	v = append(v, &params.Mobility)
+	appendAll(params.DoubledPawn[:])
+	appendAll(params.IsolatedPawn[:])
+	appendAll(params.BackwardPawn[:])
+	appendAll(params.ConnectedPawn[0][:])
+	appendAll(params.ConnectedPawn[1][:])
+	appendAll(params.PassedPawn[0][:])
+	appendAll(params.PassedPawn[1][:])
+	v = append(v, &params.PassedPawnOwnKingDistance, &params.PassedPawnEnemyKingDistance)
+	appendAll(params.PawnShield[:])
+	v = append(v, &params.MissingPawnShield)
+	appendAll(params.PawnStorm[:])
+	v = append(v, &params.SemiOpenFileNearKing, &params.OpenFileNearKing)
+	appendAll(params.KingAttackValue[:])
+	appendAll(params.KingAttackWeight[:])
+	return v
+}
+
+// WriteFile writes the weights as JSON to path
+func (params EvalParams) WriteFile(path string) error {
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Validate returns an error if a weight is outside of its allowed range
+func (params *EvalParams) Validate() error {
+	for _, p := range params.vector() {
+		if *p < -MAX_EVAL_PARAM || *p > MAX_EVAL_PARAM {
+			return fmt.Errorf("weight %d is outside of [-%d, %d]", *p, MAX_EVAL_PARAM, MAX_EVAL_PARAM)
+		}
+	}
+	for i, symbol := range "pbnrq" {
+		if params.MgPieceValue[i] <= 0 || params.EgPieceValue[i] <= 0 {
+			return fmt.Errorf("the value of piece %c needs to be positive", symbol)
+		}
+	}
+	for _, weight := range params.KingAttackWeight {
+		if weight < 0 || weight > 100 {
+			return fmt.Errorf("king attack weight %d is not a percentage", weight)
+		}
+	}
+	return nil
+}
+
+// checkArrayLengths returns an error if an array in the JSON value doesn't have the length of the Go array of type t.
+// The JSON decoder would set missing elements to 0 and drop additional ones.
+func checkArrayLengths(value json.RawMessage, t reflect.Type, name string) error {
+	if t.Kind() != reflect.Array {
+		return nil
+	}
+	var elements []json.RawMessage
+	if err := json.Unmarshal(value, &elements); err != nil {
+		// the decoder reports values of the wrong type
+		return nil
+	}
+	if len(elements) != t.Len() {
+		return fmt.Errorf("%s needs %d values but has %d", name, t.Len(), len(elements))
+	}
+	for i, element := range elements {
+		if err := checkArrayLengths(element, t.Elem(), fmt.Sprintf("%s[%d]", name, i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadEvalParams reads weights from a JSON file as written by WriteFile.
+// Weights missing in the file keep their built in value. Unknown keys and arrays with a wrong length are an error.
+func ReadEvalParams(path string) (EvalParams, error) {
+	params := DefaultEvalParams()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return params, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&params); err != nil {
+		return DefaultEvalParams(), fmt.Errorf("%s: %w", path, err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return DefaultEvalParams(), fmt.Errorf("%s: %w", path, err)
+	}
+	paramsType := reflect.TypeOf(params)
+	for key, value := range values {
+		for i := 0; i < paramsType.NumField(); i++ {
+			// keys are matched case insensitive like in the decoder
+			if name := paramsType.Field(i).Tag.Get("json"); strings.EqualFold(key, name) {
+				if err := checkArrayLengths(value, paramsType.Field(i).Type, name); err != nil {
+					return DefaultEvalParams(), fmt.Errorf("%s: %w", path, err)
+				}
+			}
+		}
+	}
+	if err := params.Validate(); err != nil {
+		return DefaultEvalParams(), fmt.Errorf("%s: %w", path, err)
+	}
+	return params, nil
+}
+
+// LoadEvalParams reads the weights from path and uses them for the static evaluation.
+// An empty path restores the built in weights. If the file is invalid the weights don't change.
+func LoadEvalParams(path string) error {
+	if path == "" {
+		SetEvalParams(DefaultEvalParams())
+		return nil
+	}
+	params, err := ReadEvalParams(path)
+	if err != nil {
+		return err
+	}
+	SetEvalParams(params)
+	return nil
+}
+
+// evalCoefficients counts how often every weight is applied in a position from whites perspective (black counts negative).
+// The midgame score is the dot product of the weights with mg and the endgame score the one with eg.
+// Only the king attacks are not linear in the weights as the attack value gets scaled by the attack weight,
+// therefore the number of attacked squares per piece type and the number of attackers are stored for both kings.
+type evalCoefficients struct {
+	mg           EvalParams
+	eg           EvalParams
+	phase        int
+	kingAttacks  [2][6]int
+	kingAttacker [2]int
+}
+
+// colorSign returns 1 for white and -1 for black
+func colorSign(isBlack bool) int {
+	if isBlack {
+		return -1
+	}
+	return 1
+}
+
+// evaluate returns the tapered evaluation with the given weights
+func (coef *evalCoefficients) evaluate(params *EvalParams) float64 {
+	mg, eg := 0, 0
+	paramsV := params.vector()
+	mgV := coef.mg.vector()
+	egV := coef.eg.vector()
+	for i, p := range paramsV {
+		mg += *p * *mgV[i]
+		eg += *p * *egV[i]
+	}
+	for c := 0; c < 2; c++ {
+		attackValue := 0
+		for t, n := range coef.kingAttacks[c] {
+			attackValue += n * params.KingAttackValue[t]
+		}
+		mg -= colorSign(c == 1) * (attackValue * params.KingAttackWeight[coef.kingAttacker[c]] / 100)
+	}
+	phase := min(coef.phase, MAX_PHASE)
+	return float64(mg*phase+eg*(MAX_PHASE-phase)) / MAX_PHASE
+}
+
+// getEvalCoefficients returns how often every weight is applied in the position
+func (board *Board) getEvalCoefficients() *evalCoefficients {
+	coef := &evalCoefficients{phase: board.phase}
+	board.traceEvaluation(coef)
+	return coef
+}
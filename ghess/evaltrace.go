@@ -0,0 +1,152 @@
+package ghess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EvalTerm is the midgame and endgame score of one evaluation term in centipawns for both colors
+type EvalTerm struct {
+	Name  string `json:"name"`
+	White [2]int `json:"white"` // midgame, endgame
+	Black [2]int `json:"black"` // midgame, endgame
+}
+
+// EvalTrace is the breakdown of the static evaluation into its terms.
+// If a network is set the terms are the handcrafted evaluation for reference and the network score replaces them.
+type EvalTrace struct {
+	Terms   []EvalTerm `json:"terms"`
+	Phase   int        `json:"phase"`             // between 0 (endgame) and MAX_PHASE (midgame)
+	Scale   int        `json:"scale"`             // factor in 64ths the tapered score gets scaled with in drawish endgames
+	Endgame string     `json:"endgame,omitempty"` // name of the specialised endgame evaluator which replaces the terms
+	Network bool       `json:"network"`           // whether the NNUE score replaces the terms
+	NNUE    float64    `json:"nnue,omitempty"`    // score of the network from whites perspective
+	Noise   float64    `json:"noise,omitempty"`   // evaluation error of a reduced skill level
+	Total   float64    `json:"total"`             // score from whites perspective
+}
+
+// getMaterialAndPSTOfColor returns the material and the piece square table score of one color as midgame and endgame pairs.
+// If coef is not nil the applied weights are counted.
+func (board *Board) getMaterialAndPSTOfColor(isBlack bool, coef *evalCoefficients) ([2]int, [2]int) {
+	PieceIds := board.whiteIds
+	if isBlack {
+		PieceIds = board.blackIds
+	}
+	var material, pst [2]int
+	for _, PieceId := range PieceIds {
+		piece := &board.pieces[PieceId]
+		if piece.posB == 0 {
+			continue
+		}
+		idx := pieceIndex(piece.pieceType)
+		pos := piece.pos
+		if isBlack {
+			pos ^= 56
+		}
+		material[0] += evalParams.MgPieceValue[idx]
+		material[1] += evalParams.EgPieceValue[idx]
+		pst[0] += evalParams.MgPST[idx][pos]
+		pst[1] += evalParams.EgPST[idx][pos]
+		if coef != nil {
+			sign := colorSign(isBlack)
+			coef.mg.MgPieceValue[idx] += sign
+			coef.eg.EgPieceValue[idx] += sign
+			coef.mg.MgPST[idx][pos] += sign
+			coef.eg.EgPST[idx][pos] += sign
+		}
+	}
+	return material, pst
+}
+
+// EvalTrace returns every term of the static evaluation for both colors and game phases together with the network
+// score and the skill noise. The total is the same as the static evaluation of a position in which the game didn't end.
+func (board *Board) EvalTrace() EvalTrace {
+	return board.traceEvaluation(nil)
+}
+
+// traceEvaluation computes the evaluation trace and counts the applied weights if coef is not nil
+func (board *Board) traceEvaluation(coef *evalCoefficients) EvalTrace {
+	trace := EvalTrace{Phase: min(board.phase, MAX_PHASE)}
+	whitePawnsB, blackPawnsB := board.getPawnBitboards()
+
+	material := EvalTerm{Name: "Material"}
+	pst := EvalTerm{Name: "Piece squares"}
+	material.White, pst.White = board.getMaterialAndPSTOfColor(false, coef)
+	material.Black, pst.Black = board.getMaterialAndPSTOfColor(true, coef)
+
+	whiteMobility := board.getMobilityOfColor(false, coef)
+	blackMobility := board.getMobilityOfColor(true, coef)
+	mobility := EvalTerm{Name: "Mobility", White: [2]int{whiteMobility, whiteMobility}, Black: [2]int{blackMobility, blackMobility}}
+
+	pawns := EvalTerm{Name: "Pawns"}
+	passed := EvalTerm{Name: "Passed pawns"}
+	var whitePassedB, blackPassedB uint64
+	pawns.White[0], pawns.White[1], whitePassedB = evaluatePawnsOfColor(whitePawnsB, blackPawnsB, false, coef)
+	pawns.Black[0], pawns.Black[1], blackPassedB = evaluatePawnsOfColor(blackPawnsB, whitePawnsB, true, coef)
+	if board.whiteKingId != 0 && board.blackKingId != 0 {
+		passed.White[1] = board.getPassedPawnKingScore(whitePassedB, false, coef)
+		passed.Black[1] = board.getPassedPawnKingScore(blackPassedB, true, coef)
+	}
+
+	kingSafety := EvalTerm{Name: "King safety"}
+	kingSafety.White[0] = board.getKingSafetyOfColor(false, whitePawnsB, blackPawnsB, coef)
+	kingSafety.Black[0] = board.getKingSafetyOfColor(true, whitePawnsB, blackPawnsB, coef)
+
+	trace.Terms = []EvalTerm{material, pst, mobility, pawns, passed, kingSafety}
+	mg, eg := 0, 0
+	for _, term := range trace.Terms {
+		mg += term.White[0] - term.Black[0]
+		eg += term.White[1] - term.Black[1]
+	}
+	trace.Total = board.taper(mg, eg)
+	trace.Scale = board.endgameScale(trace.Total)
+	trace.Total = trace.Total * float64(trace.Scale) / SCALE_NORMAL
+	if name, score, ok := board.evaluateEndgame(); ok {
+		trace.Endgame = name
+		trace.Total = score
+		return trace
+	}
+	if nnueNetwork != nil {
+		trace.Network = true
+		trace.NNUE = board.nnueEvaluation()
+		trace.Total = trace.NNUE
+	}
+	trace.Noise = board.skillNoise()
+	trace.Total += trace.Noise
+	return trace
+}
+
+// String returns the trace as a table with the scores in pawns
+func (trace EvalTrace) String() string {
+	var sb strings.Builder
+	line := "--------------+---------------+---------------+---------------\n"
+	sb.WriteString("      Term    |     White     |     Black     |     Total\n")
+	sb.WriteString("              |   MG     EG   |   MG     EG   |   MG     EG\n")
+	sb.WriteString(line)
+	var total [2]int
+	for _, term := range trace.Terms {
+		mg := term.White[0] - term.Black[0]
+		eg := term.White[1] - term.Black[1]
+		total[0] += mg
+		total[1] += eg
+		sb.WriteString(fmt.Sprintf("%13s | %6.2f %6.2f | %6.2f %6.2f | %6.2f %6.2f\n", term.Name,
+			float64(term.White[0])/100, float64(term.White[1])/100, float64(term.Black[0])/100, float64(term.Black[1])/100, float64(mg)/100, float64(eg)/100))
+	}
+	sb.WriteString(line)
+	sb.WriteString(fmt.Sprintf("%13s | %13s | %13s | %6.2f %6.2f\n", "Total", "", "", float64(total[0])/100, float64(total[1])/100))
+	sb.WriteString(fmt.Sprintf("\nPhase: %d/%d\n", trace.Phase, MAX_PHASE))
+	if trace.Scale != SCALE_NORMAL {
+		sb.WriteString(fmt.Sprintf("Endgame scale: %d/%d\n", trace.Scale, SCALE_NORMAL))
+	}
+	if trace.Endgame != "" {
+		sb.WriteString(fmt.Sprintf("Specialised endgame: %s\n", trace.Endgame))
+	}
+	if trace.Network {
+		sb.WriteString(fmt.Sprintf("NNUE evaluation: %.2f (replaces the terms)\n", trace.NNUE/100))
+	}
+	if trace.Noise != 0 {
+		sb.WriteString(fmt.Sprintf("Skill noise: %.2f\n", trace.Noise/100))
+	}
+	sb.WriteString(fmt.Sprintf("Final evaluation: %.2f (white side)\n", trace.Total/100))
+	return sb.String()
+}
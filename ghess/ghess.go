@@ -25,6 +25,10 @@ const PAWN = 'p'
 
 const MAX_ENGINE_TIME = 4000 // ms
 
+// Polyglot opening book used by the engines (empty for none)
+const BOOK_FILE = ""
+
+// engines playing white and black: random, captureRandom, checkCaptureRandom, alphaBeta or mcts
 const ENGINE1 = "checkCaptureRandom"
 const ENGINE2 = "alphaBeta"
 
@@ -43,15 +47,6 @@ const START_FEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 
 // const START_FEN = "8/1r5p/5kp1/4bp2/p7/4Q3/4PnPP/4K2R b - - 0 1"
 
-var materialCountMap = map[rune]int{
-	'k': 900,
-	'q': 100,
-	'r': 50,
-	'b': 35,
-	'n': 30,
-	'p': 10,
-}
-
 type Piece struct {
 	id          int
 	pos         int    // position from 0 to 63
@@ -105,6 +100,28 @@ type Board struct {
 	ply                int
 	posHashes          [500]uint64
 	zobristHashTable   [64][12]uint64
+	rootDepth          int                   // nominal depth of the current alpha beta iteration
+	selDepth           int                   // deepest ply reached in the current search including extensions and quiescence
+	singularExtensions bool                  // extend the pv move if all alternatives are clearly worse
+	pvScore            float64               // score of the last completed iteration which all nodes of its pv share
+	nodes              int                   // nodes visited in the current search including quiescence nodes
+	nodeLimit          int                   // maximum number of nodes of a search (0 is unlimited)
+	multiPV            int                   // number of lines with different root moves the alpha beta search returns
+	excludedRootMoves  []Move                // root moves which aren't searched (the moves of the better lines for MultiPV)
+	searchMoves        []Move                // root moves the search is restricted to (all moves if empty)
+	mgScore            int                   // incrementally updated midgame material + piece square score from whites perspective
+	egScore            int                   // incrementally updated endgame material + piece square score from whites perspective
+	phase              int                   // game phase between 0 (only pawns and kings) and MAX_PHASE (all pieces on the board)
+	pawnHash           uint64                // zobrist hash of only the pawns used as the key for the pawn hash table
+	pawnTable          []pawnHashEntry       // cached pawn structure evaluations which is allocated on first use
+	accumulator        [2][NNUE_HIDDEN]int16 // first layer of the neural network from whites and blacks perspective
+	accumulatorBucket  [2]int                // king bucket the accumulator of each perspective was computed for
+	accumulatorDirty   [2]bool               // the accumulator needs a full refresh before the next neural evaluation
+	skillLevel         int                   // strength of the alpha beta engine from 0 to MAX_SKILL_LEVEL (full strength)
+	skillSeed          uint64                // seed of the evaluation noise of a weakened engine which changes every move
+	moveOrderRand      *rand.Rand            // source of the random move ordering (the global source if nil)
+	infoHandler        func(SearchInfo)      // receives the progress of the searches
+	stop               chan bool             // closed to end the searches early
 }
 
 type BoardPrimitives struct {
@@ -120,11 +137,13 @@ type BoardPrimitives struct {
 	blackKingId        int
 }
 
+// getInitZobrist returns random keys for every square and piece with the white pieces indexed like pieceMap followed by
+// the black pieces
 func getInitZobrist() [64][12]uint64 {
 	rand.Seed(time.Now().UnixNano())
 	zobristHashTable := [64][12]uint64{}
 	for i := 0; i < 64; i++ {
-		for p := 0; p < 6; p++ {
+		for p := 0; p < 12; p++ {
 			zobristHashTable[i][p] = rand.Uint64()
 		}
 	}
@@ -135,6 +154,10 @@ func (board *Board) setHash() {
 	var h uint64 = 0
 	for i := 0; i < 64; i++ {
 		pi := board.pos2PieceId[i]
+		if pi == 0 {
+			// empty square
+			continue
+		}
 		piece := board.pieces[pi]
 		blackAdd := 0
 		if piece.isBlack {
@@ -187,9 +210,11 @@ func NewBoard(pieces [33]Piece, whiteIds [16]int, blackIds [16]int, isBlack bool
 		ply:                1,
 		posHashes:          [500]uint64{},
 		zobristHashTable:   zobristHashTable,
+		skillLevel:         MAX_SKILL_LEVEL,
 	}
 
 	board.setHash()
+	board.initEvaluation()
 
 	whitePiecePosB := board.combinePositionsOf(whiteIds)
 	blackPiecePosB := board.combinePositionsOf(blackIds)
@@ -213,6 +238,7 @@ type JSONRequest struct {
 	CaptureId   int    `json:"captureId"`
 	To          int    `json:"to"`
 	Promote     int    `json:"promote"` // 0 -> no promotion, 1 -> queen, 2 -> rook, 3 -> bishop, 4 -> knight
+	Level       int    `json:"level"`   // skill level of the engine for the "skill" request
 }
 
 type JSONSurrounding struct {
@@ -220,6 +246,11 @@ type JSONSurrounding struct {
 	Surrounding [8][8]bool `json:"surrounding"`
 }
 
+type JSONEval struct {
+	RequestType string    `json:"requestType"`
+	Trace       EvalTrace `json:"trace"`
+}
+
 type JSONEnd struct {
 	RequestType string `json:"requestType"`
 	Msg         string `json:"msg"`
@@ -228,6 +259,30 @@ type JSONEnd struct {
 var websocketConns map[int]*websocket.Conn
 var nextConnectionId = 1
 
+// engineBook is the opening book read from BOOK_FILE or nil
+var engineBook *Book
+
+// newGameEngines returns the engines ENGINE1 and ENGINE2 for the game of a single websocket connection
+// as engines keep state between the searches of a game
+func newGameEngines() ([2]Engine, error) {
+	var engines [2]Engine
+	for i, name := range []string{ENGINE1, ENGINE2} {
+		engine, err := NewEngine(name)
+		if err != nil {
+			return engines, err
+		}
+		switch e := engine.(type) {
+		case *AlphaBetaEngine:
+			e.Verbose = true
+		case *MCTSEngine:
+			e.Threads = 4
+			e.Verbose = true
+		}
+		engines[i] = engine
+	}
+	return engines, nil
+}
+
 func getPieceName(piece *Piece) string {
 	color := "white"
 	if piece.isBlack {
@@ -333,6 +388,66 @@ func (board *Board) GetFenWithoutMoves() string {
 	return strings.Join(parts[:len(parts)-2], " ")
 }
 
+// MirrorFen returns the FEN of the position with the ranks flipped and the colors swapped
+func MirrorFen(fen string) string {
+	parts := strings.Split(fen, " ")
+	rows := strings.Split(parts[0], "/")
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	parts[0] = swapCase(strings.Join(rows, "/"))
+	if len(parts) > 1 {
+		if parts[1] == "w" {
+			parts[1] = "b"
+		} else {
+			parts[1] = "w"
+		}
+	}
+	if len(parts) > 2 && parts[2] != "-" {
+		// white castling rights are written first
+		castling := swapCase(parts[2])
+		parts[2] = ""
+		for _, c := range "KQkq" {
+			if strings.ContainsRune(castling, c) {
+				parts[2] += string(c)
+			}
+		}
+	}
+	if len(parts) > 3 && len(parts[3]) == 2 {
+		parts[3] = string(parts[3][0]) + string('1'+'8'-parts[3][1])
+	}
+	return strings.Join(parts, " ")
+}
+
+// swapCase turns lower case letters into upper case ones and vice versa
+func swapCase(str string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, str)
+}
+
+// DisplayASCII returns the board as text with white at the bottom
+func (board *Board) DisplayASCII() string {
+	line := " +---+---+---+---+---+---+---+---+\n"
+	result := line
+	rows := strings.Split(strings.Split(board.GetFen(), " ")[0], "/")
+	for y, row := range rows {
+		result += " |"
+		for _, c := range row {
+			if unicode.IsDigit(c) {
+				result += strings.Repeat("   |", int(c-'0'))
+			} else {
+				result += " " + string(c) + " |"
+			}
+		}
+		result += " " + strconv.Itoa(8-y) + "\n" + line
+	}
+	return result + "   a   b   c   d   e   f   g   h\n"
+}
+
 func displayFen(fen string) string {
 	board := GetBoardFromFen(fen)
 	return board.display()
@@ -484,7 +599,7 @@ func (board *Board) CheckGameEnded() (bool, string, string) {
 			}
 		}
 	}
-	if !hasEnoughMaterial && numKnight <= 1 && numBishop <= 1 {
+	if !hasEnoughMaterial && numKnight+numBishop <= 1 {
 		hasEnoughMaterial = false
 		numBishop := 0
 		numKnight := 0
@@ -501,7 +616,7 @@ func (board *Board) CheckGameEnded() (bool, string, string) {
 				}
 			}
 		}
-		if !hasEnoughMaterial && numKnight <= 1 && numBishop <= 1 {
+		if !hasEnoughMaterial && numKnight+numBishop <= 1 {
 			return true, "draw", "Draw: Not enough material..."
 		}
 	}
@@ -525,24 +640,16 @@ func (board *Board) CheckGameEnded() (bool, string, string) {
 	return false, "", ""
 }
 
-func (board *Board) makeEngineMove() (Move, Move) {
+// makeEngineMove plays the book move or the move of the white or black engine of the connection
+func (board *Board) makeEngineMove(engines [2]Engine) (Move, Move) {
 	rand.Seed(time.Now().UnixNano())
-	engineMove := Move{}
-	engine := ENGINE1
+	engine := engines[0]
 	if board.IsBlacksTurn {
-		engine = ENGINE2
+		engine = engines[1]
 	}
-
-	switch engine {
-	case "random":
-		engineMove = board.randomEngineMove()
-	case "captureRandom":
-		engineMove = board.captureEngineMove()
-	case "checkCaptureRandom":
-		engineMove = board.checkCaptureEngineMove()
-	case "alphaBeta":
-		ab := board.AlphaBetaEngineMove([30]Move{}, 2, 30, false, true, MAX_ENGINE_TIME)
-		engineMove = ab.Pv[0]
+	engineMove, ok := board.BookMove(engineBook, false)
+	if !ok {
+		engineMove = engine.Search(board, SearchLimits{MoveTime: MAX_ENGINE_TIME}).Move
 	}
 	// time.Sleep(time.Duration((rand.Intn(3) + 1)) * time.Second)
 	// time.Sleep(500 * time.Millisecond)
@@ -564,6 +671,8 @@ func (board *Board) makeHumanMove(c *websocket.Conn) (bool, Move, Move) {
 	case "movement":
 		c.WriteJSON(JSONSurrounding{RequestType: "surrounding", Surrounding: bits2array(board.pieces[jsonObj.PieceId].movementB)})
 		// c.WriteJSON(JSONSurrounding{RequestType: "surrounding", Surrounding: bits2array(board.blackPiecePosB)})
+	case "skill":
+		board.SetSkillLevel(jsonObj.Level)
 	case "move", "capture":
 		isMove = true
 		move, needsPromotionType = board.NewMove(jsonObj.PieceId, jsonObj.CaptureId, jsonObj.To, jsonObj.Promote)
@@ -594,6 +703,14 @@ func Run() {
 
 	app.Static("/", "./../ghess/public")
 
+	if BOOK_FILE != "" {
+		book, err := ReadBook(BOOK_FILE)
+		if err != nil {
+			log.Println("Couldn't read the opening book:", err)
+		}
+		engineBook = book
+	}
+
 	board := GetBoardFromFen(START_FEN)
 	// board := GetBoardFromFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1") // Kiwipete
 	// board := GetBoardFromFen("r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1") // position 4
@@ -632,10 +749,16 @@ func Run() {
 		c.WriteJSON(JSONWelcome{Id: nextConnectionId})
 		nextConnectionId += 1
 
+		engines, err := newGameEngines()
+		if err != nil {
+			log.Println("Couldn't create the engines:", err)
+			return
+		}
+
 		isStarted := false
 
 		var jsonObj JSONRequest
-		err := c.ReadJSON(&jsonObj)
+		err = c.ReadJSON(&jsonObj)
 		move := Move{}
 		rookMove := Move{}
 		isMove := false
@@ -654,6 +777,11 @@ func Run() {
 			return
 		*/
 
+		err = c.WriteJSON(JSONEval{RequestType: "eval", Trace: board.EvalTrace()})
+		if err != nil {
+			log.Println("Couldn't send evaluation:", err)
+		}
+
 		for {
 			fmt.Println(board.GetFen())
 			ended, _, msg := board.CheckGameEnded()
@@ -677,6 +805,8 @@ func Run() {
 					}
 					if jsonObj.RequestType == "start" {
 						isStarted = true
+					} else if jsonObj.RequestType == "skill" {
+						board.SetSkillLevel(jsonObj.Level)
 					}
 				}
 
@@ -686,11 +816,11 @@ func Run() {
 					isStarted = false
 				}
 
-				move, rookMove = board.makeEngineMove()
+				move, rookMove = board.makeEngineMove(engines)
 				isMove = true
 			} else if GAME_MODE == "human_vs_engine" {
 				if board.IsBlacksTurn {
-					move, rookMove = board.makeEngineMove()
+					move, rookMove = board.makeEngineMove(engines)
 					isMove = true
 				} else {
 					isMove, move, rookMove = board.makeHumanMove(c)
@@ -712,6 +842,11 @@ func Run() {
 						break
 					}
 				}
+				err = c.WriteJSON(JSONEval{RequestType: "eval", Trace: board.EvalTrace()})
+				if err != nil {
+					log.Println("eval write:", err)
+					break
+				}
 				isMove = false
 			}
 		}
@@ -1,7 +1,14 @@
 package ghess
 
 import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNumMoves(t *testing.T) {
@@ -93,6 +100,46 @@ func TestNotation(t *testing.T) {
 	}
 }
 
+func TestInvalidLongAlgebraic(t *testing.T) {
+	board := GetBoardFromFen(START_FEN)
+	for _, moveStr := range []string{"zzzz", "e0e4", "e2i4", "e2e9", "e2e", "e2e4k", "e3e4", "e7e5"} {
+		if _, err := board.GetMoveFromLongAlgebraic(moveStr); err == nil {
+			t.Errorf("Expected an error for %s", moveStr)
+		}
+	}
+}
+
+func TestZobristHash(t *testing.T) {
+	// every board has its own random keys so all positions are hashed with the keys of the first one
+	keys := GetBoardFromFen(START_FEN).zobristHashTable
+	hash := func(fen string) uint64 {
+		board := GetBoardFromFen(fen)
+		board.zobristHashTable = keys
+		board.setHash()
+		return board.posHashes[board.ply]
+	}
+	// a black knight or bishop on the same square and a white pawn or an empty square
+	if hash("4k3/8/8/8/8/8/8/4K2n w - - 0 1") == hash("4k3/8/8/8/8/8/8/4K2b w - - 0 1") {
+		t.Errorf("Expected different hashes for different black pieces on the same square")
+	}
+	if hash("4k3/8/8/8/8/8/P7/4K3 w - - 0 1") == hash("4k3/8/8/8/8/8/8/4K3 w - - 0 1") {
+		t.Errorf("Expected different hashes with and without a white pawn")
+	}
+
+	board := GetBoardFromFen(START_FEN)
+	for _, moveStr := range []string{"g1f3", "g8f6", "f3g1", "f6g8", "g1f3", "g8f6", "f3g1", "f6g8"} {
+		if ended, _, msg := board.CheckGameEnded(); ended {
+			t.Fatalf("Expected the game to continue before %s but it ended with %s", moveStr, msg)
+		}
+		if err := board.MoveLongAlgebraic(moveStr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ended, endType, _ := board.CheckGameEnded(); !ended || endType != "draw" {
+		t.Errorf("Expected a draw by threefold repetition")
+	}
+}
+
 func TestBits2Array(t *testing.T) {
 	startFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 	board := GetBoardFromFen(startFEN)
@@ -143,6 +190,73 @@ func TestEngines(t *testing.T) {
 	}
 }
 
+func TestForcedMates(t *testing.T) {
+	for _, test := range forcedMateTests {
+		board := GetBoardFromFen(test.fen)
+		ab := board.AlphaBetaEngineMove([30]Move{}, 2, test.maxDepth, false, false, MAX_ENGINE_TIME)
+		algebraic := GetAlgebraicFromMove(&ab.Pv[0])
+		if algebraic != test.expected {
+			t.Errorf("Expected %s in position %s but got %s", test.expected, test.fen, algebraic)
+		}
+		if math.Abs(ab.Score) < 10000 {
+			t.Errorf("Expected a mate score in position %s but got %.2f", test.fen, ab.Score)
+		}
+		if ab.SelDepth <= ab.Depth {
+			t.Errorf("Expected the selective depth %d to be larger than the depth %d in position %s", ab.SelDepth, ab.Depth, test.fen)
+		}
+	}
+}
+
+func TestSingularMargin(t *testing.T) {
+	board := GetBoardFromFen(START_FEN)
+	moves := board.getPossibleMovesOrdered(false, [30]Move{}, 0)
+	// best score of the alternatives to the pv move with the reduced depth of the singular search
+	best := math.Inf(-1)
+	for _, om := range moves[1:] {
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&om.move)
+		ab := board.alphaBetaPruning(nil, true, 1, 1, math.Inf(-1), math.Inf(1), false, [30]Move{}, false, time.Now(), time.Hour, AlphaBetaOutput{})
+		board.reverseMove(&om.move, &boardPrimitives)
+		best = math.Max(best, ab.Score)
+	}
+	// the pv move has to be more than half a pawn better than every alternative
+	for _, lead := range []float64{30, 70} {
+		singular, completed := board.isSingular(nil, true, 0, 2, best+lead, true, moves, time.Now(), time.Hour)
+		if !completed || singular != (lead > SINGULAR_MARGIN) {
+			t.Errorf("Expected the pv move to be singular: %t with a lead of %.0f but got %t", lead > SINGULAR_MARGIN, lead, singular)
+		}
+	}
+}
+
+func TestSingularExtension(t *testing.T) {
+	// the recapture of the queen is singular and gets extended which makes the depth 4 search see as deep along the pv
+	// as a depth 5 search without the extension
+	fen := "4k3/8/8/3q4/8/8/3R4/3K4 w - - 0 1"
+	search := func(depth int, singular bool) AlphaBetaOutput {
+		board := GetBoardFromFen(fen)
+		board.SetSingularExtensions(singular)
+		return board.AlphaBetaEngineMove([30]Move{}, 2, depth, false, false, MAX_ENGINE_TIME)
+	}
+	plain, extended, deeper := search(4, false), search(4, true), search(5, false)
+	if plain.Score == extended.Score || extended.Score != deeper.Score {
+		t.Errorf("Expected the singular extension to change the depth 4 score %.0f to the depth 5 score %.0f but got %.0f", plain.Score, deeper.Score, extended.Score)
+	}
+}
+
+func TestQuiescenceCheckEvasions(t *testing.T) {
+	// white has to evade the check of the rook and loses the knight afterwards
+	board := GetBoardFromFen("1k6/8/8/3b4/4N3/8/8/r6K w - - 0 1")
+	evaded := board.quiesce(math.Inf(-1), math.Inf(1), true, 0, 0)
+	// after the first quiescence plies the check is ignored and white stands pat
+	standPat := board.quiesce(math.Inf(-1), math.Inf(1), true, QUIESCENCE_CHECK_PLIES, 0)
+	if standPat != board.staticEvaluation() {
+		t.Errorf("Expected white to stand pat with %.2f in check after %d quiescence plies but got %.2f", board.staticEvaluation(), QUIESCENCE_CHECK_PLIES, standPat)
+	}
+	if evaded > standPat-200 {
+		t.Errorf("Expected the knight to be lost after evading the check but got %.2f and %.2f standing pat", evaded, standPat)
+	}
+}
+
 func TestStaticEvaluation(t *testing.T) {
 	for _, test := range staticEvaluationTests {
 		board := GetBoardFromFen(test.fen)
@@ -153,6 +267,169 @@ func TestStaticEvaluation(t *testing.T) {
 	}
 }
 
+// checkIncrementalEvaluation compares the incrementally updated evaluation terms with a full recomputation in every node up to depth
+func checkIncrementalEvaluation(t *testing.T, board *Board, depth int) {
+	mg, eg, phase, pawnHash := board.mgScore, board.egScore, board.phase, board.pawnHash
+	board.initEvaluation()
+	if mg != board.mgScore || eg != board.egScore || phase != board.phase {
+		t.Errorf("Incremental evaluation (%d, %d, %d) differs from full evaluation (%d, %d, %d) in %s", mg, eg, phase, board.mgScore, board.egScore, board.phase, board.GetFen())
+	}
+	if pawnHash != board.pawnHash {
+		t.Errorf("Incremental pawn hash differs from the full pawn hash in %s", board.GetFen())
+	}
+	if depth == 0 {
+		return
+	}
+	for _, move := range board.getPossibleMoves() {
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&move)
+		checkIncrementalEvaluation(t, board, depth-1)
+		board.reverseMove(&move, &boardPrimitives)
+	}
+}
+
+func TestIncrementalEvaluation(t *testing.T) {
+	for _, fen := range incrementalEvaluationTests {
+		board := GetBoardFromFen(fen)
+		checkIncrementalEvaluation(t, &board, 2)
+	}
+}
+
+// squareName returns the name of a square like "e4"
+func squareName(pos int) string {
+	x, y := xy(pos)
+	return string(rune('a'+x)) + strconv.Itoa(8-y)
+}
+
+func TestPassedPawns(t *testing.T) {
+	for _, test := range passedPawnsTests {
+		board := GetBoardFromFen(test.fen)
+		entry := board.probePawnHash()
+		for color, expected := range [2][]string{test.whitePassed, test.blackPassed} {
+			actual := []string{}
+			for pos := 0; pos < 64; pos++ {
+				if entry.passed[color]&(1<<pos) != 0 {
+					actual = append(actual, squareName(pos))
+				}
+			}
+			if strings.Join(actual, " ") != strings.Join(expected, " ") {
+				t.Errorf("Passed pawns of color %d in %s expected %v actual %v", color, test.fen, expected, actual)
+			}
+		}
+	}
+}
+
+func TestMirroredPawnStructure(t *testing.T) {
+	for _, test := range mirroredPawnsTests {
+		board := GetBoardFromFen(test.fen)
+		mirroredBoard := GetBoardFromFen(test.mirroredFen)
+		mg, eg := board.getPawnScore()
+		mirroredMg, mirroredEg := mirroredBoard.getPawnScore()
+		if mg != -mirroredMg || eg != -mirroredEg {
+			t.Errorf("Pawn score (%d, %d) of %s is not the negative of (%d, %d) of %s", mg, eg, test.fen, mirroredMg, mirroredEg, test.mirroredFen)
+		}
+	}
+}
+
+func TestPawnStructure(t *testing.T) {
+	for _, test := range pawnStructureTests {
+		board := GetBoardFromFen(test.fen)
+		mg, eg := board.getPawnScore()
+		if mg != test.mg || eg != test.eg {
+			t.Errorf("The pawn structure score in %s should be (%d, %d) but is (%d, %d)", test.fen, test.mg, test.eg, mg, eg)
+		}
+		// second probe is answered by the pawn hash table
+		cachedMg, cachedEg := board.getPawnScore()
+		if cachedMg != mg || cachedEg != eg {
+			t.Errorf("The cached pawn structure score (%d, %d) differs from (%d, %d) in %s", cachedMg, cachedEg, mg, eg, test.fen)
+		}
+	}
+}
+
+func TestAttackMap(t *testing.T) {
+	board := GetBoardFromFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	whiteAttacks := bits2array(board.attackMap(false))
+	blackAttacks := bits2array(board.attackMap(true))
+	for j := 0; j < 8; j++ {
+		if !whiteAttacks[5][j] || !blackAttacks[2][j] {
+			t.Errorf("Every square on the third rank should be attacked by the pawns")
+		}
+		for i := 0; i < 5; i++ {
+			if whiteAttacks[i][j] || blackAttacks[7-i][j] {
+				t.Errorf("Square %d,%d shouldn't be attacked in the starting position", i, j)
+			}
+		}
+	}
+}
+
+func TestKingSafety(t *testing.T) {
+	for _, test := range kingSafetyTests {
+		saferBoard := GetBoardFromFen(test.saferFen)
+		lessSafeBoard := GetBoardFromFen(test.lessSafeFen)
+		whitePawnsB, blackPawnsB := saferBoard.getPawnBitboards()
+		safer := saferBoard.getKingSafetyOfColor(false, whitePawnsB, blackPawnsB, nil)
+		whitePawnsB, blackPawnsB = lessSafeBoard.getPawnBitboards()
+		lessSafe := lessSafeBoard.getKingSafetyOfColor(false, whitePawnsB, blackPawnsB, nil)
+		if safer <= lessSafe {
+			t.Errorf("The white king in %s (%d) should be safer than in %s (%d)", test.saferFen, safer, test.lessSafeFen, lessSafe)
+		}
+	}
+	for _, test := range mirroredKingSafetyTests {
+		board := GetBoardFromFen(test.fen)
+		mirroredBoard := GetBoardFromFen(test.mirroredFen)
+		if board.getKingSafetyScore() != -mirroredBoard.getKingSafetyScore() {
+			t.Errorf("King safety %d of %s is not the negative of %d of %s", board.getKingSafetyScore(), test.fen, mirroredBoard.getKingSafetyScore(), test.mirroredFen)
+		}
+	}
+}
+
+func TestEvalTrace(t *testing.T) {
+	fens := incrementalEvaluationTests
+	for _, test := range staticEvaluationTests {
+		fens = append(fens, test.fen)
+	}
+	for _, fen := range fens {
+		board := GetBoardFromFen(fen)
+		trace := board.EvalTrace()
+		score := board.staticEvaluation()
+		if math.Abs(trace.Total-score) > 1e-9 {
+			t.Errorf("The total of the evaluation trace %.2f differs from the static evaluation %.2f in %s", trace.Total, score, fen)
+		}
+		if !strings.Contains(trace.String(), "King safety") {
+			t.Errorf("The evaluation trace table should contain the king safety term")
+		}
+	}
+
+	// the network score and the noise of a reduced skill level are part of the total
+	SetNetwork(NewRandomNetwork(4))
+	defer SetNetwork(nil)
+	noisy := 0
+	for _, fen := range fens {
+		board := GetBoardFromFen(fen)
+		board.SetSkillLevel(5)
+		// only some positions get noise so every position is evaluated with several seeds of the noise
+		for seed := uint64(0); seed < 10; seed++ {
+			board.skillSeed = seed
+			trace := board.EvalTrace()
+			if score := board.staticEvaluation(); math.Abs(trace.Total-score) > 1e-9 {
+				t.Errorf("The total of the evaluation trace %.2f differs from the static evaluation %.2f with a network in %s", trace.Total, score, fen)
+			}
+			if trace.Endgame == "" && (!trace.Network || trace.NNUE != board.nnueEvaluation()) {
+				t.Errorf("Expected the network score %.2f in the evaluation trace of %s but got %.2f", board.nnueEvaluation(), fen, trace.NNUE)
+			}
+			if trace.Noise != 0 {
+				noisy++
+				if !strings.Contains(trace.String(), "Skill noise") {
+					t.Errorf("The evaluation trace table should contain the skill noise")
+				}
+			}
+		}
+	}
+	if noisy == 0 {
+		t.Errorf("Expected the skill noise in some evaluation traces")
+	}
+}
+
 func BenchmarkNumMove(b *testing.B) {
 	startFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 	board := GetBoardFromFen(startFEN)
@@ -176,3 +453,960 @@ func BenchmarkEvaluationStart5(b *testing.B) {
 	}
 	// 3.9s
 }
+
+func TestEvalCoefficients(t *testing.T) {
+	fens := incrementalEvaluationTests
+	for _, test := range staticEvaluationTests {
+		fens = append(fens, test.fen)
+	}
+	for _, fen := range fens {
+		board := GetBoardFromFen(fen)
+		score := board.getEvalCoefficients().evaluate(&evalParams)
+		if math.Abs(board.staticEvaluation()-score) > 1e-9 {
+			t.Errorf("The evaluation %.2f from the weight coefficients differs from the static evaluation %.2f in %s", score, board.staticEvaluation(), fen)
+		}
+	}
+}
+
+func TestParseTuningPosition(t *testing.T) {
+	for _, test := range parseTuningPositionTests {
+		position, err := ParseTuningPosition(test.line)
+		if err != nil {
+			t.Errorf("Parsing %q failed: %s", test.line, err)
+		} else if position != test.expected {
+			t.Errorf("Parsing %q should return %v but returned %v", test.line, test.expected, position)
+		}
+	}
+	if _, err := ParseTuningPosition("8/8/4k3/8/8/3QK3/8/8 b - - 0 1 2-0"); err == nil {
+		t.Errorf("Parsing an unknown result should fail")
+	}
+}
+
+func TestTuneEvalParams(t *testing.T) {
+	params := DefaultEvalParams()
+	before := newTuner(tuningPositions, params)
+	k := before.computeK()
+	tuned, err := TuneEvalParams(tuningPositions, params, TunerOptions{Epochs: 50, LearningRate: 1, K: k})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := newTuner(tuningPositions, tuned)
+	if after.error(k) >= before.error(k) {
+		t.Errorf("Tuning should reduce the error but it changed from %.6f to %.6f", before.error(k), after.error(k))
+	}
+	if GetEvalParams() != params {
+		t.Errorf("Tuning shouldn't change the weights used by the engine")
+	}
+}
+
+func TestLoadEvalParams(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := dir + "/" + name
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	defer SetEvalParams(DefaultEvalParams())
+
+	if err := LoadEvalParams(write("partial.json", `{"mobility": 7, "doubledPawn": [20, 30]}`)); err != nil {
+		t.Fatal(err)
+	}
+	expected := DefaultEvalParams()
+	expected.Mobility = 7
+	expected.DoubledPawn = [2]int{20, 30}
+	if GetEvalParams() != expected {
+		t.Errorf("Weights missing in the file should keep their built in value")
+	}
+
+	invalidFiles := []string{
+		write("unknown.json", `{"mobilty": 7}`),
+		write("syntax.json", `{"mobility": }`),
+		write("pieceValue.json", `{"mgPieceValue": [82, 0, 337, 477, 1025, 0]}`),
+		write("range.json", `{"mobility": 100000}`),
+		write("kingAttack.json", `{"kingAttackWeight": [0, 0, 50, 75, 88, 94, 97, 150]}`),
+		write("short.json", `{"doubledPawn": [20]}`),
+		write("long.json", `{"DoubledPawn": [20, 30, 40]}`),
+		write("shortPST.json", `{"mgPST": [[1, 2], [], [], [], [], []]}`),
+		dir + "/missing.json",
+	}
+	for _, path := range invalidFiles {
+		if err := LoadEvalParams(path); err == nil {
+			t.Errorf("Loading %s should fail", path)
+		}
+		if GetEvalParams() != expected {
+			t.Errorf("Loading the invalid file %s shouldn't change the weights", path)
+		}
+	}
+
+	board := GetBoardFromFen(staticEvaluationTests[1].fen)
+	before := board.staticEvaluation()
+	tuned := DefaultEvalParams()
+	tuned.MgPST[0][4*8+3] += 40
+	if err := tuned.WriteFile(dir + "/tuned.json"); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadEvalParams(dir + "/tuned.json"); err != nil {
+		t.Fatal(err)
+	}
+	board.RefreshEvaluation()
+	newBoard := GetBoardFromFen(staticEvaluationTests[1].fen)
+	if board.staticEvaluation() == before || math.Abs(board.staticEvaluation()-newBoard.staticEvaluation()) > 1e-9 {
+		t.Errorf("The refreshed evaluation %.2f should use the loaded weights like a new board %.2f", board.staticEvaluation(), newBoard.staticEvaluation())
+	}
+
+	if err := LoadEvalParams(""); err != nil || GetEvalParams() != DefaultEvalParams() {
+		t.Errorf("An empty path should restore the built in weights")
+	}
+}
+
+// checkIncrementalAccumulators compares the incrementally updated accumulators with a full refresh in every node up to depth
+func checkIncrementalAccumulators(t *testing.T, board *Board, depth int) {
+	board.nnueEvaluation()
+	accumulator := board.accumulator
+	board.refreshAccumulator(0)
+	board.refreshAccumulator(1)
+	if accumulator != board.accumulator {
+		t.Errorf("The incrementally updated accumulators differ from the refreshed ones in %s", board.GetFen())
+		return
+	}
+	if depth == 0 {
+		return
+	}
+	for _, move := range board.getPossibleMoves() {
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&move)
+		checkIncrementalAccumulators(t, board, depth-1)
+		board.reverseMove(&move, &boardPrimitives)
+	}
+}
+
+func TestNNUEIncrementalAccumulators(t *testing.T) {
+	SetNetwork(NewRandomNetwork(1))
+	defer SetNetwork(nil)
+	for _, fen := range incrementalEvaluationTests {
+		board := GetBoardFromFen(fen)
+		checkIncrementalAccumulators(t, &board, 2)
+	}
+}
+
+func TestNNUEMirroredEvaluation(t *testing.T) {
+	SetNetwork(NewRandomNetwork(2))
+	defer SetNetwork(nil)
+	for _, test := range append(mirroredPawnsTests, mirroredKingSafetyTests...) {
+		board := GetBoardFromFen(test.fen)
+		mirroredBoard := GetBoardFromFen(test.mirroredFen)
+		if board.staticEvaluation() != -mirroredBoard.staticEvaluation() {
+			t.Errorf("The neural evaluation %.2f of %s is not the negative of %.2f of %s", board.staticEvaluation(), test.fen, mirroredBoard.staticEvaluation(), test.mirroredFen)
+		}
+	}
+}
+
+func TestNNUEFile(t *testing.T) {
+	network := NewRandomNetwork(3)
+	path := t.TempDir() + "/net.nnue"
+	if err := network.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	readNetwork, err := ReadNetwork(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *readNetwork != *network {
+		t.Errorf("The network read from %s differs from the written one", path)
+	}
+
+	board := GetBoardFromFen(staticEvaluationTests[1].fen)
+	handcrafted := board.staticEvaluation()
+	SetNetwork(readNetwork)
+	defer SetNetwork(nil)
+	board.RefreshEvaluation()
+	if board.staticEvaluation() != board.nnueEvaluation() || board.staticEvaluation() == handcrafted {
+		t.Errorf("The static evaluation should use the network after it's set")
+	}
+
+	if err := ioutil.WriteFile(path, []byte("GHNN\x02\x00\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadNetwork(path); err == nil {
+		t.Errorf("Reading a network with an unsupported version should fail")
+	}
+}
+
+func TestPlaySelfPlayGame(t *testing.T) {
+	options := SelfPlayOptions{RandomPlies: 4, Nodes: 300, MaxPlies: 24, Rand: rand.New(rand.NewSource(1))}
+	positions := PlaySelfPlayGame(options)
+	if len(positions) == 0 {
+		t.Fatalf("The self play game should return positions")
+	}
+	for _, position := range positions {
+		parsed, err := ParseTuningPosition(position.String())
+		if err != nil {
+			t.Errorf("The self play position %q can't be parsed: %s", position.String(), err)
+			continue
+		}
+		if parsed.Fen != position.Fen || parsed.Result != positions[0].Result {
+			t.Errorf("Parsing %q returned %v", position.String(), parsed)
+		}
+		board := GetBoardFromFen(position.Fen)
+		if board.check {
+			t.Errorf("The self play position %s shouldn't be in check", position.Fen)
+		}
+	}
+	// without a source the random opening uses its own one
+	options.Rand = nil
+	options.MaxPlies = 8
+	if positions := PlaySelfPlayGame(options); len(positions) == 0 {
+		t.Errorf("The self play game without a random source should return positions")
+	}
+}
+
+func TestNodeLimit(t *testing.T) {
+	board := GetBoardFromFen(START_FEN)
+	board.SetNodeLimit(5000)
+	ab := board.AlphaBetaEngineMove([30]Move{}, 1, 30, false, false, 60*1000)
+	if !ab.Completed || ab.NodesSearched < 5000 || ab.NodesSearched > 10000 {
+		t.Errorf("The search with a limit of 5000 nodes searched %d nodes (depth %d)", ab.NodesSearched, ab.Depth)
+	}
+}
+
+func TestSyzygyIndexTables(t *testing.T) {
+	maxKK := 0
+	for _, squares := range tbMapKK {
+		for _, code := range squares {
+			maxKK = max(maxKK, code)
+		}
+	}
+	if maxKK != 461 {
+		t.Errorf("The two kings should have 462 placements but have %d", maxKK+1)
+	}
+	if tbMapA1D1D4[1] != 0 || tbMapA1D1D4[27] != 9 || tbMapB1H1H7[55] != 27 {
+		t.Errorf("The triangle encodings are wrong")
+	}
+	if tbMapPawns[8] != 47 || tbMapPawns[15] != 46 || tbBinomial[2][5] != 10 || tbLeadPawnsSize[1][0] != 6 {
+		t.Errorf("The pawn encodings are wrong")
+	}
+}
+
+// newSyzygyIndexTable returns a table without data which is enough to compute indices
+func newSyzygyIndexTable(t *testing.T, test syzygyIndexStruct) *tbTable {
+	table, err := newTBTable(test.code, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for f := 0; f < 4; f++ {
+		for i := 0; i < 2; i++ {
+			d := &table.items[i][f]
+			copy(d.pieces[:], test.pieces)
+			table.setGroups(d, test.order, f)
+		}
+	}
+	return table
+}
+
+// syzygySymmetry maps a square by one of the 8 symmetries of the board (only the 2 left right mirrors with pawns)
+func syzygySymmetry(sq int, symmetry int) int {
+	if symmetry&1 != 0 {
+		sq ^= 7
+	}
+	if symmetry&2 != 0 {
+		sq ^= 56
+	}
+	if symmetry&4 != 0 {
+		sq = ((sq >> 3) | (sq << 3)) & 63
+	}
+	return sq
+}
+
+func TestSyzygyIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, test := range syzygyIndexTests {
+		table := newSyzygyIndexTable(t, test)
+		numSymmetries := 8
+		if table.hasPawns {
+			numSymmetries = 2
+		}
+		// symmetric positions may share an index but other positions may not
+		positionOfIndex := make(map[[2]uint64]string)
+		for n := 0; n < 20000; n++ {
+			var squares [TB_PIECES]int
+			var position tbPosition
+			position.key = table.key
+			for i, piece := range test.pieces {
+				for {
+					squares[i] = r.Intn(64)
+					if position.pieces[squares[i]] == 0 && (piece&7 != 1 || (squares[i] >= 8 && squares[i] < 56)) {
+						break
+					}
+				}
+				position.pieces[squares[i]] = piece
+			}
+			kings := [2]int{}
+			for sq, piece := range position.pieces {
+				if piece&7 == 6 {
+					kings[piece>>3] = sq
+				}
+			}
+			if tbSquareDistance(kings[0], kings[1]) <= 1 {
+				continue
+			}
+
+			canonical := ""
+			var indices [][2]uint64
+			for symmetry := 0; symmetry < numSymmetries; symmetry++ {
+				var mirrored tbPosition
+				mirrored.key = position.key
+				for sq, piece := range position.pieces {
+					mirrored.pieces[syzygySymmetry(sq, symmetry)] = piece
+				}
+				d, tbFile, idx, _ := table.index(&mirrored)
+				n := 0
+				for d.groupLen[n] != 0 {
+					n++
+				}
+				if idx >= d.groupIdx[n] {
+					t.Fatalf("%s: index %d is outside of the table of size %d", test.code, idx, d.groupIdx[n])
+				}
+				indices = append(indices, [2]uint64{uint64(tbFile), idx})
+				if s := fmt.Sprint(mirrored.pieces); canonical == "" || s < canonical {
+					canonical = s
+				}
+			}
+			for _, idx := range indices {
+				if other, ok := positionOfIndex[idx]; ok && other != canonical {
+					t.Fatalf("%s: the positions %s and %s have the same index %v", test.code, other, canonical, idx)
+				}
+				positionOfIndex[idx] = canonical
+			}
+		}
+	}
+}
+
+// loadSyzygyTestTables loads the official test tablebases listed in the README of syzygyTestPath
+func loadSyzygyTestTables(t *testing.T) {
+	n, err := InitSyzygy(syzygyTestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatalf("no tablebases in %s (see its README)", syzygyTestPath)
+	}
+	t.Cleanup(func() { InitSyzygy("") })
+}
+
+func TestSyzygyProbe(t *testing.T) {
+	loadSyzygyTestTables(t)
+	for _, test := range syzygyTests {
+		board := GetBoardFromFen(test.fen)
+		wdl, ok := board.ProbeWDL()
+		if !ok || wdl != test.wdl {
+			t.Errorf("WDL of %s should be %d but is %d (%v)", test.fen, test.wdl, wdl, ok)
+		}
+		dtz, ok := board.ProbeDTZ()
+		if !ok || signOf(dtz) != signOf(test.wdl) || (test.dtz != 0 && dtz != test.dtz) {
+			t.Errorf("DTZ of %s should be %d with the sign of the WDL %d but is %d", test.fen, test.dtz, test.wdl, dtz)
+		}
+		move, rootWdl, ok := board.probeSyzygyRoot()
+		if !ok || rootWdl != test.wdl {
+			t.Errorf("The root probe of %s returned %d instead of %d", test.fen, rootWdl, test.wdl)
+			continue
+		}
+		board.Move(&move)
+		if wdl, _ := board.ProbeWDL(); wdl != -test.wdl {
+			t.Errorf("The tablebase move %s in %s leads to %d for the opponent", GetAlgebraicFromMove(&move), test.fen, wdl)
+		}
+	}
+}
+
+func TestSyzygySearch(t *testing.T) {
+	loadSyzygyTestTables(t)
+	// the rook can be captured which leads to a won KQvK position
+	board := GetBoardFromFen("8/8/4k3/8/3r4/8/8/3QK3 w - - 0 1")
+	ab := board.AlphaBetaEngineMove([30]Move{}, 1, 4, false, false, 10000)
+	if ab.Score < SYZYGY_WIN_SCORE-10 || GetAlgebraicFromMove(&ab.Pv[0]) != "d1d4" {
+		t.Errorf("The search should capture the rook with a tablebase win but played %s with %f", GetAlgebraicFromMove(&ab.Pv[0]), ab.Score)
+	}
+}
+
+func TestKPKBitbase(t *testing.T) {
+	for _, test := range kpkTests {
+		board := GetBoardFromFen(test.fen)
+		name, score, ok := board.evaluateEndgame()
+		if !ok || name != "KPK" {
+			t.Errorf("%s should be evaluated as KPK", test.fen)
+			continue
+		}
+		if (score != 0) != test.win {
+			t.Errorf("The KPK evaluation of %s is %.2f but the position is a win: %v", test.fen, score, test.win)
+		}
+	}
+}
+
+func TestEndgameEvaluation(t *testing.T) {
+	for _, test := range endgameOrderTests {
+		better := GetBoardFromFen(test.better)
+		worse := GetBoardFromFen(test.worse)
+		if better.staticEvaluation() <= worse.staticEvaluation() {
+			t.Errorf("%s should be evaluated better than %s but has %.2f <= %.2f", test.better, test.worse, better.staticEvaluation(), worse.staticEvaluation())
+		}
+	}
+}
+
+func TestEndgameScale(t *testing.T) {
+	for _, test := range endgameScaleTests {
+		board := GetBoardFromFen(test.fen)
+		trace := board.EvalTrace()
+		if trace.Scale != test.scale {
+			t.Errorf("The scale of %s should be %d but is %d", test.fen, test.scale, trace.Scale)
+		}
+		if score := board.staticEvaluation(); score != trace.Total || (test.scale == SCALE_DRAW && score != 0) {
+			t.Errorf("The evaluation %.2f of %s doesn't match the scale %d", score, test.fen, test.scale)
+		}
+	}
+}
+
+func TestPolyglotKey(t *testing.T) {
+	for _, test := range polyglotKeyTests {
+		board := GetBoardFromFen(START_FEN)
+		for _, moveStr := range test.moves {
+			if err := board.MoveLongAlgebraic(moveStr); err != nil {
+				t.Fatalf("%s: %s", moveStr, err)
+			}
+		}
+		if key := board.PolyglotKey(); key != test.key {
+			t.Errorf("Expected key %016x after %v but got %016x", test.key, test.moves, key)
+		}
+	}
+}
+
+func TestPolyglotMove(t *testing.T) {
+	for _, test := range polyglotMoveTests {
+		board := GetBoardFromFen(test.fen)
+		move, err := board.GetMoveFromLongAlgebraic(test.move)
+		if err != nil {
+			t.Fatalf("%s: %s", test.move, err)
+		}
+		if encoded := board.PolyglotMove(&move); encoded != test.encoded {
+			t.Errorf("Expected %s to be encoded as %d but got %d", test.move, test.encoded, encoded)
+		}
+		decoded, err := board.moveFromPolyglot(test.encoded)
+		if err != nil || decoded != move {
+			t.Errorf("Expected %d to be decoded as %s but got %s (%v)", test.encoded, test.move, GetAlgebraicFromMove(&decoded), err)
+		}
+	}
+}
+
+func TestBook(t *testing.T) {
+	board := GetBoardFromFen(START_FEN)
+	key := board.PolyglotKey()
+	e4, _ := board.GetMoveFromLongAlgebraic("e2e4")
+	d4, _ := board.GetMoveFromLongAlgebraic("d2d4")
+	book := NewBook([]BookEntry{
+		{Key: key, Move: board.PolyglotMove(&d4), Weight: 1},
+		{Key: key + 1, Move: board.PolyglotMove(&d4), Weight: 100},
+		{Key: key, Move: board.PolyglotMove(&e4), Weight: 3},
+		{Key: key - 1, Move: board.PolyglotMove(&e4), Weight: 100},
+	})
+	path := t.TempDir() + "/book.bin"
+	if err := book.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	book, err := ReadBook(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book.Len() != 4 || len(book.Entries(key)) != 2 {
+		t.Fatalf("Expected 4 entries and 2 for the start position but got %d and %d", book.Len(), len(book.Entries(key)))
+	}
+	if move, ok := board.BookMove(book, true); !ok || move != e4 {
+		t.Errorf("Expected e2e4 as the best book move but got %s", GetAlgebraicFromMove(&move))
+	}
+	counts := map[Move]int{}
+	for i := 0; i < 400; i++ {
+		move, _ := board.BookMove(book, false)
+		counts[move]++
+	}
+	if counts[e4]+counts[d4] != 400 || counts[e4] < counts[d4] || counts[d4] == 0 {
+		t.Errorf("Expected e2e4 about three times as often as d2d4 but got %d and %d", counts[e4], counts[d4])
+	}
+	board.MoveLongAlgebraic("e2e4")
+	if _, ok := board.BookMove(book, false); ok {
+		t.Errorf("Expected no book move after e2e4")
+	}
+}
+
+func TestParseStandardAlgebraic(t *testing.T) {
+	for _, test := range standardAlgebraicTests {
+		board := GetBoardFromFen(test.fen)
+		// check symbols, castling with zeros and promotions without = are accepted as well
+		variants := []string{test.expected, test.expected + "+", strings.ReplaceAll(test.expected, "O", "0"), strings.Replace(test.expected, "=", "", 1)}
+		for _, san := range variants {
+			move, err := board.GetMoveFromStandardAlgebraic(san)
+			if err != nil {
+				t.Errorf(err.Error())
+			} else if GetAlgebraicFromMove(&move) != test.move {
+				t.Errorf("Expected %s for %s but got %s", test.move, san, GetAlgebraicFromMove(&move))
+			}
+		}
+	}
+}
+
+func TestSkillLevelFromElo(t *testing.T) {
+	for _, test := range skillEloTests {
+		if level := SkillLevelFromElo(test.elo); level != test.level {
+			t.Errorf("Expected skill level %d for Elo %d but got %d", test.level, test.elo, level)
+		}
+	}
+}
+
+func TestSkillEngineMove(t *testing.T) {
+	for _, test := range skillCaptureTests {
+		board := GetBoardFromFen(test.fen)
+		board.SetSkillLevel(15)
+		for i := 0; i < 3; i++ {
+			ab := board.AlphaBetaEngineMove([30]Move{}, 2, 30, false, false, 300)
+			if moveStr := GetAlgebraicFromMove(&ab.Pv[0]); moveStr != test.expected {
+				t.Errorf("Expected skill level 15 to play %s in %s but got %s", test.expected, test.fen, moveStr)
+			}
+		}
+	}
+
+	// the weakest level doesn't play the same move every time and stays within its depth
+	board := GetBoardFromFen(START_FEN)
+	board.SetSkillLevel(0)
+	moves := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		ab := board.AlphaBetaEngineMove([30]Move{}, 2, 30, false, false, 1000)
+		if !board.isLegal(&ab.Pv[0]) || ab.Depth != skillDepth(0) {
+			t.Fatalf("Expected a legal move at depth %d but got %s at depth %d", skillDepth(0), GetAlgebraicFromMove(&ab.Pv[0]), ab.Depth)
+		}
+		moves[GetAlgebraicFromMove(&ab.Pv[0])] = true
+	}
+	if len(moves) < 2 {
+		t.Errorf("Expected different moves at skill level 0 but got %v", moves)
+	}
+	if board.nodeLimit != 0 {
+		t.Errorf("Expected the node limit to be restored after the search")
+	}
+	board.SetSkillLevel(MAX_SKILL_LEVEL)
+	if noise := board.skillNoise(); noise != 0 {
+		t.Errorf("Expected no evaluation noise at full strength but got %f", noise)
+	}
+}
+
+func TestMCTSEngine(t *testing.T) {
+	for _, threads := range []int{1, 4} {
+		for _, test := range mctsTests {
+			board := GetBoardFromFen(test.fen)
+			fen := board.GetFen()
+			engine := NewMCTSEngine()
+			engine.Threads = threads
+			result := engine.Search(&board, SearchLimits{Nodes: 2000})
+			if moveStr := GetAlgebraicFromMove(&result.Move); moveStr != test.expected {
+				t.Errorf("Expected %s in %s with %d threads but got %s", test.expected, test.fen, threads, moveStr)
+			}
+			if result.Nodes != 2000 {
+				t.Errorf("Expected 2000 playouts but got %d", result.Nodes)
+			}
+			if board.GetFen() != fen {
+				t.Errorf("Expected the board to be unchanged after the search but got %s", board.GetFen())
+			}
+		}
+	}
+}
+
+func TestMCTSDepth(t *testing.T) {
+	// a depth limit alone stops the search once the most visited line is that long or ends in a mate
+	for _, test := range []struct {
+		fen   string
+		depth int
+		plies int // minimum length of the pv
+	}{{START_FEN, 1, 1}, {START_FEN, 4, 4}, {mctsTests[1].fen, 20, 1}} {
+		board := GetBoardFromFen(test.fen)
+		done := make(chan SearchResult)
+		go func() {
+			done <- NewMCTSEngine().Search(&board, SearchLimits{Depth: test.depth})
+		}()
+		select {
+		case result := <-done:
+			if len(result.Pv) < test.plies {
+				t.Errorf("Expected a pv of at least %d plies in %s but got %d", test.plies, test.fen, len(result.Pv))
+			}
+		case <-time.After(30 * time.Second):
+			t.Fatalf("The search with depth %d in %s didn't stop", test.depth, test.fen)
+		}
+	}
+}
+
+func TestMCTSTreeReuse(t *testing.T) {
+	board := GetBoardFromFen(START_FEN)
+	engine := NewMCTSEngine()
+	result := engine.Search(&board, SearchLimits{Nodes: 300})
+	board.Move(&result.Move)
+	reply := engine.root.children[0]
+	for _, child := range engine.root.children {
+		if child.move == result.Move {
+			reply = child
+		}
+	}
+	if engine.reuseTree(&board) != reply {
+		t.Errorf("Expected the subtree of %s to be reused", GetAlgebraicFromMove(&result.Move))
+	}
+	engine.NewGame()
+	if engine.reuseTree(&board) != nil {
+		t.Errorf("Expected no tree after NewGame")
+	}
+}
+
+func TestMCTSValue(t *testing.T) {
+	for _, score := range []float64{-500, -30, 0, 250, 1200} {
+		for _, isBlacksTurn := range []bool{false, true} {
+			value := valueFromScore(score, isBlacksTurn)
+			if math.Abs(scoreFromValue(value, isBlacksTurn)-score) > 1e-6 {
+				t.Errorf("Expected score %f to be restored from value %f", score, value)
+			}
+		}
+	}
+}
+
+func TestEngineInterface(t *testing.T) {
+	engines := []Engine{&AlphaBetaEngine{}, NewMCTSEngine()}
+	for _, engine := range engines {
+		board := GetBoardFromFen(mctsTests[0].fen)
+		result := engine.Search(&board, SearchLimits{Depth: 3, Nodes: 1000})
+		if moveStr := GetAlgebraicFromMove(&result.Move); moveStr != mctsTests[0].expected {
+			t.Errorf("Expected %s to play %s but got %s", engine.Name(), mctsTests[0].expected, moveStr)
+		}
+		if len(result.Pv) == 0 || result.Pv[0] != result.Move {
+			t.Errorf("Expected the pv of %s to start with the best move", engine.Name())
+		}
+	}
+}
+
+func TestNewGameEngines(t *testing.T) {
+	first, err := newGameEngines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := newGameEngines()
+	for i, name := range []string{ENGINE1, ENGINE2} {
+		if first[i].Name() != name {
+			t.Errorf("Expected the engine %s but got %s", name, first[i].Name())
+		}
+		if first[i] == second[i] {
+			t.Errorf("Expected every connection to get its own %s engine", name)
+		}
+	}
+}
+
+func TestMultiPV(t *testing.T) {
+	board := GetBoardFromFen(mctsTests[0].fen)
+	board.SetMultiPV(3)
+	ab := board.AlphaBetaEngineMove([30]Move{}, 2, 3, false, false, 100000)
+	if len(ab.Lines) != 3 {
+		t.Fatalf("Expected 3 lines but got %d", len(ab.Lines))
+	}
+	if ab.Lines[0].Pv[0] != ab.Pv[0] || ab.Lines[0].Score != ab.Score {
+		t.Errorf("Expected the first line to be the best line")
+	}
+	for i := 1; i < len(ab.Lines); i++ {
+		if ab.Lines[i].Score > ab.Lines[i-1].Score {
+			t.Errorf("Expected the lines to be sorted by score but got %f after %f", ab.Lines[i].Score, ab.Lines[i-1].Score)
+		}
+		for j := 0; j < i; j++ {
+			if ab.Lines[i].Pv[0] == ab.Lines[j].Pv[0] {
+				t.Errorf("Expected different root moves but got %s twice", GetAlgebraicFromMove(&ab.Lines[i].Pv[0]))
+			}
+		}
+	}
+	if len(board.excludedRootMoves) != 0 {
+		t.Errorf("Expected no excluded root moves after the search")
+	}
+}
+
+func TestSetHashSize(t *testing.T) {
+	defer func() { pawnHashSize = PAWN_HASH_SIZE }()
+	SetHashSize(1)
+	small := pawnHashSize
+	SetHashSize(16)
+	if pawnHashSize != 16*small {
+		t.Errorf("Expected %d pawn hash entries for 16 MB but got %d", 16*small, pawnHashSize)
+	}
+	board := GetBoardFromFen(START_FEN)
+	board.staticEvaluation()
+	if len(board.pawnTable) != pawnHashSize {
+		t.Errorf("Expected a pawn hash table with %d entries but got %d", pawnHashSize, len(board.pawnTable))
+	}
+	copied := board.Copy()
+	copied.staticEvaluation()
+	if &copied.pawnTable[0] == &board.pawnTable[0] {
+		t.Errorf("Expected the copy of a board to have its own pawn hash table")
+	}
+}
+
+func TestSearchInfo(t *testing.T) {
+	for _, test := range mateInTests {
+		board := GetBoardFromFen(test.fen)
+		infos := []SearchInfo{}
+		board.SetInfoHandler(func(info SearchInfo) {
+			infos = append(infos, info)
+		})
+		ab := board.AlphaBetaEngineMove([30]Move{}, 2, 6, false, false, 100000)
+		if len(infos) == 0 {
+			t.Fatalf("Expected search info for %s", test.fen)
+		}
+		for i := 1; i < len(infos); i++ {
+			if infos[i].Depth < infos[i-1].Depth {
+				t.Errorf("Expected increasing depths but got %d after %d", infos[i].Depth, infos[i-1].Depth)
+			}
+		}
+		last := infos[len(infos)-1]
+		if last.Mate != test.moves {
+			t.Errorf("Expected mate %d in %s but got %d", test.moves, test.fen, last.Mate)
+		}
+		if len(last.Pv) == 0 || last.Pv[0] != ab.Pv[0] {
+			t.Errorf("Expected the reported pv to start with the best move in %s", test.fen)
+		}
+	}
+}
+
+func TestMateScore(t *testing.T) {
+	for _, test := range mateInTests {
+		board := GetBoardFromFen(test.fen)
+		for _, moves := range []int{test.moves, -test.moves, 3, -4} {
+			if actual, ok := board.MateIn(board.MateScore(moves)); !ok || actual != moves {
+				t.Errorf("Expected mate %d in %s to be restored from its score but got %d", moves, test.fen, actual)
+			}
+		}
+	}
+}
+
+func TestSearchMoves(t *testing.T) {
+	board := GetBoardFromFen(mctsTests[0].fen)
+	searchMoves := []Move{}
+	for _, moveStr := range []string{"e1f1", "d2d3"} {
+		move, err := board.GetMoveFromLongAlgebraic(moveStr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		searchMoves = append(searchMoves, move)
+	}
+	board.SetSearchMoves(searchMoves)
+	ab := board.AlphaBetaEngineMove([30]Move{}, 2, 3, false, false, 100000)
+	if moveStr := GetAlgebraicFromMove(&ab.Pv[0]); moveStr != "e1f1" && moveStr != "d2d3" {
+		t.Errorf("Expected one of the search moves but got %s", moveStr)
+	}
+	board.SetSearchMoves(searchMoves[1:])
+	ab = board.AlphaBetaEngineMove([30]Move{}, 2, 3, false, false, 100000)
+	if moveStr := GetAlgebraicFromMove(&ab.Pv[0]); moveStr != "d2d3" {
+		t.Errorf("Expected the only search move d2d3 but got %s", moveStr)
+	}
+	board.SetSearchMoves(nil)
+	ab = board.AlphaBetaEngineMove([30]Move{}, 2, 3, false, false, 100000)
+	if moveStr := GetAlgebraicFromMove(&ab.Pv[0]); moveStr != mctsTests[0].expected {
+		t.Errorf("Expected %s without search moves but got %s", mctsTests[0].expected, moveStr)
+	}
+}
+
+func TestStopChannel(t *testing.T) {
+	board := GetBoardFromFen(START_FEN)
+	stop := make(chan bool)
+	board.SetStopChannel(stop)
+	time.AfterFunc(200*time.Millisecond, func() { close(stop) })
+	start := time.Now()
+	ab := board.AlphaBetaEngineMove([30]Move{}, 2, 30, false, false, 100000)
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Expected the search to stop soon after the stop channel was closed but it took %s", time.Since(start))
+	}
+	if _, err := board.GetMoveFromLongAlgebraic(GetAlgebraicFromMove(&ab.Pv[0])); err != nil {
+		t.Errorf("Expected a legal move after stopping but got %v", err)
+	}
+	// a search which is stopped right away still returns a legal move
+	ab = board.AlphaBetaEngineMove([30]Move{}, 2, 30, false, false, 100000)
+	if _, err := board.GetMoveFromLongAlgebraic(GetAlgebraicFromMove(&ab.Pv[0])); err != nil {
+		t.Errorf("Expected a legal move from a stopped search but got %v", err)
+	}
+	result := NewMCTSEngine().Search(&board, SearchLimits{})
+	if result.Nodes != 1 {
+		t.Errorf("Expected a single playout of the stopped MCTS but got %d", result.Nodes)
+	}
+}
+
+func TestPerftDivide(t *testing.T) {
+	for _, test := range perftDivideTests {
+		board := GetBoardFromFen(test.fen)
+		for i, expected := range test.expected {
+			ply := i + 1
+			divide := board.PerftDivide(ply)
+			if len(divide) != test.expected[0] {
+				t.Errorf("Expected %d moves in the divide of %s at ply %d but got %d", test.expected[0], test.fen, ply, len(divide))
+			}
+			total := 0
+			for _, n := range divide {
+				total += n
+			}
+			if total != expected {
+				t.Errorf("Expected the divide of %s at ply %d to sum up to %d but got %d", test.fen, ply, expected, total)
+			}
+		}
+		if board.GetFen() != test.fen {
+			t.Errorf("Expected the board to be unchanged after perft but got %s", board.GetFen())
+		}
+	}
+}
+
+func TestMirrorFen(t *testing.T) {
+	for _, test := range append(mirroredPawnsTests, mirroredKingSafetyTests...) {
+		if mirrored := MirrorFen(test.fen); mirrored != test.mirroredFen {
+			t.Errorf("Expected the mirrored FEN of %s to be %s but got %s", test.fen, test.mirroredFen, mirrored)
+		}
+		if MirrorFen(test.mirroredFen) != test.fen {
+			t.Errorf("Expected mirroring %s twice to return the FEN", test.fen)
+		}
+	}
+	if mirrored := MirrorFen("rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kq e3 0 3"); mirrored != "rnbqkbnr/pppp1ppp/8/3Pp3/8/8/PPP1PPPP/RNBQKBNR w Qk e6 0 3" {
+		t.Errorf("Unexpected mirrored FEN %s", mirrored)
+	}
+}
+
+func TestBench(t *testing.T) {
+	first := Bench(2)
+	second := Bench(2)
+	if first.Nodes == 0 || first.Nodes != second.Nodes {
+		t.Errorf("Expected the same number of nodes in every bench but got %d and %d", first.Nodes, second.Nodes)
+	}
+}
+
+func TestEloDifference(t *testing.T) {
+	for _, test := range eloDifferenceTests {
+		elo, margin := EloDifference(test.wins, test.draws, test.losses)
+		if math.Abs(elo-test.elo) > 0.01 || margin < 0 {
+			t.Errorf("Expected an Elo difference of %.2f for %d-%d-%d but got %.2f +- %.2f", test.elo, test.wins,
+				test.draws, test.losses, elo, margin)
+		}
+	}
+}
+
+func TestAdjudication(t *testing.T) {
+	adjudication := Adjudication{ResignScore: 400, ResignMoves: 2, DrawScore: 10, DrawMoves: 4, DrawMoveNumber: 40}
+	for _, test := range adjudicationTests {
+		result, ok := adjudication.byScore(test.scores, test.nextMove)
+		if result != test.result || ok != (test.result != "") {
+			t.Errorf("Expected the scores %v at move %d to be adjudicated as %q but got %q", test.scores,
+				test.nextMove, test.result, result)
+		}
+	}
+}
+
+// gameRecorder is a GameEngine which searches with the embedded engine and records the start position and the
+// number of moves of the game it gets
+type gameRecorder struct {
+	Engine
+	startFens []string
+	plies     []int
+}
+
+func (engine *gameRecorder) SearchGame(board *Board, startFen string, moves []Move, limits SearchLimits) SearchResult {
+	engine.startFens = append(engine.startFens, startFen)
+	engine.plies = append(engine.plies, len(moves))
+	return engine.Search(board, limits)
+}
+
+func TestPlayGame(t *testing.T) {
+	white, _ := NewEngine("alphaBeta")
+	black, _ := NewEngine("random")
+	opening := Opening{Fen: "6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1"}
+	game := PlayGame(white, black, GameOptions{Opening: opening, TimeControl: TimeControl{Depth: 3}})
+	if game.Result != "1-0" || game.Termination != "checkmate" || len(game.Moves) != 1 {
+		t.Errorf("Expected the mate in one but got %s by %s after %d plies", game.Result, game.Termination,
+			len(game.Moves))
+	}
+
+	// the PGN of a game can be read as an opening again
+	opening = Opening{Fen: START_FEN, Moves: []string{"e2e4", "c7c5", "g1f3"}}
+	adjudication := Adjudication{MaxPlies: 20}
+	game = PlayGame(black, black, GameOptions{Opening: opening, Adjudication: adjudication})
+	if game.OpeningPlies != 3 || game.Result == "" {
+		t.Fatalf("Unexpected game %+v", game)
+	}
+	openings, err := ParsePGNOpenings(game.PGN("test", 1) + game.PGN("test", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(openings) != 2 || len(openings[1].Moves) != len(game.Moves) {
+		t.Fatalf("Expected two games with %d plies but got %+v", len(game.Moves), openings)
+	}
+	for i, move := range game.Moves {
+		if openings[1].Moves[i] != GetAlgebraicFromMove(&move) {
+			t.Errorf("Expected move %d to be %s but got %s", i, GetAlgebraicFromMove(&move), openings[1].Moves[i])
+		}
+	}
+
+	// a GameEngine gets the start position and all moves played before
+	recorder := &gameRecorder{Engine: black}
+	game = PlayGame(black, recorder, GameOptions{Opening: opening, Adjudication: adjudication})
+	if len(recorder.plies) == 0 || recorder.plies[0] != 3 || recorder.startFens[0] != START_FEN {
+		t.Errorf("Expected the start position and the 3 opening moves but got %v and %v", recorder.startFens, recorder.plies)
+	}
+}
+
+func TestParsePGNOpenings(t *testing.T) {
+	pgn := `[Event "?"]
+[FEN "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1"]
+
+1... e5 {open game} 2. Nf3 (2. f4 exf4 (2... d5)) Nc6 $1 3. Bb5 a6 *
+
+[Event "?"]
+
+1. d4 d5 2. c4 1/2-1/2
+`
+	openings, err := ParsePGNOpenings(pgn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"e7e5", "g1f3", "b8c6", "f1b5", "a7a6"}, {"d2d4", "d7d5", "c2c4"}}
+	if len(openings) != len(expected) {
+		t.Fatalf("Expected %d openings but got %d", len(expected), len(openings))
+	}
+	for i, opening := range openings {
+		if strings.Join(opening.Moves, " ") != strings.Join(expected[i], " ") {
+			t.Errorf("Expected the moves %v but got %v", expected[i], opening.Moves)
+		}
+	}
+	if openings[1].Fen != START_FEN {
+		t.Errorf("Expected the second game to start from the start position but got %s", openings[1].Fen)
+	}
+}
+
+func TestSPRT(t *testing.T) {
+	sprt := SPRT{Elo0: 0, Elo1: 5, Alpha: 0.05, Beta: 0.05}
+	if err := sprt.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	lower, upper := sprt.Bounds()
+	if math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Errorf("Expected the bounds -2.944 and 2.944 but got %.3f and %.3f", lower, upper)
+	}
+	for _, test := range sprtTrinomialTests {
+		if llr := sprt.LLRTrinomial(test.wins, test.draws, test.losses); math.Abs(llr-test.llr) > 0.0001 {
+			t.Errorf("Expected the LLR %.4f for %d-%d-%d but got %.4f", test.llr, test.wins, test.draws,
+				test.losses, llr)
+		}
+	}
+	for _, test := range sprtPentanomialTests {
+		if llr := sprt.LLRPentanomial(test.pairs); math.Abs(llr-test.llr) > 0.0001 {
+			t.Errorf("Expected the LLR %.4f for the pairs %v but got %.4f", test.llr, test.pairs, llr)
+		}
+	}
+	if sprt.Status(3) != SPRT_H1 || sprt.Status(-3) != SPRT_H0 || sprt.Status(0) != SPRT_CONTINUE {
+		t.Errorf("Unexpected status of the SPRT")
+	}
+	// the variance of results without losses and draws is regularized
+	if sprt.Status(sprt.LLRTrinomial(2, 0, 0)) != SPRT_H1 {
+		t.Errorf("Expected a run of only wins to accept H1")
+	}
+	if (SPRT{Elo0: 5, Elo1: 0, Alpha: 0.05, Beta: 0.05}).Validate() == nil {
+		t.Errorf("Expected an error if elo0 is larger than elo1")
+	}
+}
@@ -0,0 +1,178 @@
+package ghess
+
+import "math/bits"
+
+// default king safety weights in centipawns which are only used in the midgame.
+// The shield and storm arrays are indexed by the number of ranks between the king and the pawn.
+var defaultPawnShieldBonus = [3]int{0, 20, 10}
+
+const DEFAULT_MISSING_PAWN_SHIELD_PENALTY = 20
+
+var defaultPawnStormPenalty = [5]int{0, 10, 30, 15, 5}
+
+const DEFAULT_SEMI_OPEN_FILE_NEAR_KING_PENALTY = 15
+const DEFAULT_OPEN_FILE_NEAR_KING_PENALTY = 25
+
+// defaultKingAttackValue is the value of every square in the king zone attacked by a piece of the type (indexed like pieceMap)
+var defaultKingAttackValue = [6]int{0, 20, 20, 40, 80, 0}
+
+// defaultKingAttackWeight scales the attack value in percent by the number of pieces attacking the king zone
+var defaultKingAttackWeight = [8]int{0, 0, 50, 75, 88, 94, 97, 99}
+
+// getKingZone returns the squares around the king and the ones in front of those
+func (board *Board) getKingZone(kingPos int, isBlack bool) uint64 {
+	zoneB := kingAttacksB[kingPos] | 1<<kingPos
+	forward := NORTH
+	if isBlack {
+		forward = SOUTH
+	}
+	if kingPos+forward >= 0 && kingPos+forward < 64 {
+		zoneB |= kingAttacksB[kingPos+forward]
+	}
+	return zoneB
+}
+
+// getPawnShieldScore returns the score of the pawn shield, the pawn storm and the open files on the file of the king
+// and the two adjacent files. If coef is not nil the applied weights are counted.
+func getPawnShieldScore(kingPos int, ownPawnsB, enemyPawnsB uint64, isBlack bool, coef *evalCoefficients) int {
+	score := 0
+	sign := colorSign(isBlack)
+	kingX, _ := xy(kingPos)
+	kingRank := relativeRank(kingPos, isBlack)
+	for x := kingX - 1; x <= kingX+1; x++ {
+		if x < 0 || x > 7 {
+			continue
+		}
+		ownOnFileB := ownPawnsB & fileMasks[x]
+		enemyOnFileB := enemyPawnsB & fileMasks[x]
+		if ownOnFileB == 0 && enemyOnFileB == 0 {
+			score -= evalParams.OpenFileNearKing
+			if coef != nil {
+				coef.mg.OpenFileNearKing -= sign
+			}
+		} else if ownOnFileB == 0 {
+			score -= evalParams.SemiOpenFileNearKing
+			if coef != nil {
+				coef.mg.SemiOpenFileNearKing -= sign
+			}
+		}
+
+		// closest own pawn in front of the king
+		shieldDistance := 8
+		for pawnsB := ownOnFileB; pawnsB != 0; pawnsB &= pawnsB - 1 {
+			d := relativeRank(bits.TrailingZeros64(pawnsB), isBlack) - kingRank
+			if d > 0 && d < shieldDistance {
+				shieldDistance = d
+			}
+		}
+		if shieldDistance < len(evalParams.PawnShield) {
+			score += evalParams.PawnShield[shieldDistance]
+			if coef != nil {
+				coef.mg.PawnShield[shieldDistance] += sign
+			}
+		} else {
+			score -= evalParams.MissingPawnShield
+			if coef != nil {
+				coef.mg.MissingPawnShield -= sign
+			}
+		}
+
+		// closest enemy pawn storming towards the king
+		stormDistance := 8
+		for pawnsB := enemyOnFileB; pawnsB != 0; pawnsB &= pawnsB - 1 {
+			d := relativeRank(bits.TrailingZeros64(pawnsB), isBlack) - kingRank
+			if d > 0 && d < stormDistance {
+				stormDistance = d
+			}
+		}
+		if stormDistance < len(evalParams.PawnStorm) {
+			score -= evalParams.PawnStorm[stormDistance]
+			if coef != nil {
+				coef.mg.PawnStorm[stormDistance] -= sign
+			}
+		}
+	}
+	return score
+}
+
+// getKingAttackScore returns the penalty for enemy knights, bishops, rooks and queens attacking the king zone
+func (board *Board) getKingAttackScore(kingPos int, isBlack bool, coef *evalCoefficients) int {
+	colorIdx := 0
+	if isBlack {
+		colorIdx = 1
+	}
+	zoneB := board.getKingZone(kingPos, isBlack)
+	enemyIds := board.blackIds
+	if isBlack {
+		enemyIds = board.whiteIds
+	}
+	attackers := 0
+	attackValue := 0
+	for _, PieceId := range enemyIds {
+		piece := &board.pieces[PieceId]
+		if piece.posB == 0 || piece.pieceType == PAWN || piece.pieceType == KING {
+			continue
+		}
+		attackedB := board.attacksOf(piece) & zoneB
+		if attackedB != 0 {
+			attackers++
+			idx := pieceIndex(piece.pieceType)
+			attackValue += bits.OnesCount64(attackedB) * evalParams.KingAttackValue[idx]
+			if coef != nil {
+				coef.kingAttacks[colorIdx][idx] += bits.OnesCount64(attackedB)
+			}
+		}
+	}
+	if attackers >= len(evalParams.KingAttackWeight) {
+		attackers = len(evalParams.KingAttackWeight) - 1
+	}
+	if coef != nil {
+		coef.kingAttacker[colorIdx] = attackers
+	}
+	return -attackValue * evalParams.KingAttackWeight[attackers] / 100
+}
+
+// getKingSafetyOfColor returns the midgame king safety score of one color (positive is good for that color).
+// If coef is not nil the applied weights are counted.
+func (board *Board) getKingSafetyOfColor(isBlack bool, whitePawnsB, blackPawnsB uint64, coef *evalCoefficients) int {
+	kingId := board.whiteKingId
+	ownPawnsB, enemyPawnsB := whitePawnsB, blackPawnsB
+	if isBlack {
+		kingId = board.blackKingId
+		ownPawnsB, enemyPawnsB = blackPawnsB, whitePawnsB
+	}
+	if kingId == 0 {
+		return 0
+	}
+	kingPos := board.pieces[kingId].pos
+	canCastleKing, canCastleQueen := board.white_castle_king, board.white_castle_queen
+	if isBlack {
+		canCastleKing, canCastleQueen = board.black_castle_king, board.black_castle_queen
+	}
+	// if the king can still castle the pawn shield after castling counts if it's better
+	shieldPos := kingPos
+	shieldScore := getPawnShieldScore(kingPos, ownPawnsB, enemyPawnsB, isBlack, nil)
+	_, kingY := xy(kingPos)
+	var castledPositions []int
+	if canCastleKing {
+		castledPositions = append(castledPositions, kingY*8+6)
+	}
+	if canCastleQueen {
+		castledPositions = append(castledPositions, kingY*8+2)
+	}
+	for _, castledPos := range castledPositions {
+		if castledScore := getPawnShieldScore(castledPos, ownPawnsB, enemyPawnsB, isBlack, nil); castledScore > shieldScore {
+			shieldPos, shieldScore = castledPos, castledScore
+		}
+	}
+	if coef != nil {
+		getPawnShieldScore(shieldPos, ownPawnsB, enemyPawnsB, isBlack, coef)
+	}
+	return shieldScore + board.getKingAttackScore(kingPos, isBlack, coef)
+}
+
+// getKingSafetyScore returns the midgame king safety score from whites perspective
+func (board *Board) getKingSafetyScore() int {
+	whitePawnsB, blackPawnsB := board.getPawnBitboards()
+	return board.getKingSafetyOfColor(false, whitePawnsB, blackPawnsB, nil) - board.getKingSafetyOfColor(true, whitePawnsB, blackPawnsB, nil)
+}
@@ -0,0 +1,26 @@
+package ghess
+
+type kingSafety struct {
+	saferFen    string
+	lessSafeFen string
+}
+
+// the white king in the first position should be safer than in the second
+var kingSafetyTests = []kingSafety{
+	// pawn shield pushed
+	{"r5k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", "r5k1/5ppp/8/8/8/5PPP/8/R5K1 w - - 0 1"},
+	// open file next to the king
+	{"6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1", "6k1/5ppp/8/8/8/8/5P1P/6K1 w - - 0 1"},
+	// pawn storm
+	{"6k1/8/8/8/8/8/5PPP/6K1 w - - 0 1", "6k1/8/8/8/8/6p1/5PPP/6K1 w - - 0 1"},
+	// knight and queen attacking the king zone
+	{"6k1/5ppp/8/8/8/8/5PPP/6K1 w - - 0 1", "6k1/5ppp/8/8/5n1q/8/5PPP/6K1 w - - 0 1"},
+	// can still castle into safety
+	{"r3k2r/pppppppp/8/8/3PP3/8/PPP2PPP/R3K2R w KQkq - 0 1", "r3k2r/pppppppp/8/8/3PP3/8/PPP2PPP/R3K2R w kq - 0 1"},
+}
+
+// the king safety score of a position must be the negative of the score with colors swapped
+var mirroredKingSafetyTests = []mirroredPawns{
+	{"r5k1/5ppp/8/8/5n1q/8/5PPP/R5K1 w - - 0 1", "r5k1/5ppp/8/5N1Q/8/8/5PPP/R5K1 b - - 0 1"},
+	{"2kr3r/pp3ppp/8/8/2P5/8/PP3PPP/R3K2R w KQ - 0 1", "r3k2r/pp3ppp/8/2p5/8/8/PP3PPP/2KR3R b kq - 0 1"},
+}
@@ -0,0 +1,457 @@
+package ghess
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MATCH_MOVE_OVERHEAD is the time in ms of the clock which isn't given to the engine to cover the communication
+const MATCH_MOVE_OVERHEAD = 20
+
+// MATCH_TIME_MARGIN is the time in ms an engine may exceed its clock before it loses on time
+const MATCH_TIME_MARGIN = 100
+
+// DEFAULT_MOVES_TO_GO is the number of moves the remaining time is split into if the time control has no sessions
+const DEFAULT_MOVES_TO_GO = 30
+
+// Opening is a start position of a match game given by a FEN and moves in long algebraic notation
+type Opening struct {
+	Fen   string
+	Moves []string
+}
+
+// TimeControl limits the search of each move. Time and Increment use a clock per side, MoveTime, Depth and Nodes
+// limit each search on their own. Without any limit the engines search for MAX_ENGINE_TIME.
+type TimeControl struct {
+	Time      time.Duration // time per session
+	Increment time.Duration // time added after each move
+	MovesToGo int           // moves per session after which Time is added again (0 if the session is the whole game)
+	MoveTime  time.Duration
+	Depth     int
+	Nodes     int
+}
+
+// Adjudication ends games early. Zero values disable the rule.
+type Adjudication struct {
+	ResignScore    int  // centipawns a side has to be behind in the scores of both engines to lose
+	ResignMoves    int  // number of consecutive moves of each engine with a resign score
+	DrawScore      int  // centipawns the scores of both engines have to stay within for a draw
+	DrawMoves      int  // number of consecutive moves of each engine with a draw score
+	DrawMoveNumber int  // full move number before which no draw is adjudicated
+	Tablebases     bool // use the result of the Syzygy tablebases once the position is covered
+	MaxPlies       int  // the game is a draw after this many plies (at most MAX_SELF_PLAY_PLIES)
+}
+
+// GameOptions configure PlayGame
+type GameOptions struct {
+	Opening      Opening
+	TimeControl  TimeControl
+	Adjudication Adjudication
+}
+
+// Game is a finished match game
+type Game struct {
+	White, Black string
+	Fen          string // start position of the opening
+	Moves        []Move // moves of the opening followed by the moves of the engines
+	OpeningPlies int
+	Result       string // 1-0, 0-1 or 1/2-1/2
+	Termination  string // reason why the game ended
+	Date         time.Time
+}
+
+// WhiteScore returns 1, 0.5 or 0 for a win, draw or loss of white
+func (game *Game) WhiteScore() float64 {
+	switch game.Result {
+	case "1-0":
+		return 1
+	case "0-1":
+		return 0
+	}
+	return 0.5
+}
+
+// ReadOpenings reads the start positions of an EPD file (one position per line) or the games of a PGN file which
+// end after their last move. The format is chosen by the file extension.
+func ReadOpenings(path string) ([]Opening, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	if strings.ToLower(filepath.Ext(path)) != ".pgn" {
+		openings := []Opening{}
+		for scanner.Scan() {
+			fields := strings.Fields(strings.SplitN(scanner.Text(), ";", 2)[0])
+			if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			// the EPD operations after the first four fields replace the move counters
+			fen := strings.Join(fields[:4], " ") + " 0 1"
+			if len(fields) >= 6 && isNumber(fields[4]) && isNumber(fields[5]) {
+				fen = strings.Join(fields[:6], " ")
+			}
+			openings = append(openings, Opening{Fen: fen})
+		}
+		return openings, scanner.Err()
+	}
+
+	var pgn strings.Builder
+	for scanner.Scan() {
+		pgn.WriteString(scanner.Text() + "\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParsePGNOpenings(pgn.String())
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+var pgnTagRegexp = regexp.MustCompile(`^\[(\w+)\s+"(.*)"\]$`)
+var pgnCommentRegexp = regexp.MustCompile(`\{[^}]*\}|;[^\n]*`)
+var pgnMoveNumberRegexp = regexp.MustCompile(`^\d+\.+`)
+var pgnVariationRegexp = regexp.MustCompile(`\([^()]*\)`)
+
+// ParsePGNOpenings returns the start position and the moves of every game in pgn. Comments, variations and
+// annotations are skipped.
+func ParsePGNOpenings(pgn string) ([]Opening, error) {
+	openings := []Opening{}
+	fen := START_FEN
+	movetext := ""
+	inMovetext := false
+	finishGame := func() error {
+		if !inMovetext {
+			return nil
+		}
+		opening, err := parsePGNMovetext(fen, movetext)
+		if err != nil {
+			return fmt.Errorf("game %d: %w", len(openings)+1, err)
+		}
+		openings = append(openings, opening)
+		fen = START_FEN
+		movetext = ""
+		inMovetext = false
+		return nil
+	}
+	for _, line := range strings.Split(pgn, "\n") {
+		line = strings.TrimSpace(line)
+		if match := pgnTagRegexp.FindStringSubmatch(line); match != nil {
+			if err := finishGame(); err != nil {
+				return nil, err
+			}
+			if match[1] == "FEN" {
+				fen = match[2]
+			}
+			continue
+		}
+		if line != "" {
+			inMovetext = true
+			movetext += line + "\n"
+		}
+	}
+	if err := finishGame(); err != nil {
+		return nil, err
+	}
+	return openings, nil
+}
+
+// parsePGNMovetext converts the moves in standard algebraic notation into long algebraic notation
+func parsePGNMovetext(fen string, movetext string) (Opening, error) {
+	movetext = pgnCommentRegexp.ReplaceAllString(movetext, " ")
+	// variations may be nested
+	for strings.Contains(movetext, "(") {
+		before := movetext
+		movetext = pgnVariationRegexp.ReplaceAllString(movetext, " ")
+		if movetext == before {
+			return Opening{}, fmt.Errorf("unbalanced variation")
+		}
+	}
+	opening := Opening{Fen: fen}
+	board := GetBoardFromFen(fen)
+	for _, token := range strings.Fields(movetext) {
+		token = pgnMoveNumberRegexp.ReplaceAllString(token, "")
+		if token == "" || strings.HasPrefix(token, "$") || token == "*" || token == "1-0" || token == "0-1" || token == "1/2-1/2" {
+			continue
+		}
+		move, err := board.GetMoveFromStandardAlgebraic(token)
+		if err != nil {
+			return Opening{}, err
+		}
+		opening.Moves = append(opening.Moves, GetAlgebraicFromMove(&move))
+		board.Move(&move)
+	}
+	return opening, nil
+}
+
+// limits returns the limits of the next search of the side with the given clock which made movesMade moves
+func (tc TimeControl) limits(clock time.Duration, movesMade int) SearchLimits {
+	limits := SearchLimits{MoveTime: int(tc.MoveTime.Milliseconds()), Depth: tc.Depth, Nodes: tc.Nodes}
+	if tc.Time > 0 {
+		movesLeft := DEFAULT_MOVES_TO_GO
+		if tc.MovesToGo > 0 {
+			movesLeft = tc.MovesToGo - movesMade%tc.MovesToGo
+		}
+		clockMs := int(clock.Milliseconds())
+		moveTime := min(clockMs/movesLeft+int(tc.Increment.Milliseconds()), clockMs-MATCH_MOVE_OVERHEAD)
+		limits.MoveTime = max(1, moveTime)
+	}
+	return limits
+}
+
+// PlayGame plays a game from the opening between the engines and returns it once it ended or was adjudicated
+func PlayGame(white, black Engine, options GameOptions) Game {
+	game := Game{White: white.Name(), Black: black.Name(), Fen: options.Opening.Fen, Date: time.Now()}
+	if game.Fen == "" {
+		game.Fen = START_FEN
+	}
+	board := GetBoardFromFen(game.Fen)
+	for _, moveStr := range options.Opening.Moves {
+		move, err := board.GetMoveFromLongAlgebraic(moveStr)
+		if err != nil {
+			game.Result, game.Termination = "*", fmt.Sprintf("illegal opening move %s", moveStr)
+			return game
+		}
+		board.Move(&move)
+		game.Moves = append(game.Moves, move)
+	}
+	game.OpeningPlies = len(game.Moves)
+	white.NewGame()
+	black.NewGame()
+
+	tc := options.TimeControl
+	adjudication := options.Adjudication
+	maxPlies := adjudication.MaxPlies
+	if maxPlies <= 0 || maxPlies > MAX_SELF_PLAY_PLIES {
+		maxPlies = MAX_SELF_PLAY_PLIES
+	}
+	engines := [2]Engine{white, black}
+	clocks := [2]time.Duration{tc.Time, tc.Time}
+	movesMade := [2]int{}
+	// scores from whites perspective of the last moves of each engine for the score adjudication
+	scores := [2][]float64{}
+
+	for {
+		if ended, result, termination := board.gameResult(adjudication.Tablebases); ended {
+			game.Result, game.Termination = result, termination
+			return game
+		}
+		if len(game.Moves) >= maxPlies {
+			game.Result, game.Termination = "1/2-1/2", "adjudication: maximum number of plies"
+			return game
+		}
+		side := 0
+		if board.IsBlacksTurn {
+			side = 1
+		}
+		limits := tc.limits(clocks[side], movesMade[side])
+		searchBoard := board
+		startTime := time.Now()
+		var result SearchResult
+		if engine, ok := engines[side].(GameEngine); ok {
+			result = engine.SearchGame(&searchBoard, game.Fen, game.Moves, limits)
+		} else {
+			result = engines[side].Search(&searchBoard, limits)
+		}
+		elapsed := time.Since(startTime)
+		loss := "1-0"
+		if side == 0 {
+			loss = "0-1"
+		}
+		if result.Move.PieceId == 0 || !board.isLegal(&result.Move) {
+			game.Result, game.Termination = loss, fmt.Sprintf("%s made no legal move", engines[side].Name())
+			return game
+		}
+		if tc.Time > 0 {
+			clocks[side] -= elapsed
+			if clocks[side] < -MATCH_TIME_MARGIN*time.Millisecond {
+				game.Result, game.Termination = loss, "time forfeit"
+				return game
+			}
+			movesMade[side]++
+			clocks[side] += tc.Increment
+			if tc.MovesToGo > 0 && movesMade[side]%tc.MovesToGo == 0 {
+				clocks[side] += tc.Time
+			}
+		}
+		move := result.Move
+		board.Move(&move)
+		game.Moves = append(game.Moves, move)
+
+		// moves without a search have no score and reset the score adjudication
+		if result.Depth == 0 && result.Nodes == 0 {
+			scores = [2][]float64{}
+			continue
+		}
+		scores[side] = append(scores[side], result.Score)
+		if result, ok := adjudication.byScore(scores, board.nextMove); ok {
+			game.Result, game.Termination = result, "adjudication"
+			return game
+		}
+	}
+}
+
+// gameResult returns whether the game ended and the result and reason in that case
+func (board *Board) gameResult(useTablebases bool) (bool, string, string) {
+	ended, endType, _ := board.CheckGameEnded()
+	if ended {
+		switch {
+		case endType == "checkmate" && board.IsBlacksTurn:
+			return true, "1-0", "checkmate"
+		case endType == "checkmate":
+			return true, "0-1", "checkmate"
+		case board.GetNumberOfMoves(1) == 0:
+			return true, "1/2-1/2", "stalemate"
+		}
+		return true, "1/2-1/2", "draw"
+	}
+	if !useTablebases {
+		return false, "", ""
+	}
+	wdl, ok := board.ProbeWDL()
+	if !ok {
+		return false, "", ""
+	}
+	if wdl != TB_WIN && wdl != TB_LOSS {
+		return true, "1/2-1/2", "adjudication: tablebase draw"
+	}
+	if (wdl == TB_WIN) == board.IsBlacksTurn {
+		return true, "0-1", "adjudication: tablebase win"
+	}
+	return true, "1-0", "adjudication: tablebase win"
+}
+
+// lastScores returns the last n scores of the engine or nil if it made fewer moves with a score
+func lastScores(scores []float64, n int) []float64 {
+	if len(scores) < n {
+		return nil
+	}
+	return scores[len(scores)-n:]
+}
+
+// byScore returns the result if the last scores of both engines agree on a decided game or a draw
+func (adjudication Adjudication) byScore(scores [2][]float64, nextMove int) (string, bool) {
+	if adjudication.ResignMoves > 0 {
+		white, black := lastScores(scores[0], adjudication.ResignMoves), lastScores(scores[1], adjudication.ResignMoves)
+		if white != nil && black != nil {
+			all := append(append([]float64{}, white...), black...)
+			whiteWins, blackWins := true, true
+			for _, score := range all {
+				whiteWins = whiteWins && score >= float64(adjudication.ResignScore)
+				blackWins = blackWins && score <= -float64(adjudication.ResignScore)
+			}
+			if whiteWins {
+				return "1-0", true
+			}
+			if blackWins {
+				return "0-1", true
+			}
+		}
+	}
+	if adjudication.DrawMoves > 0 && nextMove > adjudication.DrawMoveNumber {
+		white, black := lastScores(scores[0], adjudication.DrawMoves), lastScores(scores[1], adjudication.DrawMoves)
+		if white != nil && black != nil {
+			for _, score := range append(append([]float64{}, white...), black...) {
+				if math.Abs(score) > float64(adjudication.DrawScore) {
+					return "", false
+				}
+			}
+			return "1/2-1/2", true
+		}
+	}
+	return "", false
+}
+
+// PGN returns the game in the portable game notation with the moves in standard algebraic notation
+func (game *Game) PGN(event string, round int) string {
+	var sb strings.Builder
+	tags := [][2]string{
+		{"Event", event},
+		{"Site", "?"},
+		{"Date", game.Date.Format("2006.01.02")},
+		{"Round", strconv.Itoa(round)},
+		{"White", game.White},
+		{"Black", game.Black},
+		{"Result", game.Result},
+	}
+	if game.Fen != START_FEN {
+		tags = append(tags, [2]string{"FEN", game.Fen}, [2]string{"SetUp", "1"})
+	}
+	tags = append(tags, [2]string{"PlyCount", strconv.Itoa(len(game.Moves))}, [2]string{"Termination", game.Termination})
+	for _, tag := range tags {
+		sb.WriteString(fmt.Sprintf("[%s \"%s\"]\n", tag[0], strings.ReplaceAll(tag[1], `"`, `\"`)))
+	}
+	sb.WriteString("\n")
+
+	board := GetBoardFromFen(game.Fen)
+	tokens := []string{}
+	for i := range game.Moves {
+		if !board.IsBlacksTurn {
+			tokens = append(tokens, strconv.Itoa(board.nextMove)+".")
+		} else if i == 0 {
+			tokens = append(tokens, strconv.Itoa(board.nextMove)+"...")
+		}
+		san := board.getStandardAlgebraicFromMove(&game.Moves[i])
+		board.Move(&game.Moves[i])
+		if board.check {
+			if board.GetNumberOfMoves(1) == 0 {
+				san += "#"
+			} else {
+				san += "+"
+			}
+		}
+		tokens = append(tokens, san)
+		if i == game.OpeningPlies-1 && i != len(game.Moves)-1 {
+			tokens = append(tokens, "{end of opening}")
+		}
+	}
+	tokens = append(tokens, "{"+game.Termination+"}", game.Result)
+	// lines of at most 80 characters
+	line := ""
+	for _, token := range tokens {
+		if line != "" && len(line)+1+len(token) > 80 {
+			sb.WriteString(line + "\n")
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += token
+	}
+	sb.WriteString(line + "\n\n")
+	return sb.String()
+}
+
+// EloDifference returns the Elo difference of a player with the given results and the half width of its 95%
+// confidence interval. If one player scored all points the difference and the interval are infinite.
+func EloDifference(wins, draws, losses int) (float64, float64) {
+	n := float64(wins + draws + losses)
+	if n == 0 {
+		return 0, 0
+	}
+	score := (float64(wins) + 0.5*float64(draws)) / n
+	variance := (float64(wins)*math.Pow(1-score, 2) + float64(draws)*math.Pow(0.5-score, 2) +
+		float64(losses)*math.Pow(score, 2)) / n
+	if score == 0 || score == 1 {
+		return eloFromScore(score), math.Inf(1)
+	}
+	margin := 1.959964 * math.Sqrt(variance/n)
+	return eloFromScore(score), (eloFromScore(score+margin) - eloFromScore(score-margin)) / 2
+}
+
+// eloFromScore returns the Elo difference which leads to the expected score
+func eloFromScore(score float64) float64 {
+	score = math.Max(0, math.Min(1, score))
+	return 400 * math.Log10(score/(1-score))
+}
@@ -0,0 +1,27 @@
+package ghess
+
+var eloDifferenceTests = []struct {
+	wins, draws, losses int
+	elo                 float64
+}{
+	{0, 10, 0, 0},
+	{10, 0, 10, 0},
+	{3, 0, 1, 190.85},
+	{1, 0, 3, -190.85},
+	{60, 20, 20, 147.19},
+}
+
+var adjudicationTests = []struct {
+	scores   [2][]float64
+	nextMove int
+	result   string
+}{
+	{[2][]float64{{500, 600, 700}, {450, 800, 900}}, 20, "1-0"},
+	{[2][]float64{{-500, -600}, {-450, -800}}, 20, "0-1"},
+	{[2][]float64{{100, 600, 700}, {450, 800, 900}}, 20, "1-0"},
+	{[2][]float64{{500, 200, 700}, {450, 800, 900}}, 20, ""},
+	{[2][]float64{{500, 600, 700}, {800}}, 20, ""},
+	{[2][]float64{{5, -3, 0, 2}, {1, 0, 4, -2}}, 41, "1/2-1/2"},
+	{[2][]float64{{5, -3, 0, 2}, {1, 0, 4, -2}}, 40, ""},
+	{[2][]float64{{5, -3, 50, 2}, {1, 0, 4, -2}}, 41, ""},
+}
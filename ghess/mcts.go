@@ -0,0 +1,350 @@
+package ghess
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// MCTS_EXPLORATION is the default exploration constant of the PUCT formula
+const MCTS_EXPLORATION = 1.5
+
+// MCTS_VALUE_SCALE is the evaluation in centipawns which corresponds to a value of 1/3 (an expected score of 2/3)
+const MCTS_VALUE_SCALE = 400.0
+
+// MCTS_PRIOR_TEMPERATURE is the temperature in centipawns of the softmax over the move ordering scores used as priors
+const MCTS_PRIOR_TEMPERATURE = 200.0
+
+// MCTS_VIRTUAL_LOSS is the number of lost playouts a node counts as while a playout through it is evaluated
+const MCTS_VIRTUAL_LOSS = 3
+
+// MCTS_MAX_PLY is the maximum ply of a playout such that it stays within the position history of the board
+const MCTS_MAX_PLY = 480
+
+// mctsNode is a position in the search tree reached by move. The value is from the perspective of the player
+// who made the move.
+type mctsNode struct {
+	move          Move
+	prior         float64
+	children      []*mctsNode
+	expanded      bool
+	terminal      bool    // checkmate or draw which is never expanded
+	terminalValue float64 // value of a terminal node from the perspective of the side to move
+	visits        int
+	valueSum      float64
+	virtualLoss   int
+}
+
+// q returns the mean value of the node where playouts in progress count as losses
+func (node *mctsNode) q() float64 {
+	n := node.visits + node.virtualLoss
+	if n == 0 {
+		return 0
+	}
+	return (node.valueSum - float64(node.virtualLoss)) / float64(n)
+}
+
+// MCTSEngine is a Monte Carlo tree search which selects moves by the PUCT formula and estimates the value of
+// leaves by the static evaluation or a quiescence search instead of random playouts.
+// The tree is reused in the next search if the new position is a successor of the previous one.
+type MCTSEngine struct {
+	Exploration   float64 // exploration constant of the PUCT formula
+	Threads       int     // number of goroutines which run playouts in parallel
+	UseQuiescence bool    // estimate leaves by a quiescence search instead of the static evaluation
+	Verbose       bool    // print the search statistics
+
+	mu        sync.Mutex
+	root      *mctsNode
+	rootBoard Board
+}
+
+// NewMCTSEngine returns an engine with a single thread which uses the quiescence search for leaves
+func NewMCTSEngine() *MCTSEngine {
+	return &MCTSEngine{Exploration: MCTS_EXPLORATION, Threads: 1, UseQuiescence: true}
+}
+
+func (engine *MCTSEngine) Name() string {
+	return "mcts"
+}
+
+func (engine *MCTSEngine) NewGame() {
+	engine.root = nil
+}
+
+// valueFromScore converts a score in centipawns from whites perspective into a value between -1 and 1
+// from the perspective of the side to move
+func valueFromScore(score float64, isBlacksTurn bool) float64 {
+	if isBlacksTurn {
+		score = -score
+	}
+	return 2/(1+math.Pow(10, -score/MCTS_VALUE_SCALE)) - 1
+}
+
+// scoreFromValue is the inverse of valueFromScore
+func scoreFromValue(value float64, isBlacksTurn bool) float64 {
+	value = math.Max(-0.9999, math.Min(0.9999, value))
+	score := -MCTS_VALUE_SCALE * math.Log10(2/(value+1)-1)
+	if isBlacksTurn {
+		return -score
+	}
+	return score
+}
+
+// reuseTree returns the node of the previous tree which belongs to the position of board if it is at most two plies
+// after the previous root and nil otherwise
+func (engine *MCTSEngine) reuseTree(board *Board) *mctsNode {
+	if engine.root == nil {
+		return nil
+	}
+	key := board.PolyglotKey()
+	previous := engine.rootBoard
+	if previous.PolyglotKey() == key {
+		return engine.root
+	}
+	for _, child := range engine.root.children {
+		boardPrimitives := previous.getBoardPrimitives()
+		previous.Move(&child.move)
+		if previous.PolyglotKey() == key && child.expanded {
+			return child
+		}
+		for _, grandchild := range child.children {
+			grandchildPrimitives := previous.getBoardPrimitives()
+			previous.Move(&grandchild.move)
+			found := previous.PolyglotKey() == key && grandchild.expanded
+			previous.reverseMove(&grandchild.move, &grandchildPrimitives)
+			if found {
+				return grandchild
+			}
+		}
+		previous.reverseMove(&child.move, &boardPrimitives)
+	}
+	return nil
+}
+
+// Search runs playouts until the time, node (number of playouts) or depth limit is reached and returns the most
+// visited move. The depth is reached as soon as the line of the most visited moves is that long or ends the game.
+func (engine *MCTSEngine) Search(board *Board, limits SearchLimits) SearchResult {
+	startTime := time.Now()
+	maxTime := time.Duration(limits.moveTime()) * time.Millisecond
+	root := engine.reuseTree(board)
+	if root == nil {
+		root = &mctsNode{}
+	}
+	reused := root.visits
+	engine.root = root
+	engine.rootBoard = board.Copy()
+
+	threads := max(1, engine.Threads)
+	playouts := 0
+	maxPlyReached := 0
+	lastReport := startTime
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func(reporter bool) {
+			defer wg.Done()
+			// every goroutine has its own board with its own pawn hash table
+			local := board.Copy()
+			for {
+				engine.mu.Lock()
+				if (limits.Nodes > 0 && playouts >= limits.Nodes) || (playouts > 0 && (time.Since(startTime) >= maxTime || board.stopped() ||
+					(limits.Depth > 0 && engine.depthReached(root, board.ply, limits.Depth)))) {
+					engine.mu.Unlock()
+					return
+				}
+				playouts++
+				// the first goroutine reports the current best line every second
+				if reporter && board.infoHandler != nil && time.Since(lastReport) >= time.Second {
+					lastReport = time.Now()
+					if line := engine.bestLine(root, board.IsBlacksTurn); len(line.Pv) > 0 {
+						board.infoHandler(board.lineInfo(1, line.Score, line.Pv, len(line.Pv), maxPlyReached, playouts, startTime))
+					}
+				}
+				engine.mu.Unlock()
+				plies := engine.playout(&local, root)
+				engine.mu.Lock()
+				maxPlyReached = max(maxPlyReached, plies)
+				engine.mu.Unlock()
+			}
+		}(i == 0)
+	}
+	wg.Wait()
+
+	result := engine.bestLine(root, board.IsBlacksTurn)
+	result.Nodes = playouts
+	result.Depth = maxPlyReached
+	if result.Move.PieceId == 0 && len(root.children) > 0 {
+		result.Move = root.children[0].move
+	}
+	if board.infoHandler != nil && len(result.Pv) > 0 {
+		board.infoHandler(board.lineInfo(1, result.Score, result.Pv, len(result.Pv), maxPlyReached, playouts, startTime))
+	}
+	if engine.Verbose {
+		fmt.Printf("%d playouts (%d reused) in %.02f sec. max depth %d\n", playouts, reused, time.Since(startTime).Seconds(), maxPlyReached)
+		printPv(pvArray(result.Pv))
+		fmt.Println("score from whites perspective: ", result.Score)
+	}
+	return result
+}
+
+// mostVisitedChild returns the child of node with the most visits or nil if no child was visited yet
+func mostVisitedChild(node *mctsNode) *mctsNode {
+	if len(node.children) == 0 {
+		return nil
+	}
+	best := node.children[0]
+	for _, child := range node.children[1:] {
+		if child.visits > best.visits {
+			best = child
+		}
+	}
+	if best.visits == 0 {
+		return nil
+	}
+	return best
+}
+
+// depthReached returns whether the line of the most visited children starting at root is depth plies long or ends in
+// a terminal node or at the maximum ply. ply is the ply of the root position.
+func (engine *MCTSEngine) depthReached(root *mctsNode, ply, depth int) bool {
+	node := root
+	for plies := 0; plies < depth; plies++ {
+		if node.terminal || ply+plies >= MCTS_MAX_PLY {
+			return true
+		}
+		if node = mostVisitedChild(node); node == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// bestLine returns the line of the most visited children starting at root with the score of its first move
+func (engine *MCTSEngine) bestLine(root *mctsNode, isBlacksTurn bool) SearchResult {
+	result := SearchResult{}
+	node := root
+	for {
+		best := mostVisitedChild(node)
+		if best == nil {
+			break
+		}
+		if node == root {
+			result.Move = best.move
+			result.Score = scoreFromValue(best.q(), isBlacksTurn)
+		}
+		result.Pv = append(result.Pv, best.move)
+		node = best
+	}
+	return result
+}
+
+// pvArray converts a pv into the fixed size array used by the alpha beta search
+func pvArray(pv []Move) [30]Move {
+	var pvArr [30]Move
+	copy(pvArr[:], pv)
+	return pvArr
+}
+
+// playout walks from root to a leaf by the PUCT formula, expands the leaf and updates the values of all nodes on the
+// path. board is in the root position before and after the playout. Returns the number of plies of the path.
+func (engine *MCTSEngine) playout(board *Board, root *mctsNode) int {
+	path := []*mctsNode{root}
+	primitives := []BoardPrimitives{}
+
+	engine.mu.Lock()
+	node := root
+	for node.expanded && !node.terminal && board.ply+len(path)-1 < MCTS_MAX_PLY {
+		node = engine.selectChild(node)
+		node.virtualLoss += MCTS_VIRTUAL_LOSS
+		path = append(path, node)
+	}
+	terminal := node.terminal
+	value := node.terminalValue
+	expand := !node.expanded && board.ply+len(path)-1 < MCTS_MAX_PLY
+	engine.mu.Unlock()
+
+	// the moves are made and the leaf is evaluated without holding the lock such that other goroutines can
+	// select their paths in the meantime
+	for _, pathNode := range path[1:] {
+		primitives = append(primitives, board.getBoardPrimitives())
+		board.Move(&pathNode.move)
+	}
+	var children []*mctsNode
+	if !terminal {
+		value, terminal, children = engine.evaluateLeaf(board, expand)
+	}
+
+	engine.mu.Lock()
+	if terminal {
+		node.terminal = true
+		node.terminalValue = value
+	} else if expand && !node.expanded {
+		node.children = children
+		node.expanded = true
+	}
+	// value is from the perspective of the side to move in the leaf which is the opponent of the player who made the move
+	for i := len(path) - 1; i >= 0; i-- {
+		value = -value
+		path[i].visits++
+		path[i].valueSum += value
+		if i > 0 {
+			path[i].virtualLoss -= MCTS_VIRTUAL_LOSS
+		}
+	}
+	engine.mu.Unlock()
+
+	for i := len(path) - 1; i > 0; i-- {
+		board.reverseMove(&path[i].move, &primitives[i-1])
+	}
+	return len(path) - 1
+}
+
+// selectChild returns the child with the highest PUCT score
+func (engine *MCTSEngine) selectChild(node *mctsNode) *mctsNode {
+	sqrtVisits := math.Sqrt(float64(node.visits + node.virtualLoss))
+	var best *mctsNode
+	bestScore := math.Inf(-1)
+	for _, child := range node.children {
+		u := engine.Exploration * child.prior * sqrtVisits / float64(1+child.visits+child.virtualLoss)
+		if score := child.q() + u; score > bestScore {
+			bestScore = score
+			best = child
+		}
+	}
+	return best
+}
+
+// evaluateLeaf returns the value of the position of board from the perspective of the side to move, whether the game
+// ended and if expand is set the children of the leaf with their priors
+func (engine *MCTSEngine) evaluateLeaf(board *Board, expand bool) (float64, bool, []*mctsNode) {
+	if gameEnded, endType, _ := board.CheckGameEnded(); gameEnded {
+		if endType == "checkmate" {
+			return -1, true, nil
+		}
+		return 0, true, nil
+	}
+	var score float64
+	if engine.UseQuiescence {
+		score = board.quiesce(math.Inf(-1), math.Inf(1), !board.IsBlacksTurn, 0, 0)
+	} else {
+		score = board.staticEvaluation()
+	}
+	value := valueFromScore(score, board.IsBlacksTurn)
+	if !expand {
+		return value, false, nil
+	}
+
+	// priors are a softmax over the capture gains of the move ordering
+	orderedMoves := board.getPossibleMovesOrdered(false, [30]Move{}, 0)
+	children := make([]*mctsNode, len(orderedMoves))
+	sum := 0.0
+	for i, om := range orderedMoves {
+		children[i] = &mctsNode{move: om.move, prior: math.Exp(float64(om.score) / MCTS_PRIOR_TEMPERATURE)}
+		sum += children[i].prior
+	}
+	for _, child := range children {
+		child.prior /= sum
+	}
+	return value, false, children
+}
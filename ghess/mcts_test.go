@@ -0,0 +1,13 @@
+package ghess
+
+// mctsStruct is a position with a clearly best move which the tree search has to find
+type mctsStruct struct {
+	fen      string
+	expected string
+}
+
+var mctsTests = []mctsStruct{
+	{"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"},
+	{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8"},
+	{"r5k1/8/8/8/8/8/5PPP/6K1 b - - 0 1", "a8a1"},
+}
@@ -0,0 +1,239 @@
+package ghess
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+)
+
+// NNUE_BUCKETS is the number of king buckets. The position of the own king selects the weights of a perspective.
+const NNUE_BUCKETS = 4
+
+// NNUE_FEATURES is the number of inputs of one perspective: every non king piece of both colors on every square for every king bucket
+const NNUE_FEATURES = NNUE_BUCKETS * 10 * 64
+
+// NNUE_HIDDEN is the size of the accumulator of one perspective
+const NNUE_HIDDEN = 64
+
+// The accumulator is clipped to [0, NNUE_QA] and the output weights are scaled by NNUE_QB.
+// The output is scaled by NNUE_SCALE / (NNUE_QA * NNUE_QB) to get centipawns.
+const NNUE_QA = 255
+const NNUE_QB = 64
+const NNUE_SCALE = 400
+
+const nnueMagic = "GHNN"
+const nnueVersion = 1
+
+// Network is a neural network with a hidden layer for the side to move and one for the other side which share the weights.
+// Each perspective sees the pieces relative to its own king and color which makes the first layer cheap to update incrementally.
+type Network struct {
+	FeatureWeights [NNUE_FEATURES][NNUE_HIDDEN]int16
+	FeatureBiases  [NNUE_HIDDEN]int16
+	OutputWeights  [2 * NNUE_HIDDEN]int16 // side to move first
+	OutputBias     int32                  // scaled by NNUE_QA * NNUE_QB
+}
+
+// nnueNetwork is the network used by the static evaluation or nil for the handcrafted evaluation
+var nnueNetwork *Network
+
+// SetNetwork sets the network used by the static evaluation. nil switches back to the handcrafted evaluation.
+// Boards created before need to call RefreshEvaluation.
+func SetNetwork(network *Network) {
+	nnueNetwork = network
+}
+
+// GetNetwork returns the network used by the static evaluation or nil if the handcrafted evaluation is used
+func GetNetwork() *Network {
+	return nnueNetwork
+}
+
+// NewRandomNetwork returns a network with small random weights which is a starting point for training
+func NewRandomNetwork(seed int64) *Network {
+	r := rand.New(rand.NewSource(seed))
+	network := &Network{}
+	for i := range network.FeatureWeights {
+		for j := range network.FeatureWeights[i] {
+			network.FeatureWeights[i][j] = int16(r.Intn(65) - 32)
+		}
+	}
+	for i := range network.FeatureBiases {
+		network.FeatureBiases[i] = int16(r.Intn(128))
+	}
+	for i := range network.OutputWeights {
+		network.OutputWeights[i] = int16(r.Intn(129) - 64)
+	}
+	network.OutputBias = int32(r.Intn(2*NNUE_QA*NNUE_QB) - NNUE_QA*NNUE_QB)
+	return network
+}
+
+// ReadNetwork reads a network written by WriteFile
+func ReadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	network, err := readNetwork(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return network, nil
+}
+
+// readNetwork reads the header which describes the architecture followed by the little endian weights
+func readNetwork(r io.Reader) (*Network, error) {
+	magic := make([]byte, len(nnueMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != nnueMagic {
+		return nil, errors.New("not a network file")
+	}
+	var header [3]uint32
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header != [3]uint32{nnueVersion, NNUE_BUCKETS, NNUE_HIDDEN} {
+		return nil, fmt.Errorf("unsupported version %d with %d king buckets and %d hidden neurons", header[0], header[1], header[2])
+	}
+	network := &Network{}
+	if err := binary.Read(r, binary.LittleEndian, network); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// WriteFile writes the network in the format read by ReadNetwork
+func (network *Network) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	w.WriteString(nnueMagic)
+	binary.Write(w, binary.LittleEndian, [3]uint32{nnueVersion, NNUE_BUCKETS, NNUE_HIDDEN})
+	binary.Write(w, binary.LittleEndian, network)
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// nnueKingBucket returns the king bucket of a perspective (0 white, 1 black) depending on whether the own king is
+// on the king or queen side and whether it left the first two ranks
+func nnueKingBucket(kingPos int, perspective int) int {
+	x, _ := xy(kingPos)
+	bucket := 0
+	if x >= 4 {
+		bucket++
+	}
+	if relativeRank(kingPos, perspective == 1) >= 2 {
+		bucket += 2
+	}
+	return bucket
+}
+
+// nnueFeature returns the input index of a non king piece from the view of a perspective.
+// The board is flipped for black such that both perspectives see their own pieces from the first rank.
+func nnueFeature(perspective int, bucket int, pieceType rune, isBlack bool, pos int) int {
+	relColor := 0
+	if isBlack != (perspective == 1) {
+		relColor = 1
+	}
+	if perspective == 1 {
+		pos ^= 56
+	}
+	return bucket*10*64 + (relColor*5+pieceIndex(pieceType))*64 + pos
+}
+
+// updateAccumulators adds (sign 1) or removes (sign -1) a piece from the accumulators.
+// A king move into another bucket marks the accumulator of its color for a full refresh.
+func (board *Board) updateAccumulators(pieceType rune, isBlack bool, pos int, sign int) {
+	if pieceType == KING {
+		perspective := 0
+		if isBlack {
+			perspective = 1
+		}
+		if sign > 0 && nnueKingBucket(pos, perspective) != board.accumulatorBucket[perspective] {
+			board.accumulatorDirty[perspective] = true
+		}
+		return
+	}
+	for perspective := 0; perspective < 2; perspective++ {
+		if board.accumulatorDirty[perspective] {
+			continue
+		}
+		weights := &nnueNetwork.FeatureWeights[nnueFeature(perspective, board.accumulatorBucket[perspective], pieceType, isBlack, pos)]
+		acc := &board.accumulator[perspective]
+		if sign > 0 {
+			for i := range acc {
+				acc[i] += weights[i]
+			}
+		} else {
+			for i := range acc {
+				acc[i] -= weights[i]
+			}
+		}
+	}
+}
+
+// refreshAccumulator computes the accumulator of a perspective from scratch
+func (board *Board) refreshAccumulator(perspective int) {
+	kingId := board.whiteKingId
+	if perspective == 1 {
+		kingId = board.blackKingId
+	}
+	bucket := 0
+	if kingId != 0 {
+		bucket = nnueKingBucket(board.pieces[kingId].pos, perspective)
+	}
+	acc := &board.accumulator[perspective]
+	*acc = nnueNetwork.FeatureBiases
+	for _, piece := range board.pieces {
+		if piece.posB == 0 || piece.pieceType == KING {
+			continue
+		}
+		weights := &nnueNetwork.FeatureWeights[nnueFeature(perspective, bucket, piece.pieceType, piece.isBlack, piece.pos)]
+		for i := range acc {
+			acc[i] += weights[i]
+		}
+	}
+	board.accumulatorBucket[perspective] = bucket
+	board.accumulatorDirty[perspective] = false
+}
+
+// clippedReLU clips an accumulator value to [0, NNUE_QA]
+func clippedReLU(x int16) int {
+	if x < 0 {
+		return 0
+	}
+	if x > NNUE_QA {
+		return NNUE_QA
+	}
+	return int(x)
+}
+
+// nnueEvaluation returns the evaluation of the network in centipawns from whites perspective
+func (board *Board) nnueEvaluation() float64 {
+	for perspective := 0; perspective < 2; perspective++ {
+		if board.accumulatorDirty[perspective] {
+			board.refreshAccumulator(perspective)
+		}
+	}
+	us, them := 0, 1
+	if board.IsBlacksTurn {
+		us, them = 1, 0
+	}
+	sum := int(nnueNetwork.OutputBias)
+	for i := 0; i < NNUE_HIDDEN; i++ {
+		sum += clippedReLU(board.accumulator[us][i]) * int(nnueNetwork.OutputWeights[i])
+		sum += clippedReLU(board.accumulator[them][i]) * int(nnueNetwork.OutputWeights[NNUE_HIDDEN+i])
+	}
+	score := float64(sum*NNUE_SCALE) / (NNUE_QA * NNUE_QB)
+	if board.IsBlacksTurn {
+		return -score
+	}
+	return score
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 	"unicode"
 )
@@ -42,6 +43,11 @@ func (board *Board) GetMoveFromLongAlgebraic(moveStr string) (Move, error) {
 	if len(moveStr) != 4 && len(moveStr) != 5 {
 		return move, fmt.Errorf("currently only algebraic notation with 4 or 5 chars (with promotion) is supported")
 	}
+	for i := 0; i < 4; i += 2 {
+		if moveStr[i] < 'a' || moveStr[i] > 'h' || moveStr[i+1] < '1' || moveStr[i+1] > '8' {
+			return move, fmt.Errorf("%s is not a square", moveStr[i:i+2])
+		}
+	}
 	fromX := int(moveStr[0] - 'a')
 	fromY := 8 - int(moveStr[1]-'0')
 	toX := int(moveStr[2] - 'a')
@@ -206,6 +212,28 @@ func (board *Board) getStandardAlgebraicFromMove(m *Move) string {
 	}
 }
 
+// GetMoveFromStandardAlgebraic returns the legal move described by moveStr in standard algebraic notation like Nbd7,
+// exd5, e8=Q or O-O. Check and annotation symbols are ignored.
+func (board *Board) GetMoveFromStandardAlgebraic(moveStr string) (Move, error) {
+	san := strings.TrimRight(moveStr, "+#!?")
+	san = strings.ReplaceAll(san, "0", "O")
+	// promotions are sometimes written without =
+	if n := len(san); n > 2 && strings.ContainsRune("QRBN", rune(san[n-1])) && san[n-2] != '=' && unicode.IsDigit(rune(san[n-2])) {
+		san = san[:n-1] + "=" + san[n-1:]
+	}
+	for _, move := range board.getPossibleMoves() {
+		// only generate the notation of moves to the right square as it needs all possible moves
+		toX, toY := xy(move.to)
+		if !strings.HasPrefix(san, "O-O") && !strings.Contains(san, string(rune('a'+toX))+strconv.Itoa(8-toY)) {
+			continue
+		}
+		if board.getStandardAlgebraicFromMove(&move) == san {
+			return move, nil
+		}
+	}
+	return Move{}, fmt.Errorf("%s is not a legal move", moveStr)
+}
+
 // writePGNFile writes a file called YYYY-MM-DD-HH:MM.pgn from by converting playedMoves into the standard algebraic notation
 func writePGNFile(playedMoves []Move) {
 	dt := time.Now()
@@ -60,3 +60,14 @@ var numMovesFromFENTests = []numMovesFromFEN{
 	// position 6
 	{"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []string{}, 4, 3894594},
 }
+
+type perftDivide struct {
+	fen      string
+	expected []int // number of positions after 1, 2, 3 plies
+}
+
+// published perft results
+var perftDivideTests = []perftDivide{
+	{START_FEN, []int{20, 400, 8902}},
+	{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []int{44, 1486, 62379}},
+}
@@ -0,0 +1,287 @@
+package ghess
+
+import (
+	"math/bits"
+	"unsafe"
+)
+
+// PAWN_HASH_SIZE is the default number of entries in the pawn hash table (a power of two)
+const PAWN_HASH_SIZE = 1 << 12
+
+// pawnHashSize is the number of entries of newly allocated pawn hash tables
+var pawnHashSize = PAWN_HASH_SIZE
+
+const FILE_A_B uint64 = 0x0101010101010101
+const FILE_H_B uint64 = FILE_A_B << 7
+
+// default pawn structure weights in centipawns as midgame, endgame pairs
+var defaultDoubledPawnPenalty = [2]int{10, 25}
+var defaultIsolatedPawnPenalty = [2]int{10, 15}
+var defaultBackwardPawnPenalty = [2]int{8, 10}
+
+// bonuses indexed by the rank relative to the pawn's color (0 is the first rank of the color)
+var defaultConnectedPawnBonus = [2][8]int{{0, 5, 8, 12, 20, 35, 55, 0}, {0, 2, 4, 6, 12, 20, 30, 0}}
+var defaultPassedPawnBonus = [2][8]int{{0, 5, 10, 15, 30, 55, 90, 0}, {0, 10, 15, 25, 45, 75, 120, 0}}
+
+// passed pawns in the endgame get scaled by the distance of both kings to the square in front of the pawn
+const DEFAULT_PASSED_PAWN_OWN_KING_DISTANCE = 2
+const DEFAULT_PASSED_PAWN_ENEMY_KING_DISTANCE = 5
+
+type pawnHashEntry struct {
+	key    uint64
+	mg     int
+	eg     int
+	passed [2]uint64 // passed pawns of white and black
+}
+
+var fileMasks = getFileMasks()
+var passedPawnMasks = getPassedPawnMasks()
+
+// getFileMasks returns the bitboards of the 8 files
+func getFileMasks() [8]uint64 {
+	var masks [8]uint64
+	for x := 0; x < 8; x++ {
+		masks[x] = FILE_A_B << x
+	}
+	return masks
+}
+
+// getPassedPawnMasks returns for white (0) and black (1) and every square the squares in front of a pawn on the same and adjacent files
+// which need to be free of enemy pawns for the pawn to be passed
+func getPassedPawnMasks() [2][64]uint64 {
+	var masks [2][64]uint64
+	for pos := 0; pos < 64; pos++ {
+		x, y := xy(pos)
+		for dx := -1; dx <= 1; dx++ {
+			if x+dx < 0 || x+dx > 7 {
+				continue
+			}
+			for ty := 0; ty < 8; ty++ {
+				if ty < y {
+					masks[0][pos] |= 1 << (ty*8 + x + dx)
+				} else if ty > y {
+					masks[1][pos] |= 1 << (ty*8 + x + dx)
+				}
+			}
+		}
+	}
+	return masks
+}
+
+// adjacentFilesMask returns the files next to file x
+func adjacentFilesMask(x int) uint64 {
+	var mask uint64
+	if x > 0 {
+		mask |= fileMasks[x-1]
+	}
+	if x < 7 {
+		mask |= fileMasks[x+1]
+	}
+	return mask
+}
+
+// pawnAttacks returns all squares attacked by the given pawns of one color
+func pawnAttacks(pawnsB uint64, isBlack bool) uint64 {
+	if isBlack {
+		return ((pawnsB &^ FILE_A_B) << 7) | ((pawnsB &^ FILE_H_B) << 9)
+	}
+	return ((pawnsB &^ FILE_A_B) >> 9) | ((pawnsB &^ FILE_H_B) >> 7)
+}
+
+// relativeRank returns the rank from 0 to 7 as seen from the pawn's color
+func relativeRank(pos int, isBlack bool) int {
+	_, y := xy(pos)
+	if isBlack {
+		return y
+	}
+	return 7 - y
+}
+
+// distance returns the number of king moves between two squares
+func distance(a, b int) int {
+	ax, ay := xy(a)
+	bx, by := xy(b)
+	dx := abs(ax - bx)
+	dy := abs(ay - by)
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
+
+// getPawnBitboards returns the positions of the white and black pawns
+func (board *Board) getPawnBitboards() (uint64, uint64) {
+	var whitePawnsB, blackPawnsB uint64
+	for _, PieceId := range board.whiteIds {
+		if board.pieces[PieceId].pieceType == PAWN {
+			whitePawnsB |= board.pieces[PieceId].posB
+		}
+	}
+	for _, PieceId := range board.blackIds {
+		if board.pieces[PieceId].pieceType == PAWN {
+			blackPawnsB |= board.pieces[PieceId].posB
+		}
+	}
+	return whitePawnsB, blackPawnsB
+}
+
+// evaluatePawnsOfColor returns the midgame and endgame pawn structure score of one color (positive is good for that color)
+// and the bitboard of its passed pawns. If coef is not nil the applied weights are counted.
+func evaluatePawnsOfColor(ownPawnsB, enemyPawnsB uint64, isBlack bool, coef *evalCoefficients) (int, int, uint64) {
+	mg, eg := 0, 0
+	sign := colorSign(isBlack)
+	var passedB uint64
+	colorIdx := 0
+	forward := NORTH
+	if isBlack {
+		colorIdx = 1
+		forward = SOUTH
+	}
+	enemyAttacksB := pawnAttacks(enemyPawnsB, !isBlack)
+	ownAttacksB := pawnAttacks(ownPawnsB, isBlack)
+
+	for x := 0; x < 8; x++ {
+		if n := bits.OnesCount64(ownPawnsB & fileMasks[x]); n > 1 {
+			mg -= (n - 1) * evalParams.DoubledPawn[0]
+			eg -= (n - 1) * evalParams.DoubledPawn[1]
+			if coef != nil {
+				coef.mg.DoubledPawn[0] -= sign * (n - 1)
+				coef.eg.DoubledPawn[1] -= sign * (n - 1)
+			}
+		}
+	}
+
+	for pawnsB := ownPawnsB; pawnsB != 0; pawnsB &= pawnsB - 1 {
+		pos := bits.TrailingZeros64(pawnsB)
+		x, _ := xy(pos)
+		rank := relativeRank(pos, isBlack)
+		var posB uint64 = 1 << pos
+		neighborsB := ownPawnsB & adjacentFilesMask(x)
+
+		if neighborsB == 0 {
+			mg -= evalParams.IsolatedPawn[0]
+			eg -= evalParams.IsolatedPawn[1]
+			if coef != nil {
+				coef.mg.IsolatedPawn[0] -= sign
+				coef.eg.IsolatedPawn[1] -= sign
+			}
+		} else {
+			// backward: all neighbors are further advanced and the square in front is controlled by an enemy pawn
+			supportersB := neighborsB & ^passedPawnMasks[colorIdx][pos]
+			var stopB uint64 = 1 << (pos + forward)
+			if supportersB == 0 && stopB&enemyAttacksB != 0 {
+				mg -= evalParams.BackwardPawn[0]
+				eg -= evalParams.BackwardPawn[1]
+				if coef != nil {
+					coef.mg.BackwardPawn[0] -= sign
+					coef.eg.BackwardPawn[1] -= sign
+				}
+			}
+		}
+
+		// connected: defended by a pawn or next to a pawn on the same rank
+		phalanx := neighborsB&(posB<<1|posB>>1) != 0
+		if ownAttacksB&posB != 0 || phalanx {
+			mg += evalParams.ConnectedPawn[0][rank]
+			eg += evalParams.ConnectedPawn[1][rank]
+			if coef != nil {
+				coef.mg.ConnectedPawn[0][rank] += sign
+				coef.eg.ConnectedPawn[1][rank] += sign
+			}
+		}
+
+		if enemyPawnsB&passedPawnMasks[colorIdx][pos] == 0 {
+			passedB |= posB
+			mg += evalParams.PassedPawn[0][rank]
+			eg += evalParams.PassedPawn[1][rank]
+			if coef != nil {
+				coef.mg.PassedPawn[0][rank] += sign
+				coef.eg.PassedPawn[1][rank] += sign
+			}
+		}
+	}
+	return mg, eg, passedB
+}
+
+// SetHashSize sets the size of the hash tables in megabytes. The size is rounded down to a power of two entries.
+func SetHashSize(megabytes int) {
+	entries := megabytes * 1024 * 1024 / int(unsafe.Sizeof(pawnHashEntry{}))
+	pawnHashSize = 1
+	for pawnHashSize*2 <= entries {
+		pawnHashSize *= 2
+	}
+}
+
+// hashFull returns the permille of used entries of the pawn hash table
+func (board *Board) hashFull() int {
+	n := min(1000, len(board.pawnTable))
+	if n == 0 {
+		return 0
+	}
+	used := 0
+	for _, entry := range board.pawnTable[:n] {
+		if entry.key != 0 {
+			used++
+		}
+	}
+	return used * 1000 / n
+}
+
+// probePawnHash returns the pawn structure evaluation from the pawn hash table and computes it if it's not stored yet
+func (board *Board) probePawnHash() *pawnHashEntry {
+	if len(board.pawnTable) != pawnHashSize {
+		board.pawnTable = make([]pawnHashEntry, pawnHashSize)
+	}
+	entry := &board.pawnTable[board.pawnHash&uint64(pawnHashSize-1)]
+	if entry.key == board.pawnHash && board.pawnHash != 0 {
+		return entry
+	}
+	whitePawnsB, blackPawnsB := board.getPawnBitboards()
+	whiteMg, whiteEg, whitePassedB := evaluatePawnsOfColor(whitePawnsB, blackPawnsB, false, nil)
+	blackMg, blackEg, blackPassedB := evaluatePawnsOfColor(blackPawnsB, whitePawnsB, true, nil)
+	entry.key = board.pawnHash
+	entry.mg = whiteMg - blackMg
+	entry.eg = whiteEg - blackEg
+	entry.passed = [2]uint64{whitePassedB, blackPassedB}
+	return entry
+}
+
+// getPassedPawnKingScore returns the endgame score of passed pawns depending on the king distances to the square in front of them.
+// It depends on the king positions and is therefore not part of the pawn hash table.
+func (board *Board) getPassedPawnKingScore(passedB uint64, isBlack bool, coef *evalCoefficients) int {
+	ownKingPos := board.pieces[board.whiteKingId].pos
+	enemyKingPos := board.pieces[board.blackKingId].pos
+	forward := NORTH
+	if isBlack {
+		ownKingPos, enemyKingPos = enemyKingPos, ownKingPos
+		forward = SOUTH
+	}
+	eg := 0
+	for ; passedB != 0; passedB &= passedB - 1 {
+		pos := bits.TrailingZeros64(passedB)
+		rank := relativeRank(pos, isBlack)
+		if rank <= 2 {
+			continue
+		}
+		stop := pos + forward
+		enemyDistance := (rank - 2) * distance(enemyKingPos, stop)
+		ownDistance := (rank - 2) * distance(ownKingPos, stop)
+		eg += evalParams.PassedPawnEnemyKingDistance*enemyDistance - evalParams.PassedPawnOwnKingDistance*ownDistance
+		if coef != nil {
+			sign := colorSign(isBlack)
+			coef.eg.PassedPawnEnemyKingDistance += sign * enemyDistance
+			coef.eg.PassedPawnOwnKingDistance -= sign * ownDistance
+		}
+	}
+	return eg
+}
+
+// getPawnScore returns the midgame and endgame pawn structure score from whites perspective
+func (board *Board) getPawnScore() (int, int) {
+	entry := board.probePawnHash()
+	eg := entry.eg
+	if board.whiteKingId != 0 && board.blackKingId != 0 {
+		eg += board.getPassedPawnKingScore(entry.passed[0], false, nil) - board.getPassedPawnKingScore(entry.passed[1], true, nil)
+	}
+	return entry.mg, eg
+}
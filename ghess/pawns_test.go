@@ -0,0 +1,42 @@
+package ghess
+
+type passedPawns struct {
+	fen         string
+	whitePassed []string
+	blackPassed []string
+}
+
+var passedPawnsTests = []passedPawns{
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []string{}, []string{}},
+	{"4k3/8/8/3P4/8/8/8/4K3 w - - 0 1", []string{"d5"}, []string{}},
+	{"4k3/2p5/8/3P4/8/8/8/4K3 w - - 0 1", []string{}, []string{}},
+	{"4k3/8/8/3P4/2p5/8/8/4K3 w - - 0 1", []string{"d5"}, []string{"c4"}},
+	{"4k3/p7/8/8/8/8/1P5P/4K3 w - - 0 1", []string{"h2"}, []string{}},
+}
+
+type mirroredPawns struct {
+	fen         string
+	mirroredFen string
+}
+
+// the pawn structure score of a position must be the negative of the score with colors swapped
+var mirroredPawnsTests = []mirroredPawns{
+	{"4k3/pp3ppp/8/3p4/8/2P5/PP3PPP/4K3 w - - 0 1", "4k3/pp3ppp/2p5/8/3P4/8/PP3PPP/4K3 b - - 0 1"},
+	{"4k3/8/8/1P1P4/8/2p1p3/8/4K3 w - - 0 1", "4k3/8/2P1P3/8/1p1p4/8/8/4K3 b - - 0 1"},
+	{"6k1/5p1p/6p1/1P6/8/8/5PPP/6K1 w - - 0 1", "6k1/5ppp/8/8/1p6/6P1/5P1P/6K1 b - - 0 1"},
+}
+
+type pawnStructure struct {
+	fen string
+	mg  int
+	eg  int
+}
+
+var pawnStructureTests = []pawnStructure{
+	// doubled isolated pawns against a passed pawn
+	{"4k3/ppp5/8/8/8/2P5/2P5/4K3 w - - 0 1", -50, -71},
+	// connected passed pawns
+	{"4k3/p7/8/3PP3/8/8/8/4K3 w - - 0 1", 105, 119},
+	// isolated pawns on c4 and e4 against a backward pawn on d6
+	{"4k3/8/3p4/2p1p3/2P1P3/8/8/4K3 w - - 0 1", -36, -32},
+}
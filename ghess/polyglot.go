@@ -0,0 +1,223 @@
+package ghess
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// polyglotPieces are the piece types in the order of the Polyglot piece kinds (black kind = 2*index, white = 2*index+1)
+const polyglotPieces = "pnbrqk"
+
+// BookEntry is a single entry of a Polyglot opening book which stores a move for the position with the given key
+type BookEntry struct {
+	Key    uint64
+	Move   uint16 // to square in bits 0-5, from square in bits 6-11 (a1 = 0) and promotion in bits 12-14
+	Weight uint16
+	Learn  uint32
+}
+
+// Book is a Polyglot opening book
+type Book struct {
+	entries []BookEntry // sorted by key and decreasing weight
+}
+
+// NewBook returns a book containing entries
+func NewBook(entries []BookEntry) *Book {
+	book := &Book{entries: append([]BookEntry{}, entries...)}
+	sort.SliceStable(book.entries, func(i, j int) bool {
+		if book.entries[i].Key != book.entries[j].Key {
+			return book.entries[i].Key < book.entries[j].Key
+		}
+		return book.entries[i].Weight > book.entries[j].Weight
+	})
+	return book
+}
+
+// ReadBook reads a Polyglot .bin book
+func ReadBook(path string) (*Book, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%16 != 0 {
+		return nil, fmt.Errorf("%s: not a polyglot book as the size isn't a multiple of 16 bytes", path)
+	}
+	entries := make([]BookEntry, len(data)/16)
+	for i := range entries {
+		entry := data[16*i : 16*(i+1)]
+		entries[i] = BookEntry{
+			Key:    binary.BigEndian.Uint64(entry[0:8]),
+			Move:   binary.BigEndian.Uint16(entry[8:10]),
+			Weight: binary.BigEndian.Uint16(entry[10:12]),
+			Learn:  binary.BigEndian.Uint32(entry[12:16]),
+		}
+	}
+	return NewBook(entries), nil
+}
+
+// WriteFile writes the book in the Polyglot .bin format
+func (book *Book) WriteFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for _, entry := range book.entries {
+		binary.Write(w, binary.BigEndian, entry)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Len returns the number of entries in the book
+func (book *Book) Len() int {
+	return len(book.entries)
+}
+
+// Entries returns all entries for the position with the given Polyglot key
+func (book *Book) Entries(key uint64) []BookEntry {
+	start := sort.Search(len(book.entries), func(i int) bool { return book.entries[i].Key >= key })
+	end := start
+	for end < len(book.entries) && book.entries[end].Key == key {
+		end++
+	}
+	return book.entries[start:end]
+}
+
+// PolyglotKey returns the hash of the position as used by Polyglot opening books
+func (board *Board) PolyglotKey() uint64 {
+	var key uint64
+	for _, piece := range board.pieces[1:] {
+		if piece.posB == 0 {
+			continue
+		}
+		kind := 2 * strings.IndexRune(polyglotPieces, piece.pieceType)
+		if !piece.isBlack {
+			kind++
+		}
+		x, y := xy(piece.pos)
+		key ^= polyglotRandom[64*kind+8*(7-y)+x]
+	}
+	if board.white_castle_king {
+		key ^= polyglotRandom[768]
+	}
+	if board.white_castle_queen {
+		key ^= polyglotRandom[769]
+	}
+	if board.black_castle_king {
+		key ^= polyglotRandom[770]
+	}
+	if board.black_castle_queen {
+		key ^= polyglotRandom[771]
+	}
+	// the en passant file only counts if a pawn of the side to move stands next to the pawn that moved two squares
+	if board.en_passant_pos != -1 {
+		pawnPos := board.en_passant_pos + SOUTH
+		if board.IsBlacksTurn {
+			pawnPos = board.en_passant_pos + NORTH
+		}
+		x, _ := xy(pawnPos)
+		for _, dx := range [2]int{-1, 1} {
+			if x+dx < 0 || x+dx > 7 {
+				continue
+			}
+			neighbor := board.pieces[board.pos2PieceId[pawnPos+dx]]
+			if neighbor.pieceType == PAWN && neighbor.isBlack == board.IsBlacksTurn {
+				key ^= polyglotRandom[772+x]
+				break
+			}
+		}
+	}
+	if !board.IsBlacksTurn {
+		key ^= polyglotRandom[780]
+	}
+	return key
+}
+
+// PolyglotMove encodes move for a Polyglot book where castling is encoded as the king capturing its own rook
+func (board *Board) PolyglotMove(move *Move) uint16 {
+	// polyglot squares start at a1 instead of a8
+	from := move.from ^ 56
+	to := move.to ^ 56
+	if board.pieces[move.PieceId].pieceType == KING && abs(move.to-move.from) == 2 {
+		if move.to > move.from {
+			to = from + 3
+		} else {
+			to = from - 4
+		}
+	}
+	promotion := 0
+	if move.promote != 0 {
+		// polyglot uses 1 knight, ..., 4 queen and Move 1 queen, ..., 4 knight
+		promotion = 5 - move.promote
+	}
+	return uint16(promotion<<12 | from<<6 | to)
+}
+
+// moveFromPolyglot decodes a move of a Polyglot book and checks that it's legal in the current position
+func (board *Board) moveFromPolyglot(encoded uint16) (Move, error) {
+	from := int(encoded>>6&63) ^ 56
+	to := int(encoded&63) ^ 56
+	promotion := int(encoded >> 12 & 7)
+	piece := board.pieces[board.pos2PieceId[from]]
+	target := board.pieces[board.pos2PieceId[to]]
+	if piece.pieceType == KING && target.pieceType == ROOK && target.isBlack == piece.isBlack {
+		if to > from {
+			to = from + 2
+		} else {
+			to = from - 2
+		}
+	}
+	fromX, fromY := xy(from)
+	toX, toY := xy(to)
+	moveStr := string(rune('a'+fromX)) + strconv.Itoa(8-fromY) + string(rune('a'+toX)) + strconv.Itoa(8-toY)
+	if promotion > 0 && promotion <= 4 {
+		moveStr += string(polyglotPieces[promotion])
+	}
+	return board.GetMoveFromLongAlgebraic(moveStr)
+}
+
+// BookMove returns a move from book for the current position. The move is either the one with the highest weight
+// or chosen randomly with a probability proportional to its weight. Returns false if the book has no move.
+func (board *Board) BookMove(book *Book, bestMove bool) (Move, bool) {
+	if book == nil {
+		return Move{}, false
+	}
+	moves := []Move{}
+	weights := []int{}
+	totalWeight := 0
+	for _, entry := range book.Entries(board.PolyglotKey()) {
+		move, err := board.moveFromPolyglot(entry.Move)
+		if err != nil || entry.Weight == 0 {
+			continue
+		}
+		moves = append(moves, move)
+		weights = append(weights, int(entry.Weight))
+		totalWeight += int(entry.Weight)
+	}
+	if len(moves) == 0 {
+		return Move{}, false
+	}
+	if bestMove {
+		// entries are sorted by decreasing weight
+		return moves[0], true
+	}
+	r := rand.Intn(totalWeight)
+	for i, weight := range weights {
+		if r < weight {
+			return moves[i], true
+		}
+		r -= weight
+	}
+	return moves[len(moves)-1], true
+}
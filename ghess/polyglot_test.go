@@ -0,0 +1,38 @@
+package ghess
+
+// polyglotKeyStruct is a position reached from the start position by moves in long algebraic notation
+type polyglotKeyStruct struct {
+	moves []string
+	key   uint64
+}
+
+// keys from the Polyglot book format specification
+var polyglotKeyTests = []polyglotKeyStruct{
+	{[]string{}, 0x463b96181691fc9c},
+	{[]string{"e2e4"}, 0x823c9b50fd114196},
+	{[]string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+	{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+	{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+	{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+	{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+	{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+	{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+}
+
+// polyglotMoveStruct is a move in long algebraic notation and its encoding in a Polyglot book
+type polyglotMoveStruct struct {
+	fen     string
+	move    string
+	encoded uint16
+}
+
+var polyglotMoveTests = []polyglotMoveStruct{
+	{START_FEN, "e2e4", 12<<6 | 28},
+	{START_FEN, "g1f3", 6<<6 | 21},
+	{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", 4<<6 | 7},
+	{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1c1", 4<<6 | 0},
+	{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8g8", 60<<6 | 63},
+	{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", 60<<6 | 56},
+	{"8/1P6/8/8/8/8/k7/4K3 w - - 0 1", "b7b8q", 4<<12 | 49<<6 | 57},
+	{"8/1P6/8/8/8/8/k7/4K3 w - - 0 1", "b7b8n", 1<<12 | 49<<6 | 57},
+}
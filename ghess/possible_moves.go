@@ -78,3 +78,19 @@ func (board *Board) getNumberOfMoves(startPly, ply int, IsBlacksTurn bool) int {
 func (board *Board) GetNumberOfMoves(ply int) int {
 	return board.getNumberOfMoves(ply, ply, board.IsBlacksTurn)
 }
+
+// PerftDivide returns the number of positions after ply plies for each legal move in long algebraic notation
+func (board *Board) PerftDivide(ply int) map[string]int {
+	divide := make(map[string]int)
+	for _, move := range board.getPossibleMoves() {
+		n := 1
+		if ply > 1 {
+			boardPrimitives := board.getBoardPrimitives()
+			board.Move(&move)
+			n = board.GetNumberOfMoves(ply - 1)
+			board.reverseMove(&move, &boardPrimitives)
+		}
+		divide[GetAlgebraicFromMove(&move)] = n
+	}
+	return divide
+}
@@ -23,12 +23,44 @@ socket.onmessage = function (event) {
         captureId = jsonObj.captureId
         to = jsonObj.to
         promotionDialog.showModal();
+    } else if (jsonObj.requestType == "eval") {
+        showEvaluation(jsonObj.trace)
     } else if (jsonObj.requestType == "end") {
         endDialog.innerHTML = jsonObj.msg;
         endDialog.showModal();
     }
 }
 
+function formatPawns(centipawns) {
+    return (centipawns / 100).toFixed(2)
+}
+
+function showEvaluation(trace) {
+    let table = document.getElementById("evaluation")
+    let html = "<tr><th>Term</th><th>White MG</th><th>White EG</th><th>Black MG</th><th>Black EG</th><th>Total MG</th><th>Total EG</th></tr>"
+    for (let term of trace.terms) {
+        html += "<tr><td>" + term.name + "</td>"
+        html += "<td>" + formatPawns(term.white[0]) + "</td><td>" + formatPawns(term.white[1]) + "</td>"
+        html += "<td>" + formatPawns(term.black[0]) + "</td><td>" + formatPawns(term.black[1]) + "</td>"
+        html += "<td>" + formatPawns(term.white[0] - term.black[0]) + "</td><td>" + formatPawns(term.white[1] - term.black[1]) + "</td></tr>"
+    }
+    html += "<tr><td>Phase</td><td colspan=\"6\">" + trace.phase + "/24</td></tr>"
+    if (trace.scale != 64) {
+        html += "<tr><td>Endgame scale</td><td colspan=\"6\">" + trace.scale + "/64</td></tr>"
+    }
+    if (trace.endgame) {
+        html += "<tr><td>Endgame</td><td colspan=\"6\">" + trace.endgame + "</td></tr>"
+    }
+    if (trace.network) {
+        html += "<tr><td>NNUE</td><td colspan=\"6\">" + formatPawns(trace.nnue) + " (replaces the terms)</td></tr>"
+    }
+    if (trace.noise) {
+        html += "<tr><td>Skill noise</td><td colspan=\"6\">" + formatPawns(trace.noise) + "</td></tr>"
+    }
+    html += "<tr><td>Evaluation</td><td colspan=\"6\">" + formatPawns(trace.total) + " (white side)</td></tr>"
+    table.innerHTML = html
+}
+
 function resetSurrounding() {
     for (let i = 0; i < 8; i++) {
         for (let j = 0; j < 8; j++) {
@@ -185,4 +217,10 @@ let startButton = document.getElementById("start");
 startButton.addEventListener('click', function() {
     var data = JSON.stringify({"requestType": "start"});
     socket.send(data);
-})
\ No newline at end of file
+})
+
+let skillSelect = document.getElementById("skill");
+skillSelect.addEventListener('change', function() {
+    var data = JSON.stringify({"requestType": "skill", "level": parseInt(skillSelect.value)});
+    socket.send(data);
+})
@@ -0,0 +1,102 @@
+package ghess
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// MAX_SELF_PLAY_PLIES is the maximum length of a self play game such that the search stays within the position history
+const MAX_SELF_PLAY_PLIES = 400
+
+// SelfPlayPosition is a quiet position of a self play game with the search score in centipawns
+// and the result of the game both from whites perspective
+type SelfPlayPosition struct {
+	Fen    string
+	Score  int
+	Result float64
+}
+
+// SelfPlayOptions configure PlaySelfPlayGame
+type SelfPlayOptions struct {
+	StartFen    string     // position the game starts from (default START_FEN)
+	RandomPlies int        // number of random moves played before the engines take over
+	Nodes       int        // nodes the alpha beta engine searches per move
+	MaxPlies    int        // the game is adjudicated as a draw after this many plies (at most MAX_SELF_PLAY_PLIES)
+	Rand        *rand.Rand // source of the random opening moves (default seeded with the current time)
+}
+
+// String returns the position in the format "fen | score | result" which can be read by ParseTuningPosition
+func (position SelfPlayPosition) String() string {
+	return fmt.Sprintf("%s | %d | %.1f", position.Fen, position.Score, position.Result)
+}
+
+// playRandomOpening plays random moves and returns false if the game ended during the opening
+func (board *Board) playRandomOpening(plies int, r *rand.Rand) bool {
+	for i := 0; i < plies; i++ {
+		moves := board.getPossibleMoves()
+		if len(moves) == 0 {
+			return false
+		}
+		move := moves[r.Intn(len(moves))]
+		board.Move(&move)
+		if gameEnded, _, _ := board.CheckGameEnded(); gameEnded {
+			return false
+		}
+	}
+	return true
+}
+
+// PlaySelfPlayGame plays a game of the alpha beta engine against itself starting with a random opening and returns
+// the positions in which the side to move wasn't in check and the engine chose a quiet move.
+// Positions with a mate score aren't returned.
+func PlaySelfPlayGame(options SelfPlayOptions) []SelfPlayPosition {
+	startFen := options.StartFen
+	if startFen == "" {
+		startFen = START_FEN
+	}
+	maxPlies := options.MaxPlies
+	if maxPlies <= 0 || maxPlies > MAX_SELF_PLAY_PLIES {
+		maxPlies = MAX_SELF_PLAY_PLIES
+	}
+	r := options.Rand
+	if r == nil {
+		r = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	var board Board
+	for {
+		board = GetBoardFromFen(startFen)
+		if board.playRandomOpening(options.RandomPlies, r) {
+			break
+		}
+	}
+	board.SetNodeLimit(options.Nodes)
+
+	var positions []SelfPlayPosition
+	result := 0.5
+	for board.ply < maxPlies {
+		gameEnded, endType, _ := board.CheckGameEnded()
+		if gameEnded {
+			if endType == "checkmate" {
+				result = 1.0
+				if !board.IsBlacksTurn {
+					result = 0.0
+				}
+			}
+			break
+		}
+		// the time limit is only a safeguard as the search is limited by the number of nodes
+		ab := board.AlphaBetaEngineMove([30]Move{}, 1, 30, false, false, 60*60*1000)
+		move := ab.Pv[0]
+		quiet := !board.check && move.captureId == 0 && move.promote == 0
+		if quiet && !math.IsNaN(ab.Score) && math.Abs(ab.Score) < 10000 {
+			positions = append(positions, SelfPlayPosition{Fen: board.GetFen(), Score: int(math.Round(ab.Score))})
+		}
+		board.Move(&move)
+	}
+	for i := range positions {
+		positions[i].Result = result
+	}
+	return positions
+}
@@ -0,0 +1,163 @@
+package ghess
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// MAX_SKILL_LEVEL is the skill level of the unrestricted alpha beta engine
+const MAX_SKILL_LEVEL = 20
+
+// SKILL_MIN_ELO and SKILL_MAX_ELO are the Elo ratings corresponding to skill level 0 and MAX_SKILL_LEVEL
+const SKILL_MIN_ELO = 800
+const SKILL_MAX_ELO = 2200
+
+// SKILL_MULTI_PV is the number of best root moves a weakened engine chooses from
+const SKILL_MULTI_PV = 4
+
+// SKILL_NOISE_PERCENT is the percentage of positions whose static evaluation is disturbed by a weakened engine
+const SKILL_NOISE_PERCENT = 20
+
+// skillCandidate is a root move with the score and pv of its search from whites perspective
+type skillCandidate struct {
+	move  Move
+	score float64
+	pv    [30]Move
+}
+
+// SetSkillLevel weakens the alpha beta engine for levels between 0 and MAX_SKILL_LEVEL - 1 by limiting the depth
+// and nodes, choosing suboptimal moves and adding noise to the evaluation. MAX_SKILL_LEVEL is full strength.
+func (board *Board) SetSkillLevel(level int) {
+	board.skillLevel = max(0, min(level, MAX_SKILL_LEVEL))
+}
+
+// SkillLevelFromElo returns the skill level which plays at roughly the given Elo rating
+func SkillLevelFromElo(elo int) int {
+	elo = max(SKILL_MIN_ELO, min(elo, SKILL_MAX_ELO))
+	return (elo - SKILL_MIN_ELO) * MAX_SKILL_LEVEL / (SKILL_MAX_ELO - SKILL_MIN_ELO)
+}
+
+// isWeakened returns true if the engine plays below full strength
+func (board *Board) isWeakened() bool {
+	return board.skillLevel < MAX_SKILL_LEVEL
+}
+
+// skillDepth returns the maximum search depth of a skill level
+func skillDepth(level int) int {
+	return 1 + level/3
+}
+
+// skillNodes returns the maximum number of nodes of a search at a skill level
+func skillNodes(level int) int {
+	return 200 * (level + 1) * (level + 1)
+}
+
+// skillNoise returns the evaluation error of the current position for a weakened engine.
+// The error is derived from the position hash such that a position gets the same error during a search.
+func (board *Board) skillNoise() float64 {
+	if !board.isWeakened() {
+		return 0
+	}
+	// splitmix64 finalizer
+	h := board.posHashes[board.ply] ^ board.skillSeed
+	h = (h ^ (h >> 30)) * 0xBF58476D1CE4E5B9
+	h = (h ^ (h >> 27)) * 0x94D049BB133111EB
+	h ^= h >> 31
+	if h%100 >= SKILL_NOISE_PERCENT {
+		return 0
+	}
+	amplitude := uint64(10 * (MAX_SKILL_LEVEL - board.skillLevel))
+	return float64(int((h>>8)%(2*amplitude+1)) - int(amplitude))
+}
+
+// skillEngineMove searches every root move with a limited depth and node budget and picks one of the best
+// SKILL_MULTI_PV moves where worse moves get chosen more often the lower the skill level is.
+func (board *Board) skillEngineMove(maxTime time.Duration, verbose bool) AlphaBetaOutput {
+	startTime := time.Now()
+	level := board.skillLevel
+	nodeLimit := board.nodeLimit
+	if nodeLimit == 0 || skillNodes(level) < nodeLimit {
+		board.nodeLimit = skillNodes(level)
+	}
+	defer func() { board.nodeLimit = nodeLimit }()
+	board.skillSeed = rand.Uint64()
+	board.nodes = 0
+	maximizing := !board.IsBlacksTurn
+	stopPondering := board.stop
+
+	candidates := []skillCandidate{}
+	for _, om := range board.filterRootMoves(board.getPossibleMovesOrdered(false, [30]Move{}, 0)) {
+		candidates = append(candidates, skillCandidate{move: om.move})
+	}
+	if len(candidates) == 0 {
+		return AlphaBetaOutput{Score: math.NaN()}
+	}
+	depth := 1
+	for ; depth <= skillDepth(level); depth++ {
+		board.rootDepth = depth
+		board.selDepth = 0
+		searched := make([]skillCandidate, 0, len(candidates))
+		for _, candidate := range candidates {
+			// the first iteration always completes such that there is a move to play
+			if board.searchExhausted(startTime, maxTime, depth > 1) {
+				break
+			}
+			boardPrimitives := board.getBoardPrimitives()
+			board.Move(&candidate.move)
+			ab := board.alphaBetaPruning(stopPondering, depth > 1, 1, depth-1, math.Inf(-1), math.Inf(1), !maximizing, [30]Move{}, false, startTime, maxTime, AlphaBetaOutput{})
+			board.reverseMove(&candidate.move, &boardPrimitives)
+			if !ab.Completed {
+				break
+			}
+			candidate.score = ab.Score
+			candidate.pv = ab.Pv
+			candidate.pv[0] = candidate.move
+			searched = append(searched, candidate)
+		}
+		if len(searched) < len(candidates) {
+			break
+		}
+		sort.SliceStable(searched, func(i, j int) bool {
+			if maximizing {
+				return searched[i].score > searched[j].score
+			}
+			return searched[i].score < searched[j].score
+		})
+		candidates = searched
+	}
+
+	chosen := board.chooseSkillCandidate(candidates)
+	if verbose {
+		printPv(chosen.pv)
+		fmt.Printf("skill level %d searched to depth %d with %d nodes\n", level, depth-1, board.nodes)
+	}
+	return AlphaBetaOutput{Completed: true, Score: chosen.score, Pv: chosen.pv, NodesSearched: board.nodes, Depth: depth - 1, SelDepth: board.selDepth}
+}
+
+// chooseSkillCandidate picks one of the best SKILL_MULTI_PV candidates which are sorted from best to worst.
+// Every move gets a bonus which grows with its loss compared to the best move and a random part which is bounded
+// by the spread of the candidates. The lower the skill level the larger both parts are.
+func (board *Board) chooseSkillCandidate(candidates []skillCandidate) skillCandidate {
+	sign := 1.0
+	if board.IsBlacksTurn {
+		sign = -1.0
+	}
+	multiPV := min(SKILL_MULTI_PV, len(candidates))
+	weakness := 120 - 2*board.skillLevel
+	topScore := sign * candidates[0].score
+	delta := math.Min(topScore-sign*candidates[multiPV-1].score, float64(evalParams.MgPieceValue[pieceIndex(PAWN)]))
+	chosen := candidates[0]
+	bestScore := math.Inf(-1)
+	for _, candidate := range candidates[:multiPV] {
+		score := sign * candidate.score
+		push := (float64(weakness)*(topScore-score) + delta*float64(rand.Intn(weakness))) / 128
+		if score+push >= bestScore {
+			bestScore = score + push
+			chosen = candidate
+		}
+	}
+	return chosen
+}
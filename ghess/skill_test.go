@@ -0,0 +1,26 @@
+package ghess
+
+type skillEloStruct struct {
+	elo   int
+	level int
+}
+
+var skillEloTests = []skillEloStruct{
+	{0, 0},
+	{SKILL_MIN_ELO, 0},
+	{1150, 5},
+	{1500, 10},
+	{SKILL_MAX_ELO, MAX_SKILL_LEVEL},
+	{3000, MAX_SKILL_LEVEL},
+}
+
+// skillCaptureStruct is a position where the side to move can win a queen with the expected move
+type skillCaptureStruct struct {
+	fen      string
+	expected string
+}
+
+var skillCaptureTests = []skillCaptureStruct{
+	{"4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1", "d2d5"},
+	{"4k3/3r4/8/8/3Q4/8/8/4K3 b - - 0 1", "d7d4"},
+}
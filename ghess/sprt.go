@@ -0,0 +1,100 @@
+package ghess
+
+import (
+	"fmt"
+	"math"
+)
+
+// SPRT_CONTINUE, SPRT_H0 and SPRT_H1 are the states of a sequential probability ratio test
+const (
+	SPRT_CONTINUE = iota // more games are needed
+	SPRT_H0              // the Elo difference is at most Elo0
+	SPRT_H1              // the Elo difference is at least Elo1
+)
+
+// SPRT_PSEUDO_COUNT replaces the count of results which didn't occur (like fishtest does) such that the variance isn't 0
+// if for example all games were won
+const SPRT_PSEUDO_COUNT = 1e-3
+
+// SPRT is a sequential probability ratio test of the hypothesis H0 that the Elo difference is Elo0 against the
+// hypothesis H1 that it is Elo1. Alpha is the probability to accept H1 if H0 is true and Beta the probability to
+// accept H0 if H1 is true. The log likelihood ratio is approximated by a normal distribution of the scores
+// (generalized SPRT) such that draws and the correlation of game pairs are taken into account.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+// Validate returns an error if the test can't decide
+func (sprt SPRT) Validate() error {
+	if sprt.Elo0 >= sprt.Elo1 {
+		return fmt.Errorf("elo0 has to be smaller than elo1")
+	}
+	if sprt.Alpha <= 0 || sprt.Alpha >= 1 || sprt.Beta <= 0 || sprt.Beta >= 1 {
+		return fmt.Errorf("alpha and beta have to be between 0 and 1")
+	}
+	return nil
+}
+
+// Bounds returns the log likelihood ratios at which H0 and H1 are accepted
+func (sprt SPRT) Bounds() (float64, float64) {
+	return math.Log(sprt.Beta / (1 - sprt.Alpha)), math.Log((1 - sprt.Beta) / sprt.Alpha)
+}
+
+// Status returns SPRT_H0 or SPRT_H1 if the log likelihood ratio reached a bound and SPRT_CONTINUE otherwise
+func (sprt SPRT) Status(llr float64) int {
+	lower, upper := sprt.Bounds()
+	switch {
+	case llr <= lower:
+		return SPRT_H0
+	case llr >= upper:
+		return SPRT_H1
+	}
+	return SPRT_CONTINUE
+}
+
+// LLRTrinomial returns the log likelihood ratio of single games with the given results
+func (sprt SPRT) LLRTrinomial(wins, draws, losses int) float64 {
+	return sprt.llr([]float64{0, 0.5, 1}, []int{losses, draws, wins})
+}
+
+// LLRPentanomial returns the log likelihood ratio of game pairs where pairs[i] is the number of pairs in which
+// i half points were scored (from 0 for two losses to 4 for two wins)
+func (sprt SPRT) LLRPentanomial(pairs [5]int) float64 {
+	return sprt.llr([]float64{0, 0.25, 0.5, 0.75, 1}, pairs[:])
+}
+
+// llr returns the log likelihood ratio of samples with the given scores where counts[i] samples scored scores[i]
+func (sprt SPRT) llr(scores []float64, counts []int) float64 {
+	total := 0
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	regularized := make([]float64, len(counts))
+	n := 0.0
+	mean := 0.0
+	for i, count := range counts {
+		regularized[i] = float64(count)
+		if count == 0 {
+			regularized[i] = SPRT_PSEUDO_COUNT
+		}
+		n += regularized[i]
+		mean += regularized[i] * scores[i]
+	}
+	mean /= n
+	variance := 0.0
+	for i, count := range regularized {
+		variance += count * math.Pow(scores[i]-mean, 2)
+	}
+	variance /= n
+	s0, s1 := scoreFromElo(sprt.Elo0), scoreFromElo(sprt.Elo1)
+	return n * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// scoreFromElo returns the expected score of a player with the given Elo difference
+func scoreFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
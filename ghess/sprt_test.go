@@ -0,0 +1,22 @@
+package ghess
+
+var sprtTrinomialTests = []struct {
+	wins, draws, losses int
+	llr                 float64
+}{
+	{110, 200, 90, 0.4953},
+	{80, 200, 120, -1.2592},
+	{0, 0, 0, 0},
+	{0, 10, 0, -5.1790},
+	{10, 0, 0, 285.8119},
+	{0, 0, 10, -289.9558},
+}
+
+var sprtPentanomialTests = []struct {
+	pairs [5]int
+	llr   float64
+}{
+	{[5]int{10, 40, 100, 50, 15}, 0.5560},
+	{[5]int{0, 0, 7, 0, 0}, -2.0317},
+	{[5]int{0, 0, 0, 0, 3}, 17.1799},
+}
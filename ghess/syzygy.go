@@ -0,0 +1,1188 @@
+package ghess
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math/bits"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Syzygy endgame tablebase probing. The WDL (win/draw/loss) tables are used inside the search and the DTZ
+// (distance to zeroing move) tables at the root. The decoding follows the reference implementation of the file format.
+// Squares are numbered like in the tablebase files with a1 = 0 and h8 = 63 which is the board position ^ 56.
+
+// TB_PIECES is the maximum number of pieces (including kings) a tablebase file can have
+const TB_PIECES = 7
+
+// WDL results of a tablebase probe from the perspective of the side to move.
+// A cursed win is a win which can't be forced within the 50 move rule and a blessed loss the opposite.
+const (
+	TB_LOSS         = -2
+	TB_BLESSED_LOSS = -1
+	TB_DRAW         = 0
+	TB_CURSED_WIN   = 1
+	TB_WIN          = 2
+)
+
+// SYZYGY_WIN_SCORE is the score of a tablebase win. It is above every evaluation but below mate scores.
+const SYZYGY_WIN_SCORE = 20000.0
+
+// probe states
+const (
+	probeFail            = 0
+	probeOK              = 1
+	probeChangeStm       = -1 // the DTZ table stores the other side to move
+	probeZeroingBestMove = 2  // the best move is a capture or pawn move
+)
+
+// flags of a table
+const (
+	tbFlagStm         = 1
+	tbFlagMapped      = 2
+	tbFlagWinPlies    = 4
+	tbFlagLossPlies   = 8
+	tbFlagWide        = 16
+	tbFlagSingleValue = 128
+)
+
+var tbWDLMagic = []byte{0xD7, 0x66, 0x0C, 0xA5}
+var tbDTZMagic = []byte{0x71, 0xE8, 0x23, 0x5D}
+
+// tbPieceChars are the piece letters indexed by the piece type used in the tablebase files (pawn = 1, ..., king = 6)
+const tbPieceChars = " PNBRQK"
+
+// index tables of the tablebase encoding
+var (
+	tbMapPawns      [64]int
+	tbMapB1H1H7     [64]int
+	tbMapA1D1D4     [64]int
+	tbMapKK         [10][64]int
+	tbBinomial      [7][64]uint64
+	tbLeadPawnIdx   [6][64]uint64
+	tbLeadPawnsSize [6][4]uint64
+)
+
+// tbPairsData describes the compressed values of one table. Offsets point into the data of the file.
+type tbPairsData struct {
+	flags           byte
+	sizeofBlock     uint64
+	span            uint64
+	numBlocks       int
+	maxSymLen       int
+	minSymLen       int // stores the value of the table if all values are the same
+	lowestSym       int
+	btree           int
+	blockLength     int
+	blockLengthSize int
+	sparseIndex     int
+	sparseIndexSize int
+	data            int
+	base64          []uint64
+	symlen          []byte
+	pieces          [TB_PIECES]int
+	groupIdx        [TB_PIECES + 1]uint64
+	groupLen        [TB_PIECES + 1]int
+	mapIdx          [4]int // offsets into the DTZ map for a win, loss, cursed win and blessed loss
+}
+
+// tbTable is a WDL or DTZ file like KRvK.rtbw which gets read on the first probe
+type tbTable struct {
+	isDTZ           bool
+	path            string
+	key             string // material key with white having the pieces of the first side of the file name
+	key2            string // material key with the colors swapped
+	pieceCount      int
+	hasPawns        bool
+	hasUniquePieces bool
+	pawnCount       [2]int // lead color and other color
+	items           [2][4]tbPairsData
+	data            []byte
+	dtzMap          int
+	once            sync.Once
+	err             error
+}
+
+// syzygyTablebases are the tables found in the SyzygyPath indexed by both material keys
+type syzygyTablebases struct {
+	wdl       map[string]*tbTable
+	dtz       map[string]*tbTable
+	maxPieces int
+}
+
+var syzygy *syzygyTablebases
+
+func init() {
+	initSyzygyIndexTables()
+}
+
+// offA1H8 returns the distance of a square to the a1-h8 diagonal which is negative below the diagonal
+func offA1H8(sq int) int {
+	return (sq >> 3) - (sq & 7)
+}
+
+func initSyzygyIndexTables() {
+	code := 0
+	for s := 0; s < 64; s++ {
+		if offA1H8(s) < 0 {
+			tbMapB1H1H7[s] = code
+			code++
+		}
+	}
+
+	// squares of the a1-d1-d4 triangle with the ones on the diagonal encoded last
+	var diagonal []int
+	code = 0
+	for s := 0; s <= 27; s++ {
+		if offA1H8(s) < 0 && s&7 <= 3 {
+			tbMapA1D1D4[s] = code
+			code++
+		} else if offA1H8(s) == 0 && s&7 <= 3 {
+			diagonal = append(diagonal, s)
+		}
+	}
+	for _, s := range diagonal {
+		tbMapA1D1D4[s] = code
+		code++
+	}
+
+	// the 462 legal placements of two kings where the first one is in the a1-d1-d4 triangle
+	type kingPair struct{ idx, sq int }
+	var bothOnDiagonal []kingPair
+	code = 0
+	for idx := 0; idx < 10; idx++ {
+		for s1 := 0; s1 <= 27; s1++ {
+			if tbMapA1D1D4[s1] != idx || (idx == 0 && s1 != 1) { // b1 is mapped to 0
+				continue
+			}
+			for s2 := 0; s2 < 64; s2++ {
+				if tbSquareDistance(s1, s2) <= 1 {
+					continue // illegal position
+				} else if offA1H8(s1) == 0 && offA1H8(s2) > 0 {
+					continue // first on diagonal, second above
+				} else if offA1H8(s1) == 0 && offA1H8(s2) == 0 {
+					bothOnDiagonal = append(bothOnDiagonal, kingPair{idx, s2})
+				} else {
+					tbMapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, p := range bothOnDiagonal {
+		tbMapKK[p.idx][p.sq] = code
+		code++
+	}
+
+	// tbBinomial[k][n] is the number of ways to choose k out of n elements
+	tbBinomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 7 && k <= n; k++ {
+			if k > 0 {
+				tbBinomial[k][n] += tbBinomial[k-1][n-1]
+			}
+			if k < n {
+				tbBinomial[k][n] += tbBinomial[k][n-1]
+			}
+		}
+	}
+
+	// tbMapPawns encodes a2-h7 such that the leading pawn (nearest to the edge and lowest rank) has the highest value
+	availableSquares := 47
+	for leadPawnsCnt := 1; leadPawnsCnt <= 5; leadPawnsCnt++ {
+		for f := 0; f < 4; f++ {
+			idx := uint64(0)
+			for r := 1; r <= 6; r++ {
+				sq := r*8 + f
+				if leadPawnsCnt == 1 {
+					tbMapPawns[sq] = availableSquares
+					availableSquares--
+					tbMapPawns[sq^7] = availableSquares
+					availableSquares--
+				}
+				tbLeadPawnIdx[leadPawnsCnt][sq] = idx
+				idx += tbBinomial[leadPawnsCnt-1][tbMapPawns[sq]]
+			}
+			tbLeadPawnsSize[leadPawnsCnt][f] = idx
+		}
+	}
+}
+
+// tbSquareDistance returns the king distance between two squares
+func tbSquareDistance(s1, s2 int) int {
+	return max(abs(s1&7-s2&7), abs(s1>>3-s2>>3))
+}
+
+// tbPieceType returns the piece type as used in the tablebase files
+func tbPieceType(pieceType rune) int {
+	return strings.IndexRune(tbPieceChars, pieceType-'a'+'A')
+}
+
+// tbMaterialKey returns the material of both colors like KRPvKR where counts are indexed by color and tablebase piece type
+func tbMaterialKey(counts [2][7]int) string {
+	var sb strings.Builder
+	for c := 0; c < 2; c++ {
+		if c == 1 {
+			sb.WriteByte('v')
+		}
+		for t := 6; t >= 1; t-- {
+			for i := 0; i < counts[c][t]; i++ {
+				sb.WriteByte(tbPieceChars[t])
+			}
+		}
+	}
+	return sb.String()
+}
+
+// tbMaterialKey returns the material key of the position
+func (board *Board) tbMaterialKey() string {
+	var counts [2][7]int
+	for _, piece := range board.pieces {
+		if piece.posB == 0 || piece.id == 0 {
+			continue
+		}
+		c := 0
+		if piece.isBlack {
+			c = 1
+		}
+		counts[c][tbPieceType(piece.pieceType)]++
+	}
+	return tbMaterialKey(counts)
+}
+
+// numberOfPieces returns the number of pieces on the board including kings
+func (board *Board) numberOfPieces() int {
+	return bits.OnesCount64(board.whitePiecePosB | board.blackPiecePosB)
+}
+
+// newTBTable parses a file name like KRvK into the piece counts of the table
+func newTBTable(code string, isDTZ bool, path string) (*tbTable, error) {
+	sides := strings.Split(code, "v")
+	if len(sides) != 2 {
+		return nil, fmt.Errorf("invalid tablebase name %s", code)
+	}
+	var counts [2][7]int
+	for c, side := range sides {
+		for _, r := range side {
+			t := strings.IndexRune(tbPieceChars, r)
+			if t < 1 {
+				return nil, fmt.Errorf("invalid tablebase name %s", code)
+			}
+			counts[c][t]++
+		}
+		if counts[c][6] != 1 {
+			return nil, fmt.Errorf("invalid tablebase name %s", code)
+		}
+	}
+	table := &tbTable{isDTZ: isDTZ, path: path}
+	table.key = tbMaterialKey(counts)
+	table.key2 = tbMaterialKey([2][7]int{counts[1], counts[0]})
+	for c := 0; c < 2; c++ {
+		for t := 1; t <= 6; t++ {
+			table.pieceCount += counts[c][t]
+			if t < 6 && counts[c][t] == 1 {
+				table.hasUniquePieces = true
+			}
+		}
+	}
+	if table.pieceCount > TB_PIECES {
+		return nil, fmt.Errorf("tablebase %s has more than %d pieces", code, TB_PIECES)
+	}
+	whitePawns, blackPawns := counts[0][1], counts[1][1]
+	table.hasPawns = whitePawns+blackPawns > 0
+	// the leading color is the one with less pawns as this leads to a better compression
+	if blackPawns == 0 || (whitePawns > 0 && blackPawns >= whitePawns) {
+		table.pawnCount = [2]int{whitePawns, blackPawns}
+	} else {
+		table.pawnCount = [2]int{blackPawns, whitePawns}
+	}
+	return table, nil
+}
+
+// InitSyzygy looks for Syzygy tablebase files in path which can contain several directories
+// separated by the os specific list separator. An empty path disables tablebase probing.
+// Returns the number of WDL tables found.
+func InitSyzygy(path string) (int, error) {
+	syzygy = nil
+	if path == "" || path == "<empty>" {
+		return 0, nil
+	}
+	tbs := &syzygyTablebases{wdl: make(map[string]*tbTable), dtz: make(map[string]*tbTable)}
+	for _, dir := range filepath.SplitList(path) {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if ext != ".rtbw" && ext != ".rtbz" {
+				continue
+			}
+			table, err := newTBTable(strings.TrimSuffix(name, ext), ext == ".rtbz", filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+			tables := tbs.wdl
+			if table.isDTZ {
+				tables = tbs.dtz
+			}
+			if _, ok := tables[table.key]; ok {
+				continue
+			}
+			tables[table.key] = table
+			tables[table.key2] = table
+			if !table.isDTZ {
+				tbs.maxPieces = max(tbs.maxPieces, table.pieceCount)
+			}
+		}
+	}
+	numTables := 0
+	for key, table := range tbs.wdl {
+		if key == table.key {
+			numTables++
+		}
+	}
+	if numTables > 0 {
+		syzygy = tbs
+	}
+	return numTables, nil
+}
+
+// SyzygyMaxPieces returns the maximum number of pieces of the loaded tablebases and 0 if none are loaded
+func SyzygyMaxPieces() int {
+	if syzygy == nil {
+		return 0
+	}
+	return syzygy.maxPieces
+}
+
+func (t *tbTable) sides() int {
+	if t.isDTZ {
+		return 1
+	}
+	return 2
+}
+
+func (t *tbTable) get(stm, f int) *tbPairsData {
+	if !t.hasPawns {
+		f = 0
+	}
+	return &t.items[stm%t.sides()][f]
+}
+
+// load reads the file on the first call and returns whether the table can be probed
+func (t *tbTable) load() bool {
+	t.once.Do(func() {
+		t.err = t.read()
+		if t.err != nil {
+			t.data = nil
+		}
+	})
+	return t.err == nil
+}
+
+func (t *tbTable) read() (err error) {
+	data, err := ioutil.ReadFile(t.path)
+	if err != nil {
+		return err
+	}
+	magic := tbWDLMagic
+	if t.isDTZ {
+		magic = tbDTZMagic
+	}
+	if len(data) < 5 || string(data[:4]) != string(magic) {
+		return fmt.Errorf("%s: not a syzygy tablebase", t.path)
+	}
+	t.data = data
+	defer func() {
+		// a truncated file would otherwise panic while probing
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: corrupt tablebase", t.path)
+		}
+	}()
+	return t.setup()
+}
+
+// setup parses the header of the file and sets the offsets of all tables
+func (t *tbTable) setup() error {
+	data := t.data
+	off := 4
+	const split, hasPawns = 1, 2
+	if t.hasPawns != (data[off]&hasPawns != 0) || (t.key != t.key2) != (data[off]&split != 0) {
+		return fmt.Errorf("%s: header doesn't match the file name", t.path)
+	}
+	off++
+
+	sides := 1
+	if !t.isDTZ && t.key != t.key2 {
+		sides = 2
+	}
+	maxFile := 0
+	if t.hasPawns {
+		maxFile = 3
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0 // pawns on both sides
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			*t.get(i, f) = tbPairsData{}
+		}
+		order := [2][2]int{{int(data[off] & 0xF), 0xF}, {int(data[off] >> 4), 0xF}}
+		if pp {
+			order[0][1] = int(data[off+1] & 0xF)
+			order[1][1] = int(data[off+1] >> 4)
+			off++
+		}
+		off++
+		for k := 0; k < t.pieceCount; k, off = k+1, off+1 {
+			for i := 0; i < sides; i++ {
+				if i == 0 {
+					t.get(i, f).pieces[k] = int(data[off] & 0xF)
+				} else {
+					t.get(i, f).pieces[k] = int(data[off] >> 4)
+				}
+			}
+		}
+		for i := 0; i < sides; i++ {
+			t.setGroups(t.get(i, f), order[i], f)
+		}
+	}
+	off += off & 1
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			off = t.setSizes(t.get(i, f), off)
+		}
+	}
+
+	if t.isDTZ {
+		off = t.setDTZMap(off, maxFile)
+	}
+
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.sparseIndex = off
+			off += d.sparseIndexSize * 6
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			d.blockLength = off
+			off += d.blockLengthSize * 2
+		}
+	}
+	for f := 0; f <= maxFile; f++ {
+		for i := 0; i < sides; i++ {
+			d := t.get(i, f)
+			off = (off + 0x3F) &^ 0x3F // 64 byte alignment
+			d.data = off
+			off += d.numBlocks * int(d.sizeofBlock)
+		}
+	}
+	if off > len(data) {
+		return fmt.Errorf("%s: file is truncated", t.path)
+	}
+	return nil
+}
+
+// setGroups splits the pieces into groups which are encoded together and computes the index factor of each group
+func (t *tbTable) setGroups(d *tbPairsData, order [2]int, f int) {
+	n := 0
+	firstLen := 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i-1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next := 1
+	freeSquares := 64 - d.groupLen[0]
+	if pp {
+		next = 2
+		freeSquares -= d.groupLen[1]
+	}
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		if k == order[0] { // leading pawns or pieces
+			d.groupIdx[0] = idx
+			if t.hasPawns {
+				idx *= tbLeadPawnsSize[d.groupLen[0]][f]
+			} else if t.hasUniquePieces {
+				idx *= 31332
+			} else {
+				idx *= 462
+			}
+		} else if k == order[1] { // remaining pawns
+			d.groupIdx[1] = idx
+			idx *= tbBinomial[d.groupLen[1]][48-d.groupLen[0]]
+		} else { // remaining pieces
+			d.groupIdx[next] = idx
+			idx *= tbBinomial[d.groupLen[next]][freeSquares]
+			freeSquares -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// setSizes reads the sizes and the huffman code of a table
+func (t *tbTable) setSizes(d *tbPairsData, off int) int {
+	data := t.data
+	d.flags = data[off]
+	off++
+	if d.flags&tbFlagSingleValue != 0 {
+		d.minSymLen = int(data[off])
+		return off + 1
+	}
+
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	tbSize := d.groupIdx[n]
+
+	d.sizeofBlock = 1 << data[off]
+	d.span = 1 << data[off+1]
+	d.sparseIndexSize = int((tbSize + d.span - 1) / d.span)
+	padding := int(data[off+2])
+	d.numBlocks = int(binary.LittleEndian.Uint32(data[off+3:]))
+	d.blockLengthSize = d.numBlocks + padding
+	d.maxSymLen = int(data[off+7])
+	d.minSymLen = int(data[off+8])
+	off += 9
+	d.lowestSym = off
+	d.base64 = make([]uint64, d.maxSymLen-d.minSymLen+1)
+
+	// canonical huffman code: longer symbols have lower values
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i+1] + uint64(t.lowestSym(d, i)) - uint64(t.lowestSym(d, i+1))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - d.minSymLen)
+	}
+
+	off += len(d.base64) * 2
+	d.symlen = make([]byte, binary.LittleEndian.Uint16(data[off:]))
+	off += 2
+	d.btree = off
+
+	visited := make([]bool, len(d.symlen))
+	for sym := range d.symlen {
+		if !visited[sym] {
+			d.symlen[sym] = t.setSymlen(d, sym, visited)
+		}
+	}
+	return off + len(d.symlen)*3 + len(d.symlen)&1
+}
+
+func (t *tbTable) lowestSym(d *tbPairsData, i int) uint16 {
+	return binary.LittleEndian.Uint16(t.data[d.lowestSym+2*i:])
+}
+
+// left and right return the symbols a symbol of the recursive pairing expands to
+func (t *tbTable) left(d *tbPairsData, sym int) int {
+	lr := t.data[d.btree+3*sym:]
+	return int(lr[1]&0xF)<<8 | int(lr[0])
+}
+
+func (t *tbTable) right(d *tbPairsData, sym int) int {
+	lr := t.data[d.btree+3*sym:]
+	return int(lr[2])<<4 | int(lr[1]>>4)
+}
+
+// setSymlen computes the number of values (minus one) a symbol expands to
+func (t *tbTable) setSymlen(d *tbPairsData, sym int, visited []bool) byte {
+	visited[sym] = true
+	sr := t.right(d, sym)
+	if sr == 0xFFF {
+		return 0
+	}
+	sl := t.left(d, sym)
+	if !visited[sl] {
+		d.symlen[sl] = t.setSymlen(d, sl, visited)
+	}
+	if !visited[sr] {
+		d.symlen[sr] = t.setSymlen(d, sr, visited)
+	}
+	return d.symlen[sl] + d.symlen[sr] + 1
+}
+
+// setDTZMap sets the offsets of the maps which convert the stored values of a DTZ table
+func (t *tbTable) setDTZMap(off int, maxFile int) int {
+	t.dtzMap = off
+	for f := 0; f <= maxFile; f++ {
+		d := t.get(0, f)
+		if d.flags&tbFlagMapped == 0 {
+			continue
+		}
+		if d.flags&tbFlagWide != 0 {
+			off += off & 1
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = (off-t.dtzMap)/2 + 1
+				off += 2*int(binary.LittleEndian.Uint16(t.data[off:])) + 2
+			}
+		} else {
+			for i := 0; i < 4; i++ {
+				d.mapIdx[i] = off - t.dtzMap + 1
+				off += int(t.data[off]) + 1
+			}
+		}
+	}
+	return off + off&1
+}
+
+// decompressPairs returns the stored value at index idx
+func (t *tbTable) decompressPairs(d *tbPairsData, idx uint64) int {
+	if d.flags&tbFlagSingleValue != 0 {
+		return d.minSymLen
+	}
+	data := t.data
+
+	// the sparse index stores the block and offset of every span-th value
+	k := idx / d.span
+	block := int(binary.LittleEndian.Uint32(data[d.sparseIndex+6*int(k):]))
+	offset := int(binary.LittleEndian.Uint16(data[d.sparseIndex+6*int(k)+4:]))
+	offset += int(idx%d.span) - int(d.span/2)
+
+	blockLength := func(block int) int {
+		return int(binary.LittleEndian.Uint16(data[d.blockLength+2*block:]))
+	}
+	for offset < 0 {
+		block--
+		offset += blockLength(block) + 1
+	}
+	for offset > blockLength(block) {
+		offset -= blockLength(block) + 1
+		block++
+	}
+
+	ptr := d.data + block*int(d.sizeofBlock)
+	buf64 := binary.BigEndian.Uint64(data[ptr:])
+	ptr += 8
+	buf64Size := 64
+	var sym int
+	for {
+		length := 0
+		for buf64 < d.base64[length] {
+			length++
+		}
+		sym = int((buf64 - d.base64[length]) >> uint(64-length-d.minSymLen))
+		sym += int(t.lowestSym(d, length))
+		if offset < int(d.symlen[sym])+1 {
+			break
+		}
+		offset -= int(d.symlen[sym]) + 1
+		length += d.minSymLen
+		buf64 <<= uint(length)
+		buf64Size -= length
+		if buf64Size <= 32 {
+			buf64Size += 32
+			buf64 |= uint64(binary.BigEndian.Uint32(data[ptr:])) << uint(64-buf64Size)
+			ptr += 4
+		}
+	}
+
+	// expand the symbol until we reach the value at offset
+	for d.symlen[sym] != 0 {
+		left := t.left(d, sym)
+		if offset < int(d.symlen[left])+1 {
+			sym = left
+		} else {
+			offset -= int(d.symlen[left]) + 1
+			sym = t.right(d, sym)
+		}
+	}
+	return t.left(d, sym)
+}
+
+// mapScore converts the stored value into a WDL result or a DTZ in plies
+func (t *tbTable) mapScore(f int, value int, wdl int) int {
+	if !t.isDTZ {
+		return value - 2
+	}
+	wdlMap := [5]int{1, 3, 0, 2, 0}
+	d := t.get(0, f)
+	if d.flags&tbFlagMapped != 0 {
+		idx := d.mapIdx[wdlMap[wdl+2]] + value
+		if d.flags&tbFlagWide != 0 {
+			value = int(binary.LittleEndian.Uint16(t.data[t.dtzMap+2*idx:]))
+		} else {
+			value = int(t.data[t.dtzMap+idx])
+		}
+	}
+	if (wdl == TB_WIN && d.flags&tbFlagWinPlies == 0) || (wdl == TB_LOSS && d.flags&tbFlagLossPlies == 0) ||
+		wdl == TB_CURSED_WIN || wdl == TB_BLESSED_LOSS {
+		value *= 2
+	}
+	return value + 1
+}
+
+// tbPosition is a position reduced to what is needed to compute its index in a table
+type tbPosition struct {
+	pieces      [64]int // piece code (color * 8 + piece type) on every square with a1 = 0 and 0 for empty squares
+	blackToMove bool
+	key         string
+}
+
+// tbPosition returns the position as used by the tablebase files
+func (board *Board) tbPosition() tbPosition {
+	position := tbPosition{blackToMove: board.IsBlacksTurn, key: board.tbMaterialKey()}
+	for _, piece := range board.pieces {
+		if piece.posB == 0 || piece.id == 0 {
+			continue
+		}
+		code := tbPieceType(piece.pieceType)
+		if piece.isBlack {
+			code += 8
+		}
+		position.pieces[piece.pos^56] = code
+	}
+	return position
+}
+
+// probeTable returns the value stored in the WDL or DTZ table of the position
+func (board *Board) probeTable(isDTZ bool, wdl int) (int, int) {
+	if board.numberOfPieces() == 2 {
+		return TB_DRAW, probeOK
+	}
+	tables := syzygy.wdl
+	if isDTZ {
+		tables = syzygy.dtz
+	}
+	position := board.tbPosition()
+	table, ok := tables[position.key]
+	if !ok || !table.load() {
+		return 0, probeFail
+	}
+	d, tbFile, idx, state := table.index(&position)
+	if state != probeOK {
+		return 0, state
+	}
+	return table.mapScore(tbFile, table.decompressPairs(d, idx), wdl), probeOK
+}
+
+// index returns the compressed table, the file of the leading pawn and the index of the position in the table
+func (t *tbTable) index(position *tbPosition) (*tbPairsData, int, uint64, int) {
+	// the tables are stored for white being the stronger side and symmetric tables only for white to move
+	stm := 0
+	if position.blackToMove {
+		stm = 1
+	}
+	symmetricBlackToMove := t.key == t.key2 && position.blackToMove
+	blackStronger := position.key != t.key
+	flipColor, flipSquares := 0, 0
+	if symmetricBlackToMove || blackStronger {
+		flipColor, flipSquares = 8, 56
+		stm ^= 1
+	}
+
+	var squares, pieces [TB_PIECES]int
+	size, leadPawnsCnt, tbFile := 0, 0, 0
+	leadPawn := -1
+	if t.hasPawns {
+		// the first piece of a table with pawns is a pawn of the leading color
+		leadPawn = t.get(0, 0).pieces[0] ^ flipColor
+		for sq := 0; sq < 64; sq++ {
+			if position.pieces[sq] == leadPawn {
+				squares[size] = sq ^ flipSquares
+				size++
+			}
+		}
+		leadPawnsCnt = size
+		maxI := 0
+		for i := 1; i < leadPawnsCnt; i++ {
+			if tbMapPawns[squares[i]] > tbMapPawns[squares[maxI]] {
+				maxI = i
+			}
+		}
+		squares[0], squares[maxI] = squares[maxI], squares[0]
+		tbFile = min(squares[0]&7, 7-squares[0]&7)
+	}
+
+	// DTZ tables only store one side to move
+	if t.isDTZ {
+		flags := t.get(stm, tbFile).flags
+		if int(flags&tbFlagStm) != stm && !(t.key == t.key2 && !t.hasPawns) {
+			return nil, tbFile, 0, probeChangeStm
+		}
+	}
+
+	for sq := 0; sq < 64; sq++ {
+		if code := position.pieces[sq]; code != 0 && code != leadPawn {
+			squares[size] = sq ^ flipSquares
+			pieces[size] = code ^ flipColor
+			size++
+		}
+	}
+
+	d := t.get(stm, tbFile)
+
+	// order the pieces like in the table
+	for i := leadPawnsCnt; i < size-1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// mirror such that the leading piece is on the files a-d
+	if squares[0]&7 > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	var idx uint64
+	if t.hasPawns {
+		idx = tbLeadPawnIdx[leadPawnsCnt][squares[0]]
+		sortSquares(squares[1:leadPawnsCnt], func(sq int) int { return tbMapPawns[sq] })
+		for i := 1; i < leadPawnsCnt; i++ {
+			idx += tbBinomial[i][tbMapPawns[squares[i]]]
+		}
+	} else {
+		idx = encodeLeadingPieces(t, d, squares[:size])
+	}
+
+	// encode the remaining groups in ascending order of their squares
+	idx *= d.groupIdx[0]
+	groupStart := d.groupLen[0]
+	remainingPawns := t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[groupStart : groupStart+d.groupLen[next]]
+		sortSquares(group, func(sq int) int { return sq })
+		n := uint64(0)
+		for i, sq := range group {
+			adjust := 0
+			for _, s := range squares[:groupStart] {
+				if sq > s {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += tbBinomial[i+1][sq-adjust]
+		}
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		groupStart += d.groupLen[next]
+	}
+	return d, tbFile, idx, probeOK
+}
+
+// encodeLeadingPieces returns the index of the leading group of a table without pawns
+func encodeLeadingPieces(table *tbTable, d *tbPairsData, squares []int) uint64 {
+	// mirror such that the leading piece is below the fifth rank
+	if squares[0]>>3 > 3 {
+		for i := range squares {
+			squares[i] ^= 56
+		}
+	}
+	// mirror at the a1-h8 diagonal such that the first piece of the leading group which isn't on it is below it
+	for i := 0; i < d.groupLen[0]; i++ {
+		if offA1H8(squares[i]) == 0 {
+			continue
+		}
+		if offA1H8(squares[i]) > 0 {
+			for j := i; j < len(squares); j++ {
+				squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+			}
+		}
+		break
+	}
+
+	if !table.hasUniquePieces {
+		return uint64(tbMapKK[tbMapA1D1D4[squares[0]]][squares[1]])
+	}
+	// the three leading pieces are encoded together
+	adjust1, adjust2 := 0, 0
+	if squares[1] > squares[0] {
+		adjust1++
+	}
+	if squares[2] > squares[0] {
+		adjust2++
+	}
+	if squares[2] > squares[1] {
+		adjust2++
+	}
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	if offA1H8(s0) != 0 {
+		return uint64((tbMapA1D1D4[s0]*63+(s1-adjust1))*62 + s2 - adjust2)
+	} else if offA1H8(s1) != 0 {
+		return uint64((6*63+(s0>>3)*28+tbMapB1H1H7[s1])*62 + s2 - adjust2)
+	} else if offA1H8(s2) != 0 {
+		return uint64(6*63*62 + 4*28*62 + (s0>>3)*7*28 + ((s1>>3)-adjust1)*28 + tbMapB1H1H7[s2])
+	}
+	return uint64(6*63*62 + 4*28*62 + 4*7*28 + (s0>>3)*7*6 + ((s1>>3)-adjust1)*6 + (s2 >> 3) - adjust2)
+}
+
+// sortSquares is a stable insertion sort of the few squares of a group by key
+func sortSquares(squares []int, key func(sq int) int) {
+	for i := 1; i < len(squares); i++ {
+		for j := i; j > 0 && key(squares[j]) < key(squares[j-1]); j-- {
+			squares[j], squares[j-1] = squares[j-1], squares[j]
+		}
+	}
+}
+
+// isZeroing returns whether the move resets the 50 move counter
+func (board *Board) isZeroing(move *Move) bool {
+	return move.captureId != 0 || board.pieces[move.PieceId].pieceType == PAWN
+}
+
+// tbSearch resolves captures (and pawn moves if checkZeroing is set) before probing the WDL table
+// as the tables don't store positions with en passant rights and values after zeroing moves can be wrong
+func (board *Board) tbSearch(checkZeroing bool) (int, int) {
+	moves := board.getPossibleMoves()
+	bestValue := TB_LOSS
+	moveCount := 0
+	for _, move := range moves {
+		if move.captureId == 0 && (!checkZeroing || board.pieces[move.PieceId].pieceType != PAWN) {
+			continue
+		}
+		moveCount++
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&move)
+		value, state := board.tbSearch(false)
+		board.reverseMove(&move, &boardPrimitives)
+		if state == probeFail {
+			return TB_DRAW, probeFail
+		}
+		if -value > bestValue {
+			bestValue = -value
+			if bestValue >= TB_WIN {
+				return bestValue, probeZeroingBestMove
+			}
+		}
+	}
+
+	noMoreMoves := moveCount > 0 && moveCount == len(moves)
+	value := bestValue
+	if !noMoreMoves {
+		var state int
+		value, state = board.probeTable(false, TB_DRAW)
+		if state == probeFail {
+			return TB_DRAW, probeFail
+		}
+	}
+	if bestValue >= value {
+		if bestValue > TB_DRAW || noMoreMoves {
+			return bestValue, probeZeroingBestMove
+		}
+		return bestValue, probeOK
+	}
+	return value, probeOK
+}
+
+// dtzBeforeZeroing returns the DTZ of a position in which the best move is a zeroing move
+func dtzBeforeZeroing(wdl int) int {
+	switch wdl {
+	case TB_WIN:
+		return 1
+	case TB_CURSED_WIN:
+		return 101
+	case TB_BLESSED_LOSS:
+		return -101
+	case TB_LOSS:
+		return -1
+	}
+	return 0
+}
+
+func signOf(x int) int {
+	if x > 0 {
+		return 1
+	} else if x < 0 {
+		return -1
+	}
+	return 0
+}
+
+// canProbeSyzygy returns whether tablebases are loaded which cover the position. Positions with castle rights aren't in the tables.
+func (board *Board) canProbeSyzygy() bool {
+	return syzygy != nil && board.numberOfPieces() <= syzygy.maxPieces &&
+		!board.white_castle_king && !board.white_castle_queen && !board.black_castle_king && !board.black_castle_queen
+}
+
+// ProbeWDL returns the tablebase result (TB_LOSS to TB_WIN) of the position from the perspective of the side to move
+// and false if the position isn't covered by the loaded tablebases
+func (board *Board) ProbeWDL() (int, bool) {
+	if !board.canProbeSyzygy() {
+		return 0, false
+	}
+	wdl, state := board.tbSearch(false)
+	return wdl, state != probeFail
+}
+
+// ProbeDTZ returns the distance to the next zeroing move (capture or pawn move) in plies with the sign of the result
+// for the side to move, 0 for draws. A DTZ of more than 100 plies means a cursed win or blessed loss.
+func (board *Board) ProbeDTZ() (int, bool) {
+	if !board.canProbeSyzygy() {
+		return 0, false
+	}
+	dtz, state := board.probeDTZ()
+	return dtz, state != probeFail
+}
+
+func (board *Board) probeDTZ() (int, int) {
+	wdl, state := board.tbSearch(true)
+	if state == probeFail || wdl == TB_DRAW {
+		return 0, state
+	}
+	if state == probeZeroingBestMove {
+		return dtzBeforeZeroing(wdl), probeOK
+	}
+	dtz, state := board.probeTable(true, wdl)
+	if state == probeFail {
+		return 0, probeFail
+	}
+	if state != probeChangeStm {
+		if wdl == TB_CURSED_WIN || wdl == TB_BLESSED_LOSS {
+			dtz += 100
+		}
+		return dtz * signOf(wdl), probeOK
+	}
+
+	// the table stores the other side to move so we search one ply for the best DTZ
+	minDTZ := 0xFFFF
+	for _, move := range board.getPossibleMoves() {
+		zeroing := board.isZeroing(&move)
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&move)
+		if zeroing {
+			var value int
+			value, state = board.tbSearch(false)
+			dtz = -dtzBeforeZeroing(value)
+		} else {
+			dtz, state = board.probeDTZ()
+			dtz = -dtz
+		}
+		// a mate gets a DTZ of 1
+		if dtz == 1 && board.check && len(board.getPossibleMoves()) == 0 {
+			minDTZ = 1
+		}
+		if !zeroing {
+			dtz += signOf(dtz)
+		}
+		if dtz < minDTZ && signOf(dtz) == signOf(wdl) {
+			minDTZ = dtz
+		}
+		board.reverseMove(&move, &boardPrimitives)
+		if state == probeFail {
+			return 0, probeFail
+		}
+	}
+	if minDTZ == 0xFFFF {
+		return -1, probeOK
+	}
+	return minDTZ, probeOK
+}
+
+// probeSyzygyRoot returns the tablebase optimal move: the fastest zeroing move when winning, the longest resistance
+// when losing and otherwise a drawing move. The second return value is the WDL result of the position.
+func (board *Board) probeSyzygyRoot() (Move, int, bool) {
+	if !board.canProbeSyzygy() {
+		return Move{}, 0, false
+	}
+	var bestMove Move
+	bestRank, bestDTZ := -1<<31, 0
+	for _, move := range board.getPossibleMoves() {
+		if !board.isRootMoveSearched(&move) {
+			continue
+		}
+		zeroing := board.isZeroing(&move)
+		boardPrimitives := board.getBoardPrimitives()
+		board.Move(&move)
+		var dtz, state int
+		if zeroing {
+			var wdl int
+			wdl, state = board.tbSearch(false)
+			dtz = dtzBeforeZeroing(-wdl)
+		} else {
+			dtz, state = board.probeDTZ()
+			dtz = -dtz
+			dtz += signOf(dtz)
+		}
+		if board.check && dtz == 2 && len(board.getPossibleMoves()) == 0 {
+			dtz = 1
+		}
+		board.reverseMove(&move, &boardPrimitives)
+		if state == probeFail {
+			return Move{}, 0, false
+		}
+		// wins within the 50 move rule are ranked by DTZ and losses by how long they can be delayed
+		rank := 0
+		if dtz > 0 {
+			rank = max(1, 1000-dtz-board.halfMoves)
+		} else if dtz < 0 {
+			rank = min(-1, -1000-dtz+board.halfMoves)
+		}
+		if rank > bestRank {
+			bestRank, bestDTZ, bestMove = rank, dtz, move
+		}
+	}
+	if bestMove.PieceId == 0 {
+		return Move{}, 0, false
+	}
+	wdl := TB_DRAW
+	switch {
+	case bestDTZ > 0 && bestDTZ+board.halfMoves <= 100:
+		wdl = TB_WIN
+	case bestDTZ > 0:
+		wdl = TB_CURSED_WIN
+	case bestDTZ < 0 && -bestDTZ+board.halfMoves <= 100:
+		wdl = TB_LOSS
+	case bestDTZ < 0:
+		wdl = TB_BLESSED_LOSS
+	}
+	return bestMove, wdl, true
+}
+
+// syzygyScore converts a WDL result of the side to move into a score from whites perspective
+func (board *Board) syzygyScore(wdl int, currentDepth int) float64 {
+	score := 0.0
+	switch wdl {
+	case TB_WIN:
+		score = SYZYGY_WIN_SCORE - float64(currentDepth)
+	case TB_LOSS:
+		score = -SYZYGY_WIN_SCORE + float64(currentDepth)
+	case TB_CURSED_WIN:
+		score = 1
+	case TB_BLESSED_LOSS:
+		score = -1
+	}
+	if board.IsBlacksTurn {
+		return -score
+	}
+	return score
+}
+
+// probeSyzygyScore returns the tablebase score of a position inside the search
+func (board *Board) probeSyzygyScore(currentDepth int) (float64, bool) {
+	wdl, ok := board.ProbeWDL()
+	if !ok {
+		return 0, false
+	}
+	return board.syzygyScore(wdl, currentDepth), true
+}
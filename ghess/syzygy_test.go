@@ -0,0 +1,48 @@
+package ghess
+
+// directory of the official Syzygy tablebases used by the probing tests (see the README in it)
+const syzygyTestPath = "testdata/syzygy"
+
+// syzygyStruct is a position with its known tablebase result from the perspective of the side to move. A dtz of 0
+// for a win or a loss only checks the sign of the DTZ.
+type syzygyStruct struct {
+	fen string
+	wdl int
+	dtz int
+}
+
+var syzygyTests = []syzygyStruct{
+	{"8/8/4k3/8/8/8/8/3QK3 w - - 0 1", TB_WIN, 0},
+	{"8/8/4k3/8/8/8/8/3QK3 b - - 0 1", TB_LOSS, 0},
+	{"3qk3/8/8/8/8/4K3/8/8 b - - 0 1", TB_WIN, 0},
+	// the black king captures the rook
+	{"8/8/8/8/8/8/1kR5/7K b - - 0 1", TB_DRAW, 0},
+	{"8/8/4k3/8/8/8/8/2B1K3 w - - 0 1", TB_DRAW, 0},
+	// the king in front of its pawn on the 6th rank wins with either side to move
+	{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", TB_WIN, 0},
+	{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", TB_LOSS, 0},
+	// the promotion is the zeroing move
+	{"8/4P3/8/8/8/k7/8/4K3 w - - 0 1", TB_WIN, 1},
+	// the rook pawn doesn't win against the king in the corner
+	{"k7/8/8/8/8/8/P7/K7 w - - 0 1", TB_DRAW, 0},
+	// the e pawn promotes with check long before the a pawn
+	{"7k/p7/3K4/4P3/8/8/8/8 w - - 0 1", TB_WIN, 1},
+	{"7k/p7/3K4/4P3/8/8/8/8 b - - 0 1", TB_LOSS, 0},
+	{"k7/p7/8/8/4K3/8/8/7R w - - 0 1", TB_WIN, 0},
+	{"k7/p7/8/8/4K3/8/8/7R b - - 0 1", TB_LOSS, 0},
+}
+
+// syzygyIndexStruct describes a table by its name and the order of the pieces as stored in the file
+// (piece codes are color * 8 + piece type with pawn = 1, ..., king = 6)
+type syzygyIndexStruct struct {
+	code   string
+	pieces []int
+	order  [2]int
+}
+
+var syzygyIndexTests = []syzygyIndexStruct{
+	{"KRvK", []int{6, 4, 14}, [2]int{0, 0xF}},
+	{"KRRvK", []int{6, 14, 4, 4}, [2]int{0, 0xF}},
+	{"KPvK", []int{1, 6, 14}, [2]int{0, 0xF}},
+	{"KPvKP", []int{1, 9, 6, 14}, [2]int{0, 1}},
+}
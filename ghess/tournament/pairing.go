@@ -0,0 +1,145 @@
+package tournament
+
+import "sort"
+
+// BYE is the opponent of a player who doesn't play in a round
+const BYE = -1
+
+// Pairing is a game of a round between the players with the given indices. Black is BYE if White has a bye.
+type Pairing struct {
+	White, Black int
+}
+
+// RoundRobin returns the rounds in which each of the n players plays every other player once (Berger tables).
+// With an odd number of players one player sits out in every round.
+func RoundRobin(n int) [][]Pairing {
+	if n < 2 {
+		return nil
+	}
+	players := make([]int, 0, n+1)
+	for i := 0; i < n; i++ {
+		players = append(players, i)
+	}
+	if n%2 == 1 {
+		players = append(players, BYE)
+	}
+	size := len(players)
+	rounds := make([][]Pairing, 0, size-1)
+	for round := 0; round < size-1; round++ {
+		pairings := []Pairing{}
+		for i := 0; i < size/2; i++ {
+			white, black := players[i], players[size-1-i]
+			// the first player stays in its place and changes colours every round, the others alternate per table
+			if (i == 0 && round%2 == 1) || (i > 0 && i%2 == 1) {
+				white, black = black, white
+			}
+			if white == BYE || black == BYE {
+				continue
+			}
+			pairings = append(pairings, Pairing{White: white, Black: black})
+		}
+		rounds = append(rounds, pairings)
+		// rotate all players but the first one
+		last := players[size-1]
+		copy(players[2:], players[1:size-1])
+		players[1] = last
+	}
+	return rounds
+}
+
+// colourHistory is the colour balance (whites minus blacks) and the last colour of a player
+type colourHistory struct {
+	balance   int
+	lastWhite bool
+	played    bool
+}
+
+// swissRound pairs players with the same or similar score who haven't met yet. ranking is the order of the players
+// by their standing, played contains the pairs of players who already met and hadBye the players who had a bye.
+func swissRound(ranking []int, played map[[2]int]bool, hadBye map[int]bool, colours map[int]colourHistory) []Pairing {
+	pairings := []Pairing{}
+	order := append([]int{}, ranking...)
+	if len(order)%2 == 1 {
+		// the lowest ranked player without a bye sits out
+		bye := len(order) - 1
+		for i := len(order) - 1; i >= 0; i-- {
+			if !hadBye[order[i]] {
+				bye = i
+				break
+			}
+		}
+		pairings = append(pairings, Pairing{White: order[bye], Black: BYE})
+		order = append(order[:bye], order[bye+1:]...)
+	}
+	pairs, ok := pairTopDown(order, played)
+	if !ok {
+		// every pairing repeats a game so the players are paired by their ranking
+		pairs = nil
+		for i := 0; i+1 < len(order); i += 2 {
+			pairs = append(pairs, [2]int{order[i], order[i+1]})
+		}
+	}
+	for _, pair := range pairs {
+		pairings = append(pairings, assignColours(pair[0], pair[1], colours))
+	}
+	return pairings
+}
+
+// pairTopDown pairs the highest ranked player with the next player it hasn't played and backtracks if the remaining
+// players can't be paired
+func pairTopDown(order []int, played map[[2]int]bool) ([][2]int, bool) {
+	if len(order) == 0 {
+		return nil, true
+	}
+	first := order[0]
+	for i := 1; i < len(order); i++ {
+		opponent := order[i]
+		if played[pairKey(first, opponent)] {
+			continue
+		}
+		rest := make([]int, 0, len(order)-2)
+		rest = append(rest, order[1:i]...)
+		rest = append(rest, order[i+1:]...)
+		if pairs, ok := pairTopDown(rest, played); ok {
+			return append([][2]int{{first, opponent}}, pairs...), true
+		}
+	}
+	return nil, false
+}
+
+// assignColours gives white to the player who had black more often or, if both are balanced, to the player who had
+// black in the last game. The higher ranked player a gets white otherwise.
+func assignColours(a, b int, colours map[int]colourHistory) Pairing {
+	ca, cb := colours[a], colours[b]
+	switch {
+	case ca.balance != cb.balance:
+		if ca.balance > cb.balance {
+			return Pairing{White: b, Black: a}
+		}
+	case ca.played && ca.lastWhite:
+		return Pairing{White: b, Black: a}
+	case !ca.played && cb.played && !cb.lastWhite:
+		return Pairing{White: b, Black: a}
+	}
+	return Pairing{White: a, Black: b}
+}
+
+// pairKey identifies two players independent of their colours
+func pairKey(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
+
+// sortByRanking returns the player indices sorted by the less function which is stable for equal players
+func sortByRanking(n int, less func(a, b int) bool) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return less(order[i], order[j])
+	})
+	return order
+}
@@ -0,0 +1,433 @@
+// Package tournament runs round robin and Swiss tournaments between engines and computes the crosstable and the
+// standings with Sonneborn-Berger and Buchholz tie-breaks.
+package tournament
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// ROUND_ROBIN and SWISS are the formats of a tournament
+const (
+	ROUND_ROBIN = "roundrobin"
+	SWISS       = "swiss"
+)
+
+// BYE_POINTS are the points of a player who has a bye in a Swiss round
+const BYE_POINTS = 1.0
+
+// Player is a participant of a tournament. NewEngine is called for every game such that games can run in parallel.
+type Player struct {
+	Name      string
+	NewEngine func() (ghess.Engine, error)
+}
+
+// Options configure a tournament
+type Options struct {
+	Format      string            // ROUND_ROBIN or SWISS
+	Rounds      int               // cycles of a round robin with alternating colours or rounds of a Swiss tournament
+	Openings    []ghess.Opening   // start positions used round by round (default: start position)
+	Game        ghess.GameOptions // time control and adjudication of every game (the opening is ignored)
+	Concurrency int               // number of games played in parallel
+	OnGame      func(GameRecord)  // called after every game (may be nil)
+}
+
+// GameRecord is a finished game of a round between the players with the given indices
+type GameRecord struct {
+	Round        int // starting at 1
+	White, Black int
+	Game         ghess.Game
+}
+
+// Tournament is a finished tournament
+type Tournament struct {
+	Players []Player
+	Format  string
+	Rounds  [][]Pairing
+	Games   []GameRecord
+}
+
+// Standing is the result of a player in the tournament
+type Standing struct {
+	Player              int
+	Name                string
+	Games               int
+	Wins, Draws, Losses int
+	Points              float64
+	SonnebornBerger     float64 // sum of the points of the opponents weighted by the score against them
+	Buchholz            float64 // sum of the points of the opponents
+}
+
+// configuredEngine is an engine with its own search limits which replace the limits of the tournament
+type configuredEngine struct {
+	ghess.Engine
+	name   string
+	limits ghess.SearchLimits
+}
+
+func (engine *configuredEngine) Name() string {
+	return engine.name
+}
+
+func (engine *configuredEngine) Search(board *ghess.Board, limits ghess.SearchLimits) ghess.SearchResult {
+	if engine.limits != (ghess.SearchLimits{}) {
+		limits = engine.limits
+	}
+	return engine.Engine.Search(board, limits)
+}
+
+// ParsePlayer reads a player given as "[name=]engine[:key=value,...]" where engine is a built-in engine (random,
+// captureRandom, checkCaptureRandom, alphaBeta or mcts). The keys depth, nodes and movetime (ms) fix the search
+// limits of the player and threads and exploration configure the mcts engine.
+func ParsePlayer(spec string) (Player, error) {
+	name := ""
+	if parts := strings.SplitN(spec, "=", 2); len(parts) == 2 && !strings.Contains(parts[0], ":") {
+		name, spec = parts[0], parts[1]
+	}
+	engineName := spec
+	settings := ""
+	if parts := strings.SplitN(spec, ":", 2); len(parts) == 2 {
+		engineName, settings = parts[0], parts[1]
+	}
+	if name == "" {
+		name = spec
+	}
+	if _, err := ghess.NewEngine(engineName); err != nil {
+		return Player{}, err
+	}
+	limits := ghess.SearchLimits{}
+	threads := 0
+	exploration := 0.0
+	for _, setting := range strings.Split(settings, ",") {
+		if setting == "" {
+			continue
+		}
+		parts := strings.SplitN(setting, "=", 2)
+		if len(parts) != 2 {
+			return Player{}, fmt.Errorf("setting %q of %s is not of the form key=value", setting, name)
+		}
+		var err error
+		switch parts[0] {
+		case "depth":
+			limits.Depth, err = strconv.Atoi(parts[1])
+		case "nodes":
+			limits.Nodes, err = strconv.Atoi(parts[1])
+		case "movetime":
+			limits.MoveTime, err = strconv.Atoi(parts[1])
+		case "threads":
+			threads, err = strconv.Atoi(parts[1])
+		case "exploration":
+			exploration, err = strconv.ParseFloat(parts[1], 64)
+		default:
+			return Player{}, fmt.Errorf("unknown setting %s of %s", parts[0], name)
+		}
+		if err != nil {
+			return Player{}, fmt.Errorf("invalid value of %s for %s: %w", parts[0], name, err)
+		}
+	}
+	newEngine := func() (ghess.Engine, error) {
+		engine, err := ghess.NewEngine(engineName)
+		if err != nil {
+			return nil, err
+		}
+		if mcts, ok := engine.(*ghess.MCTSEngine); ok {
+			if threads > 0 {
+				mcts.Threads = threads
+			}
+			if exploration > 0 {
+				mcts.Exploration = exploration
+			}
+		}
+		return &configuredEngine{Engine: engine, name: name, limits: limits}, nil
+	}
+	return Player{Name: name, NewEngine: newEngine}, nil
+}
+
+// Run plays all rounds of the tournament. Swiss rounds are paired by the standings after the previous round.
+func Run(players []Player, options Options) (*Tournament, error) {
+	if len(players) < 2 {
+		return nil, fmt.Errorf("a tournament needs at least two players")
+	}
+	names := map[string]bool{}
+	for _, player := range players {
+		if names[player.Name] {
+			return nil, fmt.Errorf("the player name %s is used twice", player.Name)
+		}
+		names[player.Name] = true
+	}
+	if options.Format != ROUND_ROBIN && options.Format != SWISS {
+		return nil, fmt.Errorf("unknown tournament format %s", options.Format)
+	}
+	rounds := options.Rounds
+	if rounds <= 0 {
+		rounds = 1
+		if options.Format == SWISS {
+			rounds = int(math.Ceil(math.Log2(float64(len(players)))))
+		}
+	}
+	openings := options.Openings
+	if len(openings) == 0 {
+		openings = []ghess.Opening{{Fen: ghess.START_FEN}}
+	}
+
+	t := &Tournament{Players: players, Format: options.Format}
+	var schedule [][]Pairing
+	if options.Format == ROUND_ROBIN {
+		for cycle := 0; cycle < rounds; cycle++ {
+			for _, round := range RoundRobin(len(players)) {
+				pairings := append([]Pairing{}, round...)
+				if cycle%2 == 1 {
+					for i := range pairings {
+						pairings[i].White, pairings[i].Black = pairings[i].Black, pairings[i].White
+					}
+				}
+				schedule = append(schedule, pairings)
+			}
+		}
+		rounds = len(schedule)
+	}
+	for round := 0; round < rounds; round++ {
+		var pairings []Pairing
+		if options.Format == ROUND_ROBIN {
+			pairings = schedule[round]
+		} else {
+			pairings = t.swissPairings()
+		}
+		t.Rounds = append(t.Rounds, pairings)
+		if err := t.playRound(round+1, pairings, openings[round%len(openings)], options); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
+}
+
+// playRound plays the games of a round in parallel
+func (t *Tournament) playRound(round int, pairings []Pairing, opening ghess.Opening, options Options) error {
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	gameOptions := options.Game
+	gameOptions.Opening = opening
+	games := make(chan Pairing)
+	go func() {
+		for _, pairing := range pairings {
+			if pairing.Black != BYE {
+				games <- pairing
+			}
+		}
+		close(games)
+	}()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pairing := range games {
+				white, err := t.Players[pairing.White].NewEngine()
+				var black ghess.Engine
+				if err == nil {
+					black, err = t.Players[pairing.Black].NewEngine()
+				}
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					continue
+				}
+				game := ghess.PlayGame(white, black, gameOptions)
+				game.White, game.Black = t.Players[pairing.White].Name, t.Players[pairing.Black].Name
+				record := GameRecord{Round: round, White: pairing.White, Black: pairing.Black, Game: game}
+				mu.Lock()
+				t.Games = append(t.Games, record)
+				if options.OnGame != nil {
+					options.OnGame(record)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// swissPairings pairs the next round by the current standings
+func (t *Tournament) swissPairings() []Pairing {
+	standings := t.Standings()
+	ranking := make([]int, len(standings))
+	for i, standing := range standings {
+		ranking[i] = standing.Player
+	}
+	played := map[[2]int]bool{}
+	colours := map[int]colourHistory{}
+	for _, record := range t.Games {
+		played[pairKey(record.White, record.Black)] = true
+		white, black := colours[record.White], colours[record.Black]
+		colours[record.White] = colourHistory{balance: white.balance + 1, lastWhite: true, played: true}
+		colours[record.Black] = colourHistory{balance: black.balance - 1, lastWhite: false, played: true}
+	}
+	hadBye := map[int]bool{}
+	for _, round := range t.Rounds {
+		for _, pairing := range round {
+			if pairing.Black == BYE {
+				hadBye[pairing.White] = true
+			}
+		}
+	}
+	return swissRound(ranking, played, hadBye, colours)
+}
+
+// Standings returns the standings sorted by points and the tie-breaks. Round robins are decided by Sonneborn-Berger
+// before Buchholz and Swiss tournaments by Buchholz before Sonneborn-Berger.
+func (t *Tournament) Standings() []Standing {
+	standings := make([]Standing, len(t.Players))
+	for i, player := range t.Players {
+		standings[i] = Standing{Player: i, Name: player.Name}
+	}
+	for _, round := range t.Rounds {
+		for _, pairing := range round {
+			if pairing.Black == BYE {
+				standings[pairing.White].Points += BYE_POINTS
+			}
+		}
+	}
+	for _, record := range t.Games {
+		score := record.Game.WhiteScore()
+		standings[record.White].addGame(score)
+		standings[record.Black].addGame(1 - score)
+	}
+	for _, record := range t.Games {
+		score := record.Game.WhiteScore()
+		white, black := &standings[record.White], &standings[record.Black]
+		white.Buchholz += black.Points
+		black.Buchholz += white.Points
+		white.SonnebornBerger += score * black.Points
+		black.SonnebornBerger += (1 - score) * white.Points
+	}
+	tieBreaks := func(s Standing) [2]float64 {
+		if t.Format == SWISS {
+			return [2]float64{s.Buchholz, s.SonnebornBerger}
+		}
+		return [2]float64{s.SonnebornBerger, s.Buchholz}
+	}
+	order := sortByRanking(len(standings), func(a, b int) bool {
+		sa, sb := standings[a], standings[b]
+		if sa.Points != sb.Points {
+			return sa.Points > sb.Points
+		}
+		ta, tb := tieBreaks(sa), tieBreaks(sb)
+		if ta[0] != tb[0] {
+			return ta[0] > tb[0]
+		}
+		return ta[1] > tb[1]
+	})
+	sorted := make([]Standing, len(order))
+	for i, player := range order {
+		sorted[i] = standings[player]
+	}
+	return sorted
+}
+
+// addGame counts a game with the given score
+func (s *Standing) addGame(score float64) {
+	s.Games++
+	s.Points += score
+	switch score {
+	case 1:
+		s.Wins++
+	case 0:
+		s.Losses++
+	default:
+		s.Draws++
+	}
+}
+
+// Table returns the standings as a text table
+func (t *Tournament) Table() string {
+	var sb strings.Builder
+	width := t.nameWidth()
+	sb.WriteString(fmt.Sprintf("%4s  %-*s %6s %5s %4s %4s %4s %7s %8s\n", "Rank", width, "Name", "Points", "Games",
+		"W", "D", "L", "SB", "Buchholz"))
+	for i, s := range t.Standings() {
+		sb.WriteString(fmt.Sprintf("%4d  %-*s %6.1f %5d %4d %4d %4d %7.2f %8.2f\n", i+1, width, s.Name, s.Points,
+			s.Games, s.Wins, s.Draws, s.Losses, s.SonnebornBerger, s.Buchholz))
+	}
+	return sb.String()
+}
+
+// Crosstable returns the points of every player against every other player in the order of the standings. Pairs
+// who didn't play are marked with a dot.
+func (t *Tournament) Crosstable() string {
+	standings := t.Standings()
+	n := len(t.Players)
+	points := make([][]float64, n)
+	played := make([][]bool, n)
+	for i := range points {
+		points[i] = make([]float64, n)
+		played[i] = make([]bool, n)
+	}
+	for _, record := range t.Games {
+		score := record.Game.WhiteScore()
+		points[record.White][record.Black] += score
+		points[record.Black][record.White] += 1 - score
+		played[record.White][record.Black] = true
+		played[record.Black][record.White] = true
+	}
+
+	var sb strings.Builder
+	width := t.nameWidth()
+	sb.WriteString(fmt.Sprintf("%3s  %-*s %6s", "", width, "", "Points"))
+	for i := range standings {
+		sb.WriteString(fmt.Sprintf(" %5d", i+1))
+	}
+	sb.WriteString("\n")
+	for i, s := range standings {
+		sb.WriteString(fmt.Sprintf("%3d  %-*s %6.1f", i+1, width, s.Name, s.Points))
+		for _, opponent := range standings {
+			switch {
+			case opponent.Player == s.Player:
+				sb.WriteString(fmt.Sprintf(" %5s", "X"))
+			case !played[s.Player][opponent.Player]:
+				sb.WriteString(fmt.Sprintf(" %5s", "."))
+			default:
+				sb.WriteString(fmt.Sprintf(" %5.1f", points[s.Player][opponent.Player]))
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// PGN returns all games of the tournament round by round
+func (t *Tournament) PGN(event string) string {
+	var sb strings.Builder
+	for round := 1; round <= len(t.Rounds); round++ {
+		for _, record := range t.Games {
+			if record.Round == round {
+				sb.WriteString(record.Game.PGN(event, round))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// nameWidth returns the length of the longest player name
+func (t *Tournament) nameWidth() int {
+	width := 4
+	for _, player := range t.Players {
+		if len(player.Name) > width {
+			width = len(player.Name)
+		}
+	}
+	return width
+}
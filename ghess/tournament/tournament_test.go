@@ -0,0 +1,187 @@
+package tournament
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+func TestRoundRobin(t *testing.T) {
+	for n := 2; n <= 9; n++ {
+		rounds := RoundRobin(n)
+		met := map[[2]int]int{}
+		whites := make([]int, n)
+		for _, round := range rounds {
+			playing := map[int]bool{}
+			for _, pairing := range round {
+				if playing[pairing.White] || playing[pairing.Black] {
+					t.Errorf("A player plays twice in a round of %d players", n)
+				}
+				playing[pairing.White], playing[pairing.Black] = true, true
+				met[pairKey(pairing.White, pairing.Black)]++
+				whites[pairing.White]++
+			}
+		}
+		if len(met) != n*(n-1)/2 {
+			t.Errorf("Expected %d pairs for %d players but got %d", n*(n-1)/2, n, len(met))
+		}
+		for pair, count := range met {
+			if count != 1 {
+				t.Errorf("The players %v meet %d times in a round robin of %d players", pair, count, n)
+			}
+		}
+		for player, count := range whites {
+			if math.Abs(float64(count)-float64(n-1)/2) > 1 {
+				t.Errorf("Player %d of %d has white in %d games", player, n, count)
+			}
+		}
+	}
+}
+
+func TestSwissRound(t *testing.T) {
+	ranking := []int{0, 1, 2, 3, 4}
+	played := map[[2]int]bool{pairKey(0, 1): true, pairKey(2, 3): true}
+	colours := map[int]colourHistory{
+		0: {balance: 1, lastWhite: true, played: true},
+		1: {balance: -1, played: true},
+	}
+	pairings := swissRound(ranking, played, map[int]bool{4: true}, colours)
+	if len(pairings) != 3 {
+		t.Fatalf("Expected two games and a bye but got %v", pairings)
+	}
+	if pairings[0] != (Pairing{White: 3, Black: BYE}) {
+		t.Errorf("Expected the lowest player without a bye to sit out but got %v", pairings[0])
+	}
+	for _, pairing := range pairings[1:] {
+		if played[pairKey(pairing.White, pairing.Black)] {
+			t.Errorf("The pairing %v repeats a game", pairing)
+		}
+	}
+	// 0 had white and has to play black against 2
+	if pairings[1] != (Pairing{White: 2, Black: 0}) {
+		t.Errorf("Expected 2 to play white against 0 but got %v", pairings[1])
+	}
+}
+
+// record returns a finished game between two players
+func record(round, white, black int, result string) GameRecord {
+	return GameRecord{Round: round, White: white, Black: black, Game: ghess.Game{Result: result}}
+}
+
+func TestStandings(t *testing.T) {
+	tournament := &Tournament{
+		Players: []Player{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}},
+		Format:  ROUND_ROBIN,
+		Games: []GameRecord{
+			record(1, 0, 3, "1-0"), record(1, 1, 2, "1/2-1/2"),
+			record(2, 3, 1, "0-1"), record(2, 2, 0, "1-0"),
+			record(3, 0, 1, "1/2-1/2"), record(3, 2, 3, "1-0"),
+		},
+	}
+	// a: 1.5 (beat d, lost to c, drew b), b: 2 (drew c, beat d, drew a), c: 2.5, d: 0
+	expected := []Standing{
+		{Player: 2, Name: "c", Games: 3, Wins: 2, Draws: 1, Points: 2.5, SonnebornBerger: 2.5, Buchholz: 3.5},
+		{Player: 1, Name: "b", Games: 3, Wins: 1, Draws: 2, Points: 2, SonnebornBerger: 2, Buchholz: 4},
+		{Player: 0, Name: "a", Games: 3, Wins: 1, Draws: 1, Losses: 1, Points: 1.5, SonnebornBerger: 1, Buchholz: 4.5},
+		{Player: 3, Name: "d", Games: 3, Losses: 3, Points: 0, SonnebornBerger: 0, Buchholz: 6},
+	}
+	standings := tournament.Standings()
+	for i := range expected {
+		if standings[i] != expected[i] {
+			t.Errorf("Expected place %d to be %+v but got %+v", i+1, expected[i], standings[i])
+		}
+	}
+
+	// equal points are decided by Sonneborn-Berger in a round robin and by Buchholz in a Swiss tournament
+	tournament.Games = []GameRecord{record(1, 0, 1, "1-0"), record(1, 2, 3, "1/2-1/2"), record(2, 2, 0, "1/2-1/2")}
+	tournament.Format = SWISS
+	if standings := tournament.Standings(); standings[0].Name != "a" || standings[1].Name != "c" {
+		t.Errorf("Unexpected Swiss standings %+v", standings)
+	}
+}
+
+func TestParsePlayer(t *testing.T) {
+	player, err := ParsePlayer("ab2=alphaBeta:depth=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := player.NewEngine()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Name != "ab2" || engine.Name() != "ab2" {
+		t.Errorf("Expected the name ab2 but got %s and %s", player.Name, engine.Name())
+	}
+	board := ghess.GetBoardFromFen(ghess.START_FEN)
+	if result := engine.Search(&board, ghess.SearchLimits{MoveTime: 10000}); result.Depth != 2 {
+		t.Errorf("Expected the configured depth 2 but got %d", result.Depth)
+	}
+	for _, spec := range []string{"unknown", "alphaBeta:depth", "alphaBeta:speed=3", "mcts:threads=x"} {
+		if _, err := ParsePlayer(spec); err == nil {
+			t.Errorf("Expected an error for %s", spec)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	players := []Player{}
+	for _, spec := range []string{"random", "captureRandom", "checkCaptureRandom", "alphaBeta:depth=1"} {
+		player, err := ParsePlayer(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, player)
+	}
+	options := Options{
+		Format:      ROUND_ROBIN,
+		Rounds:      2,
+		Game:        ghess.GameOptions{Adjudication: ghess.Adjudication{MaxPlies: 40}},
+		Concurrency: 2,
+	}
+	tournament, err := Run(players, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tournament.Rounds) != 6 || len(tournament.Games) != 12 {
+		t.Errorf("Expected 12 games in 6 rounds but got %d in %d", len(tournament.Games), len(tournament.Rounds))
+	}
+	points := 0.0
+	for _, standing := range tournament.Standings() {
+		points += standing.Points
+	}
+	if points != 12 {
+		t.Errorf("Expected 12 points in total but got %.1f", points)
+	}
+	if strings.Count(tournament.PGN("test"), "[Event ") != 12 {
+		t.Errorf("Expected all games in the PGN")
+	}
+	if lines := strings.Split(strings.TrimSpace(tournament.Crosstable()), "\n"); len(lines) != 5 {
+		t.Errorf("Expected a header and a line per player in the crosstable but got\n%s", tournament.Crosstable())
+	}
+
+	options.Format = SWISS
+	options.Rounds = 3
+	players = append(players, players[0])
+	players[4].Name = "random2"
+	tournament, err = Run(players, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two games per round and a bye for a different player each round
+	byes := map[int]bool{}
+	for _, round := range tournament.Rounds {
+		for _, pairing := range round {
+			if pairing.Black == BYE {
+				byes[pairing.White] = true
+			}
+		}
+	}
+	if len(tournament.Games) != 6 || len(byes) != 3 {
+		t.Errorf("Expected 6 games and 3 different byes but got %d games and byes %v", len(tournament.Games), byes)
+	}
+	if !strings.Contains(tournament.Table(), "Buchholz") {
+		t.Errorf("Expected the tie-breaks in the table")
+	}
+}
@@ -0,0 +1,299 @@
+package ghess
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TuningPosition is a quiet position labelled with the result of the game it was played in from whites perspective
+// (1 for a white win, 0.5 for a draw and 0 for a black win)
+type TuningPosition struct {
+	Fen    string
+	Result float64
+}
+
+// TunerOptions configure TuneEvalParams
+type TunerOptions struct {
+	Epochs       int       // number of gradient descent steps over all positions
+	LearningRate float64   // step size in centipawns
+	K            float64   // scaling of the sigmoid, gets computed from the positions if it's 0
+	Log          io.Writer // progress output, nil to disable it
+}
+
+// tuningCoef is a weight index with the number of times the weight is applied in the midgame and endgame
+type tuningCoef struct {
+	index int32
+	mg    int32
+	eg    int32
+}
+
+// tuningEntry is a position reduced to the weights which are applied in it
+type tuningEntry struct {
+	coefs        []tuningCoef
+	kingAttacks  [2][6]int
+	kingAttacker [2]int
+	phase        int
+	result       float64
+}
+
+// tuner holds the positions and the current weights as floats
+type tuner struct {
+	entries []tuningEntry
+	weights []float64
+	// offsets of the king attack values and weights in the weight vector
+	kingAttackValueIdx  int
+	kingAttackWeightIdx int
+}
+
+var resultStrings = map[string]float64{
+	"1-0": 1, "0-1": 0, "1/2-1/2": 0.5,
+	"1.0": 1, "0.0": 0, "0.5": 0.5,
+	"1": 1, "0": 0,
+}
+
+// ParseTuningPosition parses a line of a tuning data set which consists of a fen (the move counters are optional)
+// followed by the result as "1-0", "0-1", "1/2-1/2" or as 1.0, 0.5, 0.0. The result may be surrounded by quotes or brackets
+// and may be preceded by an EPD opcode like c9.
+func ParseTuningPosition(line string) (TuningPosition, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 {
+		return TuningPosition{}, fmt.Errorf("expected a fen and a result: %q", line)
+	}
+	resultString := strings.Trim(fields[len(fields)-1], "\"[];")
+	result, ok := resultStrings[resultString]
+	if !ok {
+		return TuningPosition{}, fmt.Errorf("unknown result %q", resultString)
+	}
+	fen := strings.Join(fields[:4], " ")
+	_, errHalf := strconv.Atoi(fields[4])
+	if len(fields) >= 7 && errHalf == nil {
+		if _, errFull := strconv.Atoi(fields[5]); errFull == nil {
+			return TuningPosition{Fen: fen + " " + fields[4] + " " + fields[5], Result: result}, nil
+		}
+	}
+	return TuningPosition{Fen: fen + " 0 1", Result: result}, nil
+}
+
+// newTuningEntry returns the weights applied in the position or false if the game already ended in that position
+func newTuningEntry(position TuningPosition) (tuningEntry, bool) {
+	board := GetBoardFromFen(position.Fen)
+	if gameEnded, _, _ := board.CheckGameEnded(); gameEnded {
+		return tuningEntry{}, false
+	}
+	coef := board.getEvalCoefficients()
+	entry := tuningEntry{
+		kingAttacks:  coef.kingAttacks,
+		kingAttacker: coef.kingAttacker,
+		phase:        min(coef.phase, MAX_PHASE),
+		result:       position.Result,
+	}
+	mgV := coef.mg.vector()
+	egV := coef.eg.vector()
+	for i := range mgV {
+		if *mgV[i] != 0 || *egV[i] != 0 {
+			entry.coefs = append(entry.coefs, tuningCoef{index: int32(i), mg: int32(*mgV[i]), eg: int32(*egV[i])})
+		}
+	}
+	return entry, true
+}
+
+// indexOf returns the index of the pointer in the weight vector
+func indexOf(v []*int, p *int) int {
+	for i := range v {
+		if v[i] == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// newTuner converts the positions into tuning entries and initializes the weights with params
+func newTuner(positions []TuningPosition, params EvalParams) *tuner {
+	t := &tuner{}
+	v := params.vector()
+	t.weights = make([]float64, len(v))
+	for i, p := range v {
+		t.weights[i] = float64(*p)
+	}
+	t.kingAttackValueIdx = indexOf(v, &params.KingAttackValue[0])
+	t.kingAttackWeightIdx = indexOf(v, &params.KingAttackWeight[0])
+
+	// getEvalCoefficients evaluates with the global weights
+	oldParams := evalParams
+	evalParams = params
+	defer func() { evalParams = oldParams }()
+	for _, position := range positions {
+		if entry, ok := newTuningEntry(position); ok {
+			t.entries = append(t.entries, entry)
+		}
+	}
+	return t
+}
+
+// params returns the rounded weights
+func (t *tuner) params() EvalParams {
+	var params EvalParams
+	for i, p := range params.vector() {
+		*p = int(math.Round(t.weights[i]))
+	}
+	return params
+}
+
+// kingAttackValue returns the sum of the attack values of the pieces attacking the king of color c
+func (t *tuner) kingAttackValue(entry *tuningEntry, c int) float64 {
+	attackValue := 0.0
+	for pieceType, n := range entry.kingAttacks[c] {
+		attackValue += float64(n) * t.weights[t.kingAttackValueIdx+pieceType]
+	}
+	return attackValue
+}
+
+// evaluate returns the tapered evaluation of an entry with the current weights
+func (t *tuner) evaluate(entry *tuningEntry) float64 {
+	mg, eg := 0.0, 0.0
+	for _, coef := range entry.coefs {
+		mg += float64(coef.mg) * t.weights[coef.index]
+		eg += float64(coef.eg) * t.weights[coef.index]
+	}
+	for c := 0; c < 2; c++ {
+		attackWeight := t.weights[t.kingAttackWeightIdx+entry.kingAttacker[c]]
+		mg -= float64(colorSign(c == 1)) * t.kingAttackValue(entry, c) * attackWeight / 100
+	}
+	return (mg*float64(entry.phase) + eg*float64(MAX_PHASE-entry.phase)) / MAX_PHASE
+}
+
+// sigmoid maps an evaluation in centipawns to the expected result
+func sigmoid(k, q float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*q/400))
+}
+
+// forEachChunk splits the entries into numChunks chunks and runs f on them in parallel
+func forEachChunk(entries []tuningEntry, numChunks int, f func(chunk int, entries []tuningEntry)) {
+	chunkSize := (len(entries) + numChunks - 1) / numChunks
+	var wg sync.WaitGroup
+	for chunk := 0; chunk < numChunks; chunk++ {
+		start := min(chunk*chunkSize, len(entries))
+		end := min(start+chunkSize, len(entries))
+		wg.Add(1)
+		go func(chunk int, entries []tuningEntry) {
+			defer wg.Done()
+			f(chunk, entries)
+		}(chunk, entries[start:end])
+	}
+	wg.Wait()
+}
+
+// error returns the mean squared difference between the results and the predicted results
+func (t *tuner) error(k float64) float64 {
+	sums := make([]float64, runtime.NumCPU())
+	forEachChunk(t.entries, len(sums), func(chunk int, entries []tuningEntry) {
+		for i := range entries {
+			diff := entries[i].result - sigmoid(k, t.evaluate(&entries[i]))
+			sums[chunk] += diff * diff
+		}
+	})
+	total := 0.0
+	for _, sum := range sums {
+		total += sum
+	}
+	return total / float64(len(t.entries))
+}
+
+// computeK returns the sigmoid scaling between 0 and 10 which minimizes the error with the current weights
+func (t *tuner) computeK() float64 {
+	k := 1.0
+	bestError := t.error(k)
+	for step := 0.5; step >= 0.001; step /= 10 {
+		improved := true
+		for improved {
+			improved = false
+			for _, candidate := range []float64{k + step, k - step} {
+				if candidate <= 0 || candidate > 10 {
+					continue
+				}
+				if e := t.error(candidate); e < bestError {
+					k, bestError = candidate, e
+					improved = true
+					break
+				}
+			}
+		}
+	}
+	return k
+}
+
+// gradient returns the gradient of the error with respect to the weights (up to a constant factor)
+func (t *tuner) gradient(k float64) []float64 {
+	gradients := make([][]float64, runtime.NumCPU())
+	forEachChunk(t.entries, len(gradients), func(chunk int, entries []tuningEntry) {
+		gradient := make([]float64, len(t.weights))
+		gradients[chunk] = gradient
+		for i := range entries {
+			entry := &entries[i]
+			s := sigmoid(k, t.evaluate(entry))
+			g := (s - entry.result) * s * (1 - s)
+			mgFactor := g * float64(entry.phase) / MAX_PHASE
+			egFactor := g * float64(MAX_PHASE-entry.phase) / MAX_PHASE
+			for _, coef := range entry.coefs {
+				gradient[coef.index] += float64(coef.mg)*mgFactor + float64(coef.eg)*egFactor
+			}
+			// the king attack penalty is the product of the attack value and the attack weight
+			for c := 0; c < 2; c++ {
+				sign := float64(colorSign(c == 1))
+				weightIdx := t.kingAttackWeightIdx + entry.kingAttacker[c]
+				for pieceType, n := range entry.kingAttacks[c] {
+					gradient[t.kingAttackValueIdx+pieceType] -= mgFactor * sign * float64(n) * t.weights[weightIdx] / 100
+				}
+				gradient[weightIdx] -= mgFactor * sign * t.kingAttackValue(entry, c) / 100
+			}
+		}
+	})
+	total := make([]float64, len(t.weights))
+	for _, gradient := range gradients {
+		for i, g := range gradient {
+			total[i] += g
+		}
+	}
+	return total
+}
+
+// TuneEvalParams optimizes the weights starting from params such that the sigmoid of the static evaluation predicts
+// the game results of the positions as good as possible (Texel tuning). Positions in which the game ended are ignored.
+// The weights are optimized with the Adam variant of gradient descent.
+func TuneEvalParams(positions []TuningPosition, params EvalParams, options TunerOptions) (EvalParams, error) {
+	t := newTuner(positions, params)
+	if len(t.entries) == 0 {
+		return params, errors.New("no positions to tune on")
+	}
+	k := options.K
+	if k == 0 {
+		k = t.computeK()
+	}
+	if options.Log != nil {
+		fmt.Fprintf(options.Log, "%d positions, K = %.3f, error = %.6f\n", len(t.entries), k, t.error(k))
+	}
+
+	const beta1, beta2, epsilon = 0.9, 0.999, 1e-8
+	m := make([]float64, len(t.weights))
+	v := make([]float64, len(t.weights))
+	for epoch := 1; epoch <= options.Epochs; epoch++ {
+		gradient := t.gradient(k)
+		for i, g := range gradient {
+			m[i] = beta1*m[i] + (1-beta1)*g
+			v[i] = beta2*v[i] + (1-beta2)*g*g
+			mHat := m[i] / (1 - math.Pow(beta1, float64(epoch)))
+			vHat := v[i] / (1 - math.Pow(beta2, float64(epoch)))
+			t.weights[i] -= options.LearningRate * mHat / (math.Sqrt(vHat) + epsilon)
+		}
+		if options.Log != nil && (epoch%50 == 0 || epoch == options.Epochs) {
+			fmt.Fprintf(options.Log, "epoch %d, error = %.6f\n", epoch, t.error(k))
+		}
+	}
+	return t.params(), nil
+}
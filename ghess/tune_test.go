@@ -0,0 +1,29 @@
+package ghess
+
+type parseTuningPositionStruct struct {
+	line     string
+	expected TuningPosition
+}
+
+var parseTuningPositionTests = []parseTuningPositionStruct{
+	{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1 [0.5]", TuningPosition{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", 0.5}},
+	{"8/8/4k3/8/8/3QK3/8/8 w - - 12 40 1-0", TuningPosition{"8/8/4k3/8/8/3QK3/8/8 w - - 12 40", 1}},
+	{"8/8/4k3/8/8/3QK3/8/8 b - - c9 \"0-1\";", TuningPosition{"8/8/4k3/8/8/3QK3/8/8 b - - 0 1", 0}},
+	{"8/8/4k3/8/8/3QK3/8/8 b - - 1/2-1/2", TuningPosition{"8/8/4k3/8/8/3QK3/8/8 b - - 0 1", 0.5}},
+}
+
+// positions labelled with results that fit the material balance
+var tuningPositions = []TuningPosition{
+	{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 0.5},
+	{"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2", 0.5},
+	{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", 0.5},
+	{"rnb1kbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3", 1},
+	{"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNB1KBNR w KQkq - 0 3", 0},
+	{"r1bqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 3", 1},
+	{"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/R1BQKBNR w KQkq - 0 3", 0},
+	{"8/5k2/8/8/3P4/8/4K3/8 w - - 0 1", 1},
+	{"8/4k3/8/3p4/8/8/5K2/8 b - - 0 1", 0},
+	{"8/5k2/8/8/8/8/4K3/8 w - - 0 1", 0.5},
+	{"4k3/8/8/8/8/8/8/R3K3 w - - 0 1", 1},
+	{"r3k3/8/8/8/8/8/8/4K3 b - - 0 1", 0},
+}
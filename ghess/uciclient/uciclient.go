@@ -0,0 +1,549 @@
+// Package uciclient drives external chess engines which speak the UCI protocol.
+//
+// An Engine runs the engine as a subprocess. Every method waits for the answer of the engine and returns ErrTimeout
+// if it doesn't answer in time and ErrCrashed if the process exited. Engine implements ghess.GameEngine such that external
+// engines can be used wherever the built-in engines are used.
+package uciclient
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// HANDSHAKE_TIMEOUT is the time an engine gets to answer uci and isready
+const HANDSHAKE_TIMEOUT = 10 * time.Second
+
+// SEARCH_TIMEOUT_MARGIN is the time an engine may exceed its thinking time before the search is stopped
+const SEARCH_TIMEOUT_MARGIN = 5 * time.Second
+
+// ErrTimeout is returned if the engine doesn't answer in time
+var ErrTimeout = errors.New("the engine didn't answer in time")
+
+// ErrCrashed is returned if the engine process exited
+var ErrCrashed = errors.New("the engine process exited")
+
+// Option is an option the engine announced for the uci command
+type Option struct {
+	Name     string
+	Type     string
+	Default  string
+	Min, Max int
+	Vars     []string
+}
+
+// Score is the score of a line from the perspective of the side to move. Mate is the number of moves until checkmate
+// (negative if the side to move gets mated) and 0 if the score is in centipawns.
+type Score struct {
+	CP         int
+	Mate       int
+	LowerBound bool
+	UpperBound bool
+}
+
+// Info is an info line of the engine. Fields which weren't sent are zero.
+type Info struct {
+	Depth          int
+	SelDepth       int
+	MultiPV        int
+	Score          Score
+	Nodes          int
+	NPS            int
+	Time           time.Duration
+	HashFull       int
+	Pv             []string
+	CurrMove       string
+	CurrMoveNumber int
+	String         string
+}
+
+// GoParams are the parameters of a go command. Zero values aren't sent.
+type GoParams struct {
+	WTime, BTime time.Duration
+	WInc, BInc   time.Duration
+	MovesToGo    int
+	Depth        int
+	Nodes        int
+	Mate         int
+	MoveTime     time.Duration
+	Infinite     bool
+	SearchMoves  []string
+}
+
+// SearchResult is the answer of a go command with the last info of each line
+type SearchResult struct {
+	BestMove string
+	Ponder   string
+	Lines    []Info // indexed by multipv - 1
+}
+
+// Engine is a running UCI engine
+type Engine struct {
+	name    string
+	author  string
+	Options map[string]Option
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string // closed when the engine closes its output
+	mu    sync.Mutex  // protects writes to stdin and unresponsive
+	err   error       // last error of the methods without an error result
+	// the engine didn't send its best move and readyok after a timed out search. Its late answers would be taken for
+	// the answers of the next commands, so every command fails with ErrTimeout.
+	unresponsive bool
+}
+
+// Start launches the engine at path and performs the handshake
+func Start(path string, args ...string) (*Engine, error) {
+	e := &Engine{Options: make(map[string]Option), cmd: exec.Command(path, args...), lines: make(chan string, 100)}
+	stdin, err := e.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	e.stdin = stdin
+	stdout, err := e.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.cmd.Start(); err != nil {
+		return nil, err
+	}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			e.lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(e.lines)
+	}()
+
+	if err := e.send("uci"); err != nil {
+		e.Kill()
+		return nil, err
+	}
+	err = e.readUntil(HANDSHAKE_TIMEOUT, func(line string) bool {
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "id name "):
+			e.name = strings.TrimPrefix(line, "id name ")
+		case strings.HasPrefix(line, "id author "):
+			e.author = strings.TrimPrefix(line, "id author ")
+		case len(fields) > 0 && fields[0] == "option":
+			if option, ok := parseOption(line); ok {
+				e.Options[option.Name] = option
+			}
+		}
+		return line == "uciok"
+	})
+	if err != nil {
+		e.Kill()
+		return nil, fmt.Errorf("uci handshake with %s failed: %w", path, err)
+	}
+	return e, nil
+}
+
+// Name returns the name the engine sent in the handshake
+func (e *Engine) Name() string {
+	return e.name
+}
+
+// Author returns the author the engine sent in the handshake
+func (e *Engine) Author() string {
+	return e.author
+}
+
+// Err returns the last error of NewGame or Search which don't return errors to implement ghess.Engine
+func (e *Engine) Err() error {
+	return e.err
+}
+
+// send writes a command to the engine
+func (e *Engine) send(command string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.unresponsive {
+		return ErrTimeout
+	}
+	if _, err := io.WriteString(e.stdin, command+"\n"); err != nil {
+		return ErrCrashed
+	}
+	return nil
+}
+
+// readUntil passes the lines of the engine to handle until it returns true. A timeout of 0 waits forever.
+func (e *Engine) readUntil(timeout time.Duration, handle func(line string) bool) error {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrCrashed
+			}
+			if handle(line) {
+				return nil
+			}
+		case <-deadline:
+			return ErrTimeout
+		}
+	}
+}
+
+// SetOption sets an option of the engine. Buttons are pressed by an empty value.
+func (e *Engine) SetOption(name, value string) error {
+	command := "setoption name " + name
+	if value != "" {
+		command += " value " + value
+	}
+	if err := e.send(command); err != nil {
+		return err
+	}
+	return e.IsReady()
+}
+
+// IsReady waits until the engine is ready for the next command
+func (e *Engine) IsReady() error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	return e.readUntil(HANDSHAKE_TIMEOUT, func(line string) bool {
+		return line == "readyok"
+	})
+}
+
+// NewGame tells the engine that the next position is from a new game. Errors are returned by Err.
+func (e *Engine) NewGame() {
+	if e.err = e.send("ucinewgame"); e.err == nil {
+		e.err = e.IsReady()
+	}
+}
+
+// Position sets the position given by a FEN (the start position if empty) and the moves in long algebraic notation
+func (e *Engine) Position(fen string, moves []string) error {
+	command := "position startpos"
+	if fen != "" && fen != ghess.START_FEN {
+		command = "position fen " + fen
+	}
+	if len(moves) > 0 {
+		command += " moves " + strings.Join(moves, " ")
+	}
+	return e.send(command)
+}
+
+// String returns the go command of the parameters
+func (params GoParams) String() string {
+	command := "go"
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{{"wtime", params.WTime}, {"btime", params.BTime}, {"winc", params.WInc}, {"binc", params.BInc}, {"movetime", params.MoveTime}}
+	for _, d := range durations {
+		if d.value > 0 {
+			command += fmt.Sprintf(" %s %d", d.name, d.value.Milliseconds())
+		}
+	}
+	numbers := []struct {
+		name  string
+		value int
+	}{{"movestogo", params.MovesToGo}, {"depth", params.Depth}, {"nodes", params.Nodes}, {"mate", params.Mate}}
+	for _, n := range numbers {
+		if n.value > 0 {
+			command += fmt.Sprintf(" %s %d", n.name, n.value)
+		}
+	}
+	if params.Infinite {
+		command += " infinite"
+	}
+	if len(params.SearchMoves) > 0 {
+		command += " searchmoves " + strings.Join(params.SearchMoves, " ")
+	}
+	return command
+}
+
+// timeout returns the time after which the search is stopped or 0 if the search only ends by depth, nodes or Stop
+func (params GoParams) timeout(isBlacksTurn bool) time.Duration {
+	switch {
+	case params.Infinite:
+		return 0
+	case params.MoveTime > 0:
+		return params.MoveTime + SEARCH_TIMEOUT_MARGIN
+	case isBlacksTurn && params.BTime > 0:
+		return params.BTime + SEARCH_TIMEOUT_MARGIN
+	case !isBlacksTurn && params.WTime > 0:
+		return params.WTime + SEARCH_TIMEOUT_MARGIN
+	}
+	return 0
+}
+
+// Go starts a search in the position set before and waits for the best move. onInfo is called for every info line
+// (it may be nil). isBlacksTurn selects the clock after which the search is stopped. If the engine doesn't send a best
+// move and readyok within SEARCH_TIMEOUT_MARGIN after stop and isready the search fails with ErrTimeout and the
+// engine is unresponsive, i.e. all further commands fail, as its late answers would be mixed up with the next ones.
+func (e *Engine) Go(params GoParams, isBlacksTurn bool, onInfo func(Info)) (SearchResult, error) {
+	result := SearchResult{}
+	if err := e.send(params.String()); err != nil {
+		return result, err
+	}
+	handle := func(line string) bool {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			return false
+		}
+		switch fields[0] {
+		case "info":
+			info := ParseInfo(line)
+			if onInfo != nil {
+				onInfo(info)
+			}
+			if len(info.Pv) > 0 {
+				line := max(info.MultiPV, 1)
+				for len(result.Lines) < line {
+					result.Lines = append(result.Lines, Info{})
+				}
+				result.Lines[line-1] = info
+			}
+		case "bestmove":
+			if len(fields) > 1 {
+				result.BestMove = fields[1]
+			}
+			if len(fields) > 3 && fields[2] == "ponder" {
+				result.Ponder = fields[3]
+			}
+			return true
+		}
+		return false
+	}
+	err := e.readUntil(params.timeout(isBlacksTurn), handle)
+	if err == ErrTimeout {
+		// the engine may still answer if it's told to stop. The output is read until readyok such that a late best
+		// move isn't taken for the answer of the next search.
+		if err = e.Stop(); err == nil {
+			err = e.send("isready")
+		}
+		if err == nil {
+			err = e.readUntil(SEARCH_TIMEOUT_MARGIN, func(line string) bool {
+				if line == "readyok" {
+					return true
+				}
+				handle(line)
+				return false
+			})
+		}
+		if err == nil && result.BestMove == "" {
+			err = ErrTimeout
+		}
+		if err == ErrTimeout {
+			e.mu.Lock()
+			e.unresponsive = true
+			e.mu.Unlock()
+		}
+	}
+	return result, err
+}
+
+// Stop tells the engine to send its best move as soon as possible. It can be called while Go waits.
+func (e *Engine) Stop() error {
+	return e.send("stop")
+}
+
+// Search searches the position of board with the limits and returns the best move and its score from whites
+// perspective. Errors are returned by Err and the move is empty in that case.
+// Only the position is sent, so the engine doesn't know the earlier positions of the game. SearchGame sends them.
+func (e *Engine) Search(board *ghess.Board, limits ghess.SearchLimits) ghess.SearchResult {
+	return e.SearchGame(board, board.GetFen(), nil, limits)
+}
+
+// SearchGame searches the position of board which is reached by the moves from startFen like Search. The start
+// position and the moves are sent to the engine such that it can detect repetitions.
+func (e *Engine) SearchGame(board *ghess.Board, startFen string, moves []ghess.Move, limits ghess.SearchLimits) ghess.SearchResult {
+	searchResult := ghess.SearchResult{}
+	moveStrs := make([]string, len(moves))
+	for i := range moves {
+		moveStrs[i] = ghess.GetAlgebraicFromMove(&moves[i])
+	}
+	if e.err = e.Position(startFen, moveStrs); e.err != nil {
+		return searchResult
+	}
+	params := GoParams{MoveTime: time.Duration(limits.MoveTime) * time.Millisecond, Depth: limits.Depth, Nodes: limits.Nodes}
+	if limits.MoveTime <= 0 && limits.Depth <= 0 && limits.Nodes <= 0 {
+		params.MoveTime = time.Duration(ghess.MAX_ENGINE_TIME) * time.Millisecond
+	}
+	result, err := e.Go(params, board.IsBlacksTurn, nil)
+	if e.err = err; err != nil {
+		return searchResult
+	}
+	move, err := board.GetMoveFromLongAlgebraic(result.BestMove)
+	if err != nil {
+		e.err = fmt.Errorf("illegal best move %s: %w", result.BestMove, err)
+		return searchResult
+	}
+	searchResult.Move = move
+	if len(result.Lines) > 0 {
+		best := result.Lines[0]
+		searchResult.Depth = best.Depth
+		searchResult.Nodes = best.Nodes
+		searchResult.Score = float64(best.Score.CP)
+		if board.IsBlacksTurn {
+			searchResult.Score = -searchResult.Score
+		}
+		if best.Score.Mate != 0 {
+			searchResult.Score = board.MateScore(best.Score.Mate)
+		}
+		// the pv is only converted as long as the moves are legal
+		pvBoard := *board
+		for _, moveStr := range best.Pv {
+			pvMove, err := pvBoard.GetMoveFromLongAlgebraic(moveStr)
+			if err != nil {
+				break
+			}
+			pvBoard.Move(&pvMove)
+			searchResult.Pv = append(searchResult.Pv, pvMove)
+		}
+	}
+	return searchResult
+}
+
+// Quit asks the engine to exit and kills it if it doesn't exit in time or is unresponsive
+func (e *Engine) Quit() error {
+	if err := e.send("quit"); err == ErrTimeout {
+		e.Kill()
+		return err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- e.cmd.Wait()
+	}()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(HANDSHAKE_TIMEOUT):
+		e.cmd.Process.Kill()
+		return ErrTimeout
+	}
+}
+
+// Kill ends the engine process immediately
+func (e *Engine) Kill() {
+	e.cmd.Process.Kill()
+	e.cmd.Wait()
+}
+
+// ParseInfo reads an info line. Unknown tokens are skipped and string takes the rest of the line.
+func ParseInfo(line string) Info {
+	info := Info{}
+	fields := strings.Fields(line)
+	numbers := map[string]*int{
+		"depth": &info.Depth, "seldepth": &info.SelDepth, "multipv": &info.MultiPV, "nodes": &info.Nodes,
+		"nps": &info.NPS, "hashfull": &info.HashFull, "currmovenumber": &info.CurrMoveNumber,
+	}
+	for i := 1; i < len(fields); i++ {
+		hasValue := i+1 < len(fields)
+		switch token := fields[i]; token {
+		case "string":
+			info.String = strings.Join(fields[i+1:], " ")
+			return info
+		case "pv":
+			info.Pv = fields[i+1:]
+			return info
+		case "currmove":
+			if hasValue {
+				i++
+				info.CurrMove = fields[i]
+			}
+		case "time":
+			if hasValue {
+				i++
+				ms, _ := strconv.Atoi(fields[i])
+				info.Time = time.Duration(ms) * time.Millisecond
+			}
+		case "score":
+			for i+1 < len(fields) {
+				switch fields[i+1] {
+				case "cp", "mate":
+					if i+2 < len(fields) {
+						value, _ := strconv.Atoi(fields[i+2])
+						if fields[i+1] == "cp" {
+							info.Score.CP = value
+						} else {
+							info.Score.Mate = value
+						}
+					}
+					i += 2
+					continue
+				case "lowerbound":
+					info.Score.LowerBound = true
+					i++
+					continue
+				case "upperbound":
+					info.Score.UpperBound = true
+					i++
+					continue
+				}
+				break
+			}
+		default:
+			if value, ok := numbers[token]; ok && hasValue {
+				i++
+				*value, _ = strconv.Atoi(fields[i])
+			}
+		}
+	}
+	return info
+}
+
+// parseOption reads "option name <name> type <type> [default <value>] [min <n>] [max <n>] [var <value>]*" where the
+// name and the values may contain spaces
+func parseOption(line string) (Option, bool) {
+	option := Option{}
+	keywords := map[string]bool{"name": true, "type": true, "default": true, "min": true, "max": true, "var": true}
+	fields := strings.Fields(line)
+	key := ""
+	values := []string{}
+	flush := func() {
+		value := strings.Join(values, " ")
+		switch key {
+		case "name":
+			option.Name = value
+		case "type":
+			option.Type = value
+		case "default":
+			option.Default = value
+		case "min":
+			option.Min, _ = strconv.Atoi(value)
+		case "max":
+			option.Max, _ = strconv.Atoi(value)
+		case "var":
+			option.Vars = append(option.Vars, value)
+		}
+		values = values[:0]
+	}
+	for _, field := range fields[1:] {
+		if keywords[field] && !(key == "name" && field != "type") {
+			flush()
+			key = field
+			continue
+		}
+		values = append(values, field)
+	}
+	flush()
+	return option, option.Name != "" && option.Type != ""
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
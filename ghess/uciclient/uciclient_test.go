@@ -0,0 +1,233 @@
+package uciclient
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+var parseInfoTests = []struct {
+	line string
+	info Info
+}{
+	{"info depth 5 seldepth 8 multipv 1 score cp 31 nodes 12345 nps 100000 time 123 hashfull 7 pv e2e4 e7e5",
+		Info{Depth: 5, SelDepth: 8, MultiPV: 1, Score: Score{CP: 31}, Nodes: 12345, NPS: 100000,
+			Time: 123 * time.Millisecond, HashFull: 7, Pv: []string{"e2e4", "e7e5"}}},
+	{"info depth 3 score mate -2 pv h1h8", Info{Depth: 3, Score: Score{Mate: -2}, Pv: []string{"h1h8"}}},
+	{"info score cp 20 lowerbound depth 4", Info{Depth: 4, Score: Score{CP: 20, LowerBound: true}}},
+	{"info currmove e2e4 currmovenumber 3", Info{CurrMove: "e2e4", CurrMoveNumber: 3}},
+	{"info string book move e2e4", Info{String: "book move e2e4"}},
+	{"info unknown 3 depth 2", Info{Depth: 2}},
+}
+
+func TestParseInfo(t *testing.T) {
+	for _, test := range parseInfoTests {
+		info := ParseInfo(test.line)
+		if !reflect.DeepEqual(info, test.info) {
+			t.Errorf("ParseInfo(%q) = %+v, want %+v", test.line, info, test.info)
+		}
+	}
+}
+
+func TestParseOption(t *testing.T) {
+	option, ok := parseOption("option name Skill Level type spin default 20 min 0 max 20")
+	want := Option{Name: "Skill Level", Type: "spin", Default: "20", Min: 0, Max: 20}
+	if !ok || !reflect.DeepEqual(option, want) {
+		t.Errorf("got %+v, want %+v", option, want)
+	}
+	option, ok = parseOption("option name Style type combo default Normal var Solid var Normal var Risky")
+	if !ok || !reflect.DeepEqual(option.Vars, []string{"Solid", "Normal", "Risky"}) {
+		t.Errorf("got vars %v", option.Vars)
+	}
+}
+
+func TestGoParamsString(t *testing.T) {
+	params := GoParams{WTime: time.Second, BTime: 2 * time.Second, WInc: 100 * time.Millisecond, Depth: 4,
+		SearchMoves: []string{"e2e4", "d2d4"}}
+	want := "go wtime 1000 btime 2000 winc 100 depth 4 searchmoves e2e4 d2d4"
+	if params.String() != want {
+		t.Errorf("got %q, want %q", params.String(), want)
+	}
+}
+
+// startGhess builds the uci command of this repository and starts it
+func startGhess(t *testing.T) *Engine {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "ghess-uci")
+	build := exec.Command("go", "build", "-o", binary, ".")
+	build.Dir = filepath.Join("..", "..", "uci")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Skipf("can't build the uci engine: %v\n%s", err, out)
+	}
+	engine, err := Start(binary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Quit() })
+	return engine
+}
+
+func TestEngine(t *testing.T) {
+	engine := startGhess(t)
+	if engine.Name() != "Ghess v0.1.0" {
+		t.Errorf("got name %q", engine.Name())
+	}
+	if _, ok := engine.Options["Hash"]; !ok {
+		t.Errorf("option Hash is missing in %v", engine.Options)
+	}
+	if err := engine.SetOption("Hash", "16"); err != nil {
+		t.Fatal(err)
+	}
+
+	engine.NewGame()
+	if err := engine.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Position("", []string{"e2e4", "e7e5"}); err != nil {
+		t.Fatal(err)
+	}
+	infos := 0
+	result, err := engine.Go(GoParams{Depth: 3}, false, func(Info) { infos++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := ghess.GetBoardFromFen(ghess.START_FEN)
+	board.MoveLongAlgebraic("e2e4")
+	board.MoveLongAlgebraic("e7e5")
+	if _, err := board.GetMoveFromLongAlgebraic(result.BestMove); err != nil {
+		t.Errorf("best move %s is illegal: %v", result.BestMove, err)
+	}
+	if infos == 0 || len(result.Lines) == 0 || result.Lines[0].Depth == 0 {
+		t.Errorf("expected info lines, got %d infos and lines %+v", infos, result.Lines)
+	}
+
+	// an infinite search only ends with stop
+	if err := engine.Position(ghess.START_FEN, nil); err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(200*time.Millisecond, func() { engine.Stop() })
+	result, err = engine.Go(GoParams{Infinite: true}, false, nil)
+	if err != nil || result.BestMove == "" {
+		t.Errorf("infinite search returned %+v, %v", result, err)
+	}
+
+	// the engine as a ghess.Engine
+	var _ ghess.Engine = engine
+	mateBoard := ghess.GetBoardFromFen("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	searchResult := engine.Search(&mateBoard, ghess.SearchLimits{Depth: 3})
+	if err := engine.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if ghess.GetAlgebraicFromMove(&searchResult.Move) != "a1a8" || searchResult.Score != mateBoard.MateScore(1) {
+		t.Errorf("expected the mate a1a8, got %s with score %f", ghess.GetAlgebraicFromMove(&searchResult.Move),
+			searchResult.Score)
+	}
+}
+
+// fakeEngine is a shell script which answers the handshake and plays e7e5 if it gets the start position with the
+// move e2e4 and a7a6 otherwise
+const fakeEngine = `while read -r line; do
+	case "$line" in
+	uci) echo "id name fake"; echo uciok ;;
+	isready) echo readyok ;;
+	"position startpos moves e2e4") best=e7e5 ;;
+	position*) best=a7a6 ;;
+	go*) echo "bestmove $best" ;;
+	quit) exit 0 ;;
+	esac
+done`
+
+func TestSearchGame(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	engine, err := Start("sh", "-c", fakeEngine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Kill()
+	var _ ghess.GameEngine = engine
+	board := ghess.GetBoardFromFen(ghess.START_FEN)
+	move, _ := board.GetMoveFromLongAlgebraic("e2e4")
+	board.Move(&move)
+	// the start position and the moves of the game are sent instead of the current position
+	result := engine.SearchGame(&board, ghess.START_FEN, []ghess.Move{move}, ghess.SearchLimits{Depth: 1})
+	if err := engine.Err(); err != nil || ghess.GetAlgebraicFromMove(&result.Move) != "e7e5" {
+		t.Errorf("expected e7e5 after the moves of the game, got %s, %v", ghess.GetAlgebraicFromMove(&result.Move), err)
+	}
+	result = engine.Search(&board, ghess.SearchLimits{Depth: 1})
+	if err := engine.Err(); err != nil || ghess.GetAlgebraicFromMove(&result.Move) != "a7a6" {
+		t.Errorf("expected a7a6 for the position only, got %s, %v", ghess.GetAlgebraicFromMove(&result.Move), err)
+	}
+}
+
+func TestCrash(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	_, err := Start("sh", "-c", "exit 1")
+	if err == nil {
+		t.Fatal("expected an error for an engine which exits")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	// the engine answers the handshake but never answers go
+	engine, err := Start("sh", "-c", "echo uciok; exec sleep 60")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Kill()
+	start := time.Now()
+	_, err = engine.Go(GoParams{MoveTime: 10 * time.Millisecond}, false, nil)
+	if err != ErrTimeout {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+	if time.Since(start) > 3*SEARCH_TIMEOUT_MARGIN {
+		t.Errorf("the search took %s", time.Since(start))
+	}
+	if err := engine.IsReady(); err != ErrTimeout {
+		t.Errorf("expected the engine to be unresponsive after the timeout, got %v", err)
+	}
+}
+
+func TestLateBestMove(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	// the engine only answers the first stop with a best move
+	engine, err := Start("sh", "-c", `while read -r line; do
+	case "$line" in
+	uci) echo uciok ;;
+	isready) echo readyok ;;
+	stop) n=$((n+1)); [ "$n" = 1 ] && echo "bestmove e7e5" ;;
+	esac
+done`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Kill()
+	result, err := engine.Go(GoParams{MoveTime: 10 * time.Millisecond}, false, nil)
+	if err != nil || result.BestMove != "e7e5" {
+		t.Errorf("expected the best move sent after stop, got %+v, %v", result, err)
+	}
+	if err := engine.IsReady(); err != nil {
+		t.Errorf("expected the engine to be usable after answering stop, got %v", err)
+	}
+	// readyok without a best move means that a best move may still come and be taken for the next answer
+	if _, err = engine.Go(GoParams{MoveTime: 10 * time.Millisecond}, false, nil); err != ErrTimeout {
+		t.Errorf("expected ErrTimeout, got %v", err)
+	}
+	if err := engine.Position("", nil); err != ErrTimeout {
+		t.Errorf("expected the engine to be unresponsive, got %v", err)
+	}
+}
@@ -16,6 +16,13 @@ func min(x, y int) int {
 	return y
 }
 
+func max(x, y int) int {
+	if x >= y {
+		return x
+	}
+	return y
+}
+
 func xy(n int) (x, y int) {
 	y = n / 8
 	x = n % 8
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// The commands in this file aren't part of the UCI protocol but help to debug the engine on the console.
+
+// printBoard prints the board, its FEN and its Polyglot hash (command d)
+func printBoard() {
+	fmt.Print(board.DisplayASCII())
+	fmt.Printf("\nFen: %s\n", board.GetFen())
+	fmt.Printf("Key: %016X\n", board.PolyglotKey())
+}
+
+// perft prints the number of positions after each move and in total (command go perft <depth>)
+func perft(fields []string) {
+	depth := 1
+	if len(fields) > 2 {
+		if d, err := strconv.Atoi(fields[2]); err == nil && d > 0 {
+			depth = d
+		}
+	}
+	startTime := time.Now()
+	divide := board.PerftDivide(depth)
+	moves := make([]string, 0, len(divide))
+	total := 0
+	for move, n := range divide {
+		moves = append(moves, move)
+		total += n
+	}
+	sort.Strings(moves)
+	for _, move := range moves {
+		fmt.Printf("%s: %d\n", move, divide[move])
+	}
+	fmt.Printf("\nNodes searched: %d\n", total)
+	fmt.Printf("Time (ms): %d\n", time.Since(startTime).Milliseconds())
+}
+
+// bench searches the bench positions and prints the number of nodes which identifies the build (command bench [depth])
+func bench(fields []string) {
+	depth := ghess.BENCH_DEPTH
+	if len(fields) > 1 {
+		if d, err := strconv.Atoi(fields[1]); err == nil && d > 0 {
+			depth = d
+		}
+	}
+	result := ghess.Bench(depth)
+	ms := result.Time.Milliseconds()
+	nps := int64(result.Nodes) * 1000
+	if ms > 0 {
+		nps /= ms
+	}
+	fmt.Printf("Total time (ms) : %d\n", ms)
+	fmt.Printf("Nodes searched  : %d\n", result.Nodes)
+	fmt.Printf("Nodes/second    : %d\n", nps)
+}
+
+// flip mirrors the position such that the evaluation has to change its sign (command flip)
+func flip() {
+	currentFEN = ghess.MirrorFen(board.GetFen())
+	board = ghess.GetBoardFromFen(currentFEN)
+}
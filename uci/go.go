@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// DEFAULT_MOVES_TO_GO is the number of moves the remaining time is split into if the GUI doesn't send movestogo
+const DEFAULT_MOVES_TO_GO = 40
+
+// DEFAULT_CLOCK is the remaining time in ms for each side if go neither has a clock nor any other limit
+const DEFAULT_CLOCK = DEFAULT_MOVES_TO_GO * 4000
+
+// UNLIMITED_TIME is the thinking time in ms of searches which are only limited by depth, nodes or mate
+const UNLIMITED_TIME = 1000 * 60 * 60 * 24
+
+// goCommand are the parameters of a "go" command. Numbers which weren't sent are 0.
+type goCommand struct {
+	ponder      bool
+	infinite    bool
+	wtime       int
+	btime       int
+	winc        int
+	binc        int
+	movesToGo   int
+	depth       int
+	nodes       int
+	mate        int // search for a mate in that many moves
+	moveTime    int
+	searchMoves []string
+}
+
+// parseGo reads the parameters of "go [ponder] [infinite] [wtime x] ... [searchmoves m1 m2 ...]".
+// Unknown tokens and invalid numbers are skipped. searchmoves takes all following moves until the next keyword.
+func parseGo(in string) goCommand {
+	g := goCommand{}
+	numbers := map[string]*int{
+		"wtime": &g.wtime, "btime": &g.btime, "winc": &g.winc, "binc": &g.binc, "movestogo": &g.movesToGo,
+		"depth": &g.depth, "nodes": &g.nodes, "mate": &g.mate, "movetime": &g.moveTime,
+	}
+	tokens := strings.Fields(in)
+	for i := 1; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token {
+		case "ponder":
+			g.ponder = true
+		case "infinite":
+			g.infinite = true
+		case "searchmoves":
+			for i+1 < len(tokens) && !isGoKeyword(tokens[i+1], numbers) {
+				i++
+				g.searchMoves = append(g.searchMoves, tokens[i])
+			}
+		default:
+			value, ok := numbers[token]
+			if !ok || i+1 >= len(tokens) {
+				continue
+			}
+			if n, err := strconv.Atoi(tokens[i+1]); err == nil {
+				*value = n
+				i++
+			}
+		}
+	}
+	return g
+}
+
+// isGoKeyword returns true if token starts a new parameter of the go command
+func isGoKeyword(token string, numbers map[string]*int) bool {
+	_, ok := numbers[token]
+	return ok || token == "ponder" || token == "infinite" || token == "searchmoves"
+}
+
+// hasClock returns true if the remaining time of at least one side was sent
+func (g goCommand) hasClock() bool {
+	return g.wtime > 0 || g.btime > 0
+}
+
+// thinkingTime returns the time in ms the engine uses for its move. Searches without a clock which are limited by
+// depth, nodes or mate don't have a time limit and a bare "go" uses DEFAULT_CLOCK.
+func (g goCommand) thinkingTime(isBlacksTurn bool, overhead int) int {
+	if g.infinite {
+		return UNLIMITED_TIME
+	}
+	thinkingTime := 0
+	switch {
+	case g.moveTime > 0:
+		thinkingTime = g.moveTime
+	case g.hasClock() || (g.depth == 0 && g.nodes == 0 && g.mate == 0):
+		clock, inc := g.wtime, g.winc
+		if isBlacksTurn {
+			clock, inc = g.btime, g.binc
+		}
+		if !g.hasClock() {
+			clock = DEFAULT_CLOCK
+		}
+		movesToGo := DEFAULT_MOVES_TO_GO
+		if g.movesToGo > 0 {
+			movesToGo = g.movesToGo
+		}
+		thinkingTime = clock/movesToGo + inc
+		// never plan to use more than the remaining time
+		if thinkingTime > clock {
+			thinkingTime = clock
+		}
+	default:
+		return UNLIMITED_TIME
+	}
+	// keep some time for the communication with the GUI
+	thinkingTime -= overhead
+	if thinkingTime < 1 {
+		thinkingTime = 1
+	}
+	return thinkingTime
+}
+
+// maxDepth returns the maximum depth in plies of the alpha beta search. A mate in n moves needs 2n-1 plies.
+func (g goCommand) maxDepth() int {
+	maxDepth := 30
+	if g.depth > 0 {
+		maxDepth = g.depth
+	}
+	if g.mate > 0 && 2*g.mate-1 < maxDepth {
+		maxDepth = 2*g.mate - 1
+	}
+	return maxDepth
+}
+
+// limits returns the search limits for engines implementing the ghess.Engine interface
+func (g goCommand) limits(isBlacksTurn bool, overhead int) ghess.SearchLimits {
+	limits := ghess.SearchLimits{MoveTime: g.thinkingTime(isBlacksTurn, overhead), Nodes: g.nodes}
+	if g.depth > 0 || g.mate > 0 {
+		limits.Depth = g.maxDepth()
+	}
+	return limits
+}
+
+// searchMovesOf converts the search moves into moves of board. Invalid moves are reported and skipped.
+func (g goCommand) searchMovesOf(board *ghess.Board) []ghess.Move {
+	moves := []ghess.Move{}
+	for _, moveStr := range g.searchMoves {
+		move, err := board.GetMoveFromLongAlgebraic(moveStr)
+		if err != nil {
+			fmt.Printf("info string invalid search move %s: %s\n", moveStr, err)
+			continue
+		}
+		moves = append(moves, move)
+	}
+	return moves
+}
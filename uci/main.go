@@ -2,10 +2,11 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 
 	"github.com/Wikunia/Ghess/ghess"
@@ -17,232 +18,321 @@ const START_FEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 
 var currentFEN = ""
 
-var board = ghess.Board{}
-var isready = make(chan bool)
-var stopPondering = make(chan bool)
-var currentBestPv = make(chan [30]ghess.Move)
-var startPv = [30]ghess.Move{}
-var currentlyPondering = false
-var madeMoves = []ghess.Move{}
-var ponderingMove = ""
-var maxThinkingTime = 0
+var board = ghess.GetBoardFromFen(START_FEN)
+var network *ghess.Network
+var useNNUE = false
+var book *ghess.Book
+var ownBook = false
+var bookBestMove = false
+var limitStrength = false
+var uciElo = ghess.SKILL_MIN_ELO
+var skillLevel = ghess.MAX_SKILL_LEVEL
+var useMCTS = false
+var mcts = ghess.NewMCTSEngine()
+var multiPV = 1
+var singularExtensions = true
+var moveOverhead = 0
+var ponder = false
+var debug = false
+
+// uciCommands are the commands the engine understands. Unknown tokens in front of them are skipped.
+var uciCommands = []string{"uci", "debug", "isready", "setoption", "register", "ucinewgame", "position", "go", "stop",
+	"ponderhit", "quit", "d", "bench", "eval", "flip"}
 
 func main() {
-	go func() {
-		for {
-			startPv = <-currentBestPv
-		}
-	}()
+	evalFile := flag.String("evalfile", "", "JSON file with evaluation weights (default: built in weights)")
+	nnueFile := flag.String("nnue", "", "neural network file which is used instead of the handcrafted evaluation")
+	flag.Parse()
+	initOptions()
+	if *evalFile != "" {
+		setOption("EvalFile", *evalFile)
+	}
+	if *nnueFile != "" {
+		setOption("NNUEFile", *nnueFile)
+		setOption("UseNNUE", "true")
+	}
 
+	// commands are read in their own goroutine such that stop and ponderhit arrive while searching
 	input := make(chan string)
 	go func(in chan string) {
 		reader := bufio.NewReader(os.Stdin)
 		for {
 			s, err := reader.ReadString('\n')
+			if s != "" {
+				in <- s
+			}
 			if err != nil {
+				if err != io.EOF {
+					log.Println("Error in read string", err)
+				}
 				close(in)
-				log.Println("Error in read string", err)
+				return
 			}
-			in <- s
 		}
 	}(input)
-	for {
-		select {
-		case in := <-input:
-			in = strings.TrimSpace(in)
-			stillRunning := runCommand(in)
-			if !stillRunning {
-				return
-			}
+	for in := range input {
+		stillRunning := runCommand(strings.TrimSpace(in))
+		if !stillRunning {
+			break
 		}
 	}
+	stopSearch()
 }
 
 func runCommand(in string) bool {
-	command := strings.Split(in, " ")
-	switch command[0] {
+	fields := strings.Fields(in)
+	for len(fields) > 0 && !isUCICommand(fields[0]) {
+		debugf("unknown command %s", fields[0])
+		in = strings.TrimSpace(strings.TrimPrefix(in, fields[0]))
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return true
+	}
+	switch fields[0] {
 	case "uci":
 		printUCI()
+	case "debug":
+		debug = len(fields) < 2 || fields[1] != "off"
+	case "setoption":
+		handleSetOption(in)
+	case "register":
+		// registration isn't needed
 	case "isready":
 		fmt.Println("readyok")
-		go func() {
-			currentBestPv <- [30]ghess.Move{}
-			isready <- true
-		}()
+	case "ucinewgame":
+		newGame()
 	case "position":
 		handlePosition(in)
 	case "go":
-		handleGo(in)
+		if len(fields) > 1 && fields[1] == "perft" {
+			perft(fields)
+		} else {
+			startSearch(parseGo(in))
+		}
 	case "stop":
-		handleStop()
+		stopSearch()
 	case "ponderhit":
-		handlePonderHit(in)
+		ponderHit()
+	case "d":
+		printBoard()
+	case "bench":
+		bench(fields)
+	case "eval":
+		fmt.Print(board.EvalTrace().String())
+	case "flip":
+		flip()
 	case "quit":
 		return false
 	}
 	return true
 }
 
+// isUCICommand returns true if the engine understands the command
+func isUCICommand(command string) bool {
+	for _, c := range uciCommands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// debugf prints an info string if the debug mode is on
+func debugf(format string, a ...interface{}) {
+	if debug {
+		fmt.Printf("info string "+format+"\n", a...)
+	}
+}
+
+// newGame clears everything the engine remembers from previous games
+func newGame() {
+	stopSearch()
+	mcts.NewGame()
+	currentFEN = START_FEN
+	board = ghess.GetBoardFromFen(START_FEN)
+	debugf("cleared the search state for a new game")
+}
+
 func printUCI() {
 	fmt.Printf("id name %s\n", ENGINE_NAME)
 	fmt.Printf("id author %s\n", AUTHOR_NAME)
+	for _, o := range options {
+		fmt.Println(o.String())
+	}
 	fmt.Println("uciok")
 }
 
-func handlePosition(in string) {
-	commands := strings.Split(in, " ")
-	switch commands[1] {
-	case "startpos":
-		currentFEN = START_FEN
-		board = ghess.GetBoardFromFen(START_FEN)
-		if len(commands) > 2 {
-			if commands[2] == "moves" {
-				makeMoves(commands[3:])
-			}
-		}
-	case "fen":
-		currentFEN = strings.Join(commands[2:8], " ")
-		board = ghess.GetBoardFromFen(currentFEN)
-		if len(commands) > 8 {
-			if commands[8] == "moves" {
-				makeMoves(commands[9:])
-			}
-		}
-	default:
-		fmt.Println("can't handle that command atm")
+// handleSetOption handles "setoption name <name> [value <value>]" where the name and value may contain spaces
+func handleSetOption(in string) {
+	nameStart := strings.Index(in, "name ")
+	if nameStart < 0 {
+		return
+	}
+	name := in[nameStart+len("name "):]
+	value := ""
+	if valueStart := strings.Index(name, " value "); valueStart >= 0 {
+		value = strings.TrimSpace(name[valueStart+len(" value "):])
+		name = name[:valueStart]
+	}
+	if err := setOption(strings.TrimSpace(name), value); err != nil {
+		fmt.Printf("info string %s\n", err)
 	}
 }
 
-func makeMoves(moves []string) {
-	madeMoves = []ghess.Move{}
-	for _, moveStr := range moves {
-		move, err := board.GetMoveFromLongAlgebraic(moveStr)
+// loadNetwork reads the neural network from path which is used if UseNNUE is set
+func loadNetwork(path string) {
+	if path == "<empty>" || path == "" {
+		network = nil
+	} else {
+		loaded, err := ghess.ReadNetwork(path)
 		if err != nil {
-			fmt.Println("ERROR for move ", moveStr, " ", err)
+			fmt.Printf("info string could not load the network: %s\n", err)
+			return
 		}
-		board.Move(&move)
-		madeMoves = append(madeMoves, move)
+		network = loaded
 	}
+	applyNetwork()
 }
 
-func handleStop() {
-	if currentlyPondering {
-		stopPondering <- true
-		currentlyPondering = false
-		// wait until pondering stopped
-		ready := <-isready
-		if ready {
-			go func() {
-				isready <- true
-			}()
-		}
-		if startPv[0].PieceId != 0 {
-			fmt.Printf("bestmove %s\n", ghess.GetAlgebraicFromMove(&startPv[0]))
-		} else {
-			// not sure whether there is a bestmove expected
-			fmt.Println("bestmove h8h8")
-		}
+// applyNetwork switches between the neural and the handcrafted evaluation
+func applyNetwork() {
+	if useNNUE && network == nil {
+		fmt.Println("info string no network loaded (set NNUEFile) using the handcrafted evaluation")
+	}
+	if useNNUE {
+		ghess.SetNetwork(network)
 	} else {
-		// not sure whether there is a bestmove expected
-		fmt.Println("bestmove a8a8")
+		ghess.SetNetwork(nil)
 	}
+	board.RefreshEvaluation()
 }
 
-func handleGo(in string) {
-	parts := strings.Split(in, " ")
-	if parts[1] == "ponder" {
-		handleGoPonder(in)
+// loadEvalFile loads the evaluation weights from path or restores the built in weights if path is empty.
+// Invalid files are reported and the weights don't change.
+func loadEvalFile(path string) {
+	if path == "<empty>" {
+		path = ""
+	}
+	if err := ghess.LoadEvalParams(path); err != nil {
+		fmt.Printf("info string could not load the evaluation file: %s\n", err)
 		return
 	}
-	// default 4s*40
-	wtime := 40 * 4000
-	btime := 40 * 4000
-	winc := 0
-	binc := 0
-	for i := 0; i < len(parts); i++ {
-		if parts[i] == "wtime" {
-			wtime, _ = strconv.Atoi(parts[i+1])
-		}
-		if parts[i] == "btime" {
-			btime, _ = strconv.Atoi(parts[i+1])
-		}
-		if parts[i] == "winc" {
-			winc, _ = strconv.Atoi(parts[i+1])
-		}
-		if parts[i] == "binc" {
-			binc, _ = strconv.Atoi(parts[i+1])
-		}
+	board.RefreshEvaluation()
+}
+
+// loadSyzygy looks for Syzygy tablebases in path (directories separated by the os specific list separator)
+func loadSyzygy(path string) {
+	n, err := ghess.InitSyzygy(path)
+	if err != nil {
+		fmt.Printf("info string could not load the tablebases: %s\n", err)
+		return
 	}
-	if board.IsBlacksTurn {
-		maxThinkingTime = btime/40 + binc
-	} else {
-		maxThinkingTime = wtime/40 + winc
+	if n > 0 {
+		debugf("found %d tablebases with up to %d pieces", n, ghess.SyzygyMaxPieces())
 	}
+}
 
-	ready := <-isready
-	fmt.Println("ready: ", ready)
-	depth := 2
-	startPv = [30]ghess.Move{}
-
-	fmt.Println("run with depth: ", depth)
-	ab := board.AlphaBetaEngineMove(startPv, depth, 30, false, true, maxThinkingTime)
-	pv := ab.Pv
-	if pv[1].PieceId != 0 {
-		currentlyPondering = true
-		ponderingMove = ghess.GetAlgebraicFromMove(&pv[1])
-		fmt.Printf("bestmove %s ponder %s\n", ghess.GetAlgebraicFromMove(&pv[0]), ponderingMove)
-	} else {
-		fmt.Printf("bestmove %s\n", ghess.GetAlgebraicFromMove(&pv[0]))
-		currentlyPondering = false
-		go func() {
-			isready <- true
-		}()
+// loadBook reads the Polyglot opening book which is used if OwnBook is set
+func loadBook(path string) {
+	if path == "<empty>" || path == "" {
+		book = nil
+		return
+	}
+	loaded, err := ghess.ReadBook(path)
+	if err != nil {
+		fmt.Printf("info string could not load the book: %s\n", err)
+		return
 	}
+	book = loaded
+	debugf("loaded book with %d entries", book.Len())
 }
 
-func handleGoPonder(in string) {
-	ended, _, _ := board.CheckGameEnded()
-	if !ended {
-		currentlyPondering = true
-		stopPondering = make(chan bool)
-		go board.AlphaBetaEnginePonder(stopPondering, isready, currentBestPv)
-	} else {
-		currentlyPondering = false
-		go func() {
-			isready <- true
-		}()
+// currentSkillLevel returns the skill level given by UCI_Elo if UCI_LimitStrength is set and Skill Level otherwise
+func currentSkillLevel() int {
+	if limitStrength {
+		return ghess.SkillLevelFromElo(uciElo)
 	}
+	return skillLevel
 }
 
-func handlePonderHit(in string) {
-	if currentlyPondering {
-		stopPondering <- true
-		currentlyPondering = false
+// handlePosition handles "position startpos|fen <fen> [moves <move1> ...]"
+func handlePosition(in string) {
+	fields := strings.Fields(in)
+	if len(fields) < 2 {
+		return
 	}
-	ready := <-isready
-	fmt.Println("ready: ", ready)
-	currentFEN = START_FEN
-	board = ghess.GetBoardFromFen(START_FEN)
-	for _, move := range madeMoves {
-		board.Move(&move)
+	movesIdx := len(fields)
+	for i, field := range fields {
+		if field == "moves" {
+			movesIdx = i
+			break
+		}
+	}
+	switch fields[1] {
+	case "startpos":
+		currentFEN = START_FEN
+	case "fen":
+		if movesIdx <= 2 {
+			fmt.Println("info string position fen without a FEN")
+			return
+		}
+		fenFields := fields[2:movesIdx]
+		// the move counters may be left out
+		defaultFields := []string{"w", "-", "-", "0", "1"}
+		if len(fenFields) < 6 {
+			fenFields = append(fenFields[:len(fenFields):len(fenFields)], defaultFields[len(fenFields)-1:]...)
+		}
+		currentFEN = strings.Join(fenFields, " ")
+	default:
+		fmt.Printf("info string unknown position command %s\n", fields[1])
+		return
+	}
+	board = ghess.GetBoardFromFen(currentFEN)
+	if movesIdx < len(fields) {
+		makeMoves(fields[movesIdx+1:])
 	}
+}
 
-	depth := 2
-	for i := 0; i < 30; i++ {
-		if startPv[i].PieceId != 0 {
-			depth = i + 2
+// makeMoves makes the moves on the board up to the first illegal one
+func makeMoves(moves []string) {
+	for _, moveStr := range moves {
+		move, err := board.GetMoveFromLongAlgebraic(moveStr)
+		if err != nil {
+			fmt.Printf("info string illegal move %s: %s\n", moveStr, err)
+			return
 		}
+		board.Move(&move)
 	}
-	if depth < 2 {
-		depth = 2
+}
+
+// printInfo prints the progress of a search as an info command
+func printInfo(info ghess.SearchInfo) {
+	ms := info.Time.Milliseconds()
+	nps := int64(info.Nodes) * 1000
+	if ms > 0 {
+		nps /= ms
 	}
-	fmt.Println("Used pondering")
-	ab := board.AlphaBetaEngineMove(startPv, depth, 30, true, true, maxThinkingTime)
-	pv := ab.Pv
-	if pv[1].PieceId != 0 {
-		ponderingMove = ghess.GetAlgebraicFromMove(&pv[1])
-		fmt.Printf("bestmove %s ponder %s\n", ghess.GetAlgebraicFromMove(&pv[0]), ponderingMove)
-	} else {
-		fmt.Printf("bestmove %s\n", ghess.GetAlgebraicFromMove(&pv[0]))
+	if info.CurrMoveNumber > 0 {
+		fmt.Printf("info depth %d currmove %s currmovenumber %d nodes %d nps %d time %d\n", info.Depth,
+			ghess.GetAlgebraicFromMove(&info.CurrMove), info.CurrMoveNumber, info.Nodes, nps, ms)
+		return
+	}
+	score := fmt.Sprintf("cp %d", int(info.Score))
+	if info.Mate != 0 {
+		score = fmt.Sprintf("mate %d", info.Mate)
+	}
+	pv := ""
+	for i := range info.Pv {
+		pv += " " + ghess.GetAlgebraicFromMove(&info.Pv[i])
+	}
+	fmt.Printf("info depth %d seldepth %d multipv %d score %s nodes %d nps %d time %d hashfull %d pv%s\n", info.Depth,
+		info.SelDepth, info.MultiPV, score, info.Nodes, nps, ms, info.HashFull, pv)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
 	}
+	return b
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// option is a UCI option of type check, spin, combo, string or button.
+// apply is called with the validated value whenever the option is set (for buttons the value is empty).
+type option struct {
+	name         string
+	kind         string
+	defaultValue string
+	min, max     int      // range of a spin option
+	vars         []string // values of a combo option
+	value        string
+	apply        func(value string)
+}
+
+// options are all options the engine supports in the order they are printed for the uci command
+var options = []*option{
+	{name: "Hash", kind: "spin", defaultValue: "1", min: 1, max: 1024, apply: func(value string) {
+		megabytes, _ := strconv.Atoi(value)
+		ghess.SetHashSize(megabytes)
+	}},
+	{name: "Clear Hash", kind: "button", apply: func(string) {
+		board.RefreshEvaluation()
+	}},
+	{name: "Threads", kind: "spin", defaultValue: "1", min: 1, max: 64, apply: func(value string) {
+		// only the MCTS search runs on several threads
+		mcts.Threads, _ = strconv.Atoi(value)
+	}},
+	{name: "MultiPV", kind: "spin", defaultValue: "1", min: 1, max: 256, apply: func(value string) {
+		multiPV, _ = strconv.Atoi(value)
+	}},
+	{name: "SingularExtensions", kind: "check", defaultValue: "true", apply: func(value string) {
+		singularExtensions = value == "true"
+	}},
+	{name: "Move Overhead", kind: "spin", defaultValue: "10", min: 0, max: 5000, apply: func(value string) {
+		moveOverhead, _ = strconv.Atoi(value)
+	}},
+	{name: "Ponder", kind: "check", defaultValue: "false", apply: func(value string) {
+		ponder = value == "true"
+	}},
+	{name: "Skill Level", kind: "spin", defaultValue: strconv.Itoa(ghess.MAX_SKILL_LEVEL), min: 0, max: ghess.MAX_SKILL_LEVEL, apply: func(value string) {
+		skillLevel, _ = strconv.Atoi(value)
+	}},
+	{name: "UCI_LimitStrength", kind: "check", defaultValue: "false", apply: func(value string) {
+		limitStrength = value == "true"
+	}},
+	{name: "UCI_Elo", kind: "spin", defaultValue: strconv.Itoa(ghess.SKILL_MIN_ELO), min: ghess.SKILL_MIN_ELO, max: ghess.SKILL_MAX_ELO, apply: func(value string) {
+		uciElo, _ = strconv.Atoi(value)
+	}},
+	{name: "OwnBook", kind: "check", defaultValue: "false", apply: func(value string) {
+		ownBook = value == "true"
+	}},
+	{name: "BookFile", kind: "string", defaultValue: "<empty>", apply: loadBook},
+	{name: "BookBestMove", kind: "check", defaultValue: "false", apply: func(value string) {
+		bookBestMove = value == "true"
+	}},
+	{name: "SyzygyPath", kind: "string", defaultValue: "<empty>", apply: loadSyzygy},
+	{name: "EvalFile", kind: "string", defaultValue: "<empty>", apply: loadEvalFile},
+	{name: "UseNNUE", kind: "check", defaultValue: "false", apply: func(value string) {
+		useNNUE = value == "true"
+		applyNetwork()
+	}},
+	{name: "NNUEFile", kind: "string", defaultValue: "<empty>", apply: loadNetwork},
+	{name: "Search", kind: "combo", defaultValue: "AlphaBeta", vars: []string{"AlphaBeta", "MCTS"}, apply: func(value string) {
+		useMCTS = value == "MCTS"
+	}},
+	{name: "MCTS Exploration", kind: "spin", defaultValue: strconv.Itoa(int(100 * ghess.MCTS_EXPLORATION)), min: 1, max: 1000, apply: func(value string) {
+		// the exploration constant is given in hundredths
+		exploration, _ := strconv.Atoi(value)
+		mcts.Exploration = float64(exploration) / 100
+	}},
+}
+
+// String returns the description of the option for the uci command
+func (o *option) String() string {
+	str := fmt.Sprintf("option name %s type %s", o.name, o.kind)
+	if o.kind == "button" {
+		return str
+	}
+	str += " default " + o.defaultValue
+	switch o.kind {
+	case "spin":
+		str += fmt.Sprintf(" min %d max %d", o.min, o.max)
+	case "combo":
+		for _, v := range o.vars {
+			str += " var " + v
+		}
+	}
+	return str
+}
+
+// validate returns the value in its canonical form or an error if it isn't valid for the option
+func (o *option) validate(value string) (string, error) {
+	switch o.kind {
+	case "check":
+		value = strings.ToLower(value)
+		if value != "true" && value != "false" {
+			return "", fmt.Errorf("%s must be true or false", o.name)
+		}
+	case "spin":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < o.min || n > o.max {
+			return "", fmt.Errorf("%s must be an integer between %d and %d", o.name, o.min, o.max)
+		}
+		value = strconv.Itoa(n)
+	case "combo":
+		for _, v := range o.vars {
+			if strings.EqualFold(v, value) {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("%s must be one of %s", o.name, strings.Join(o.vars, ", "))
+	case "button":
+		value = ""
+	}
+	return value, nil
+}
+
+// findOption returns the option with the given name which is case insensitive or nil if it doesn't exist
+func findOption(name string) *option {
+	for _, o := range options {
+		if strings.EqualFold(o.name, name) {
+			return o
+		}
+	}
+	return nil
+}
+
+// setOption validates the value and applies it to the engine
+func setOption(name, value string) error {
+	o := findOption(name)
+	if o == nil {
+		return fmt.Errorf("unknown option %s", name)
+	}
+	value, err := o.validate(value)
+	if err != nil {
+		return err
+	}
+	o.value = value
+	o.apply(value)
+	return nil
+}
+
+// initOptions applies the default values of all options
+func initOptions() {
+	for _, o := range options {
+		if o.kind != "button" {
+			o.value = o.defaultValue
+			o.apply(o.defaultValue)
+		}
+	}
+}
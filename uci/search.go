@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Wikunia/Ghess/ghess"
+)
+
+// searchState is the state of the UCI driver
+type searchState int
+
+const (
+	idle      searchState = iota // no search is running
+	searching                    // the bestmove is sent as soon as the search ends (or on stop for go infinite)
+	pondering                    // the search runs on the opponents time until ponderhit or stop
+)
+
+// search is a running search. Only one search runs at a time in its own goroutine which sends the bestmove.
+type search struct {
+	stop         chan bool // closed to end the search
+	release      chan bool // closed as soon as the bestmove may be sent
+	done         chan bool // closed after the bestmove was sent
+	thinkingTime int       // ms the search continues after a ponderhit
+	timer        *time.Timer
+	stopOnce     sync.Once
+	releaseOnce  sync.Once
+}
+
+// searchParams are the options a search uses. They are copied before the search starts such that setoption can't
+// change them while the search runs.
+type searchParams struct {
+	ownBook            bool
+	book               *ghess.Book
+	bookBestMove       bool
+	useMCTS            bool
+	skillLevel         int
+	multiPV            int
+	singularExtensions bool
+	ponder             bool
+}
+
+// currentSearchParams returns the current values of the options a search uses
+func currentSearchParams() searchParams {
+	return searchParams{
+		ownBook:            ownBook,
+		book:               book,
+		bookBestMove:       bookBestMove,
+		useMCTS:            useMCTS,
+		skillLevel:         currentSkillLevel(),
+		multiPV:            multiPV,
+		singularExtensions: singularExtensions,
+		ponder:             ponder,
+	}
+}
+
+var stateMu sync.Mutex
+var state = idle
+var current *search
+
+// end stops the search and allows the bestmove to be sent
+func (s *search) end() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.releaseBestMove()
+}
+
+// releaseBestMove allows the bestmove to be sent once the search ended
+func (s *search) releaseBestMove() {
+	s.releaseOnce.Do(func() { close(s.release) })
+}
+
+// startSearch starts searching the current position in a new goroutine. A running search is stopped first.
+func startSearch(g goCommand) {
+	stopSearch()
+	s := &search{stop: make(chan bool), release: make(chan bool), done: make(chan bool)}
+	s.thinkingTime = g.thinkingTime(board.IsBlacksTurn, moveOverhead)
+	limits := g.limits(board.IsBlacksTurn, moveOverhead)
+	if g.ponder {
+		// the clock only starts with ponderhit
+		limits.MoveTime = UNLIMITED_TIME
+	}
+	// the search works on its own copy such that the next position command or the evaluation of the console commands
+	// can't interfere with it
+	searchBoard := board.Copy()
+	params := currentSearchParams()
+	searchBoard.SetSearchMoves(g.searchMovesOf(&searchBoard))
+	searchBoard.SetStopChannel(s.stop)
+	searchBoard.SetInfoHandler(printInfo)
+
+	stateMu.Lock()
+	state = searching
+	if g.ponder {
+		state = pondering
+	}
+	if !g.ponder && !g.infinite {
+		s.releaseBestMove()
+	}
+	current = s
+	stateMu.Unlock()
+
+	debugf("search limits: time %d ms nodes %d depth %d", limits.MoveTime, limits.Nodes, limits.Depth)
+	go func() {
+		bestMove, ponderMove := findBestMove(&searchBoard, g, limits, params)
+		<-s.release
+		stateMu.Lock()
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		state = idle
+		current = nil
+		stateMu.Unlock()
+		if ponderMove != "" && params.ponder {
+			fmt.Printf("bestmove %s ponder %s\n", bestMove, ponderMove)
+		} else {
+			fmt.Printf("bestmove %s\n", bestMove)
+		}
+		close(s.done)
+	}()
+}
+
+// stopSearch ends the running search and waits until its bestmove was sent
+func stopSearch() {
+	stateMu.Lock()
+	s := current
+	stateMu.Unlock()
+	if s == nil {
+		return
+	}
+	s.end()
+	<-s.done
+}
+
+// ponderHit continues the pondering search as a normal search which ends after the thinking time
+func ponderHit() {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	if state != pondering {
+		return
+	}
+	state = searching
+	s := current
+	s.timer = time.AfterFunc(time.Duration(s.thinkingTime)*time.Millisecond, s.end)
+	s.releaseBestMove()
+}
+
+// findBestMove returns the best move and the expected reply in long algebraic notation. The reply is empty if there is
+// none and the best move is 0000 if there are no legal moves.
+func findBestMove(b *ghess.Board, g goCommand, limits ghess.SearchLimits, params searchParams) (string, string) {
+	if b.GetNumberOfMoves(1) == 0 {
+		return "0000", ""
+	}
+	if params.ownBook && len(g.searchMoves) == 0 && !g.infinite {
+		if move, ok := b.BookMove(params.book, params.bookBestMove); ok {
+			debugf("book move")
+			return ghess.GetAlgebraicFromMove(&move), ""
+		}
+	}
+	var pv []ghess.Move
+	if params.useMCTS {
+		result := mcts.Search(b, limits)
+		pv = result.Pv
+		if len(pv) == 0 {
+			pv = []ghess.Move{result.Move}
+		}
+	} else {
+		b.SetSkillLevel(params.skillLevel)
+		b.SetMultiPV(params.multiPV)
+		b.SetSingularExtensions(params.singularExtensions)
+		b.SetNodeLimit(g.nodes)
+		maxDepth := g.maxDepth()
+		ab := b.AlphaBetaEngineMove([30]ghess.Move{}, min(2, maxDepth), maxDepth, false, false, limits.MoveTime)
+		pv = ab.Pv[:]
+	}
+	ponderMove := ""
+	if len(pv) > 1 && pv[1].PieceId != 0 {
+		ponderMove = ghess.GetAlgebraicFromMove(&pv[1])
+	}
+	return ghess.GetAlgebraicFromMove(&pv[0]), ponderMove
+}